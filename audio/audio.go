@@ -4,45 +4,31 @@ import (
 	"errors"
 	"fmt"
 	"time"
-
-	"github.com/gordonklaus/portaudio"
 )
 
-func Initialize() error {
-	err := portaudio.Initialize()
-	if err != nil {
-		return fmt.Errorf("failed to initialize audio: %w", err)
-	}
-	return nil
-}
-
-func Terminate() error {
-	err := portaudio.Terminate()
-	if err != nil {
-		return fmt.Errorf("failed to terminate audio: %w", err)
-	}
-
-	return nil
-}
-
 type SampleType interface {
-	float32 | int32 | portaudio.Int24 | int16 | int8 | uint8
+	float32 | int32 | Int24 | int16 | int8 | uint8
 }
 
 type Stream[T SampleType] struct {
-	stream    *portaudio.Stream
+	stream    BackendStream
 	ibuf      []T
 	obuf      []T
 	framePool *framePool[T]
 }
 
 func Open[T SampleType](opts ...Option) (*Stream[T], error) {
+	b, err := backend()
+	if err != nil {
+		return nil, err
+	}
+
 	var options streamOptions
 	for _, opt := range opts {
 		opt.apply(&options)
 	}
 
-	var inputDevice *portaudio.DeviceInfo
+	var inputDevice *DeviceInfo
 	if options.defaultInput {
 		device, err := GetDefaultInputDevice()
 		if err != nil {
@@ -52,7 +38,7 @@ func Open[T SampleType](opts ...Option) (*Stream[T], error) {
 	} else if options.inputDevice != nil {
 		inputDevice = options.inputDevice.info
 	}
-	var outputDevice *portaudio.DeviceInfo
+	var outputDevice *DeviceInfo
 	if options.defaultOutput {
 		device, err := GetDefaultOutputDevice()
 		if err != nil {
@@ -135,34 +121,35 @@ func Open[T SampleType](opts ...Option) (*Stream[T], error) {
 		// 10ms
 		bufferSamples = int(float64(sampleRate) * latency.Seconds())
 	}
+	params := StreamParameters{
+		Input: StreamDeviceParameters{
+			Device:   inputDevice,
+			Channels: inputChannels,
+			Latency:  inputLatency,
+		},
+		Output: StreamDeviceParameters{
+			Device:   outputDevice,
+			Channels: outputChannels,
+			Latency:  outputLatency,
+		},
+		SampleRate:      sampleRate,
+		FramesPerBuffer: bufferSamples,
+	}
+
 	var ibuf, obuf []T
 	var pool *framePool[T]
-	var args []any
 	if inputDevice != nil {
 		ibuf = make([]T, inputChannels*bufferSamples)
-		args = append(args, ibuf)
+		params.InputBuffer = ibuf
 
 		pool = newFramePool[T](len(ibuf))
 	}
 	if outputDevice != nil {
 		obuf = make([]T, outputChannels*bufferSamples)
-		args = append(args, obuf)
+		params.OutputBuffer = obuf
 	}
 
-	stream, err := portaudio.OpenStream(portaudio.StreamParameters{
-		Input: portaudio.StreamDeviceParameters{
-			Device:   inputDevice,
-			Channels: inputChannels,
-			Latency:  inputLatency,
-		},
-		Output: portaudio.StreamDeviceParameters{
-			Device:   outputDevice,
-			Channels: outputChannels,
-			Latency:  outputLatency,
-		},
-		SampleRate:      sampleRate,
-		FramesPerBuffer: bufferSamples,
-	}, args...)
+	stream, err := b.OpenStream(params)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open audio stream: %w", err)
 	}
@@ -201,11 +188,19 @@ func (s *Stream[T]) Stop() error {
 var (
 	ErrInputOverflowed  = errors.New("input overflowed")
 	ErrOutputOverflowed = errors.New("output overflowed")
+	ErrNoInput          = errors.New("stream has no input")
+	ErrNoOutput         = errors.New("stream has no output")
 )
 
+// Read reads a frame from the input stream. The frame must be released by
+// Frame.Release after use.
 func (s *Stream[T]) Read() (*Frame[T], error) {
+	if s.framePool == nil {
+		return nil, ErrNoInput
+	}
+
 	err := s.stream.Read()
-	if errors.Is(err, portaudio.InputOverflowed) {
+	if errors.Is(err, ErrInputOverflowed) {
 		return nil, ErrInputOverflowed
 	}
 	if err != nil {
@@ -219,6 +214,9 @@ func (s *Stream[T]) Read() (*Frame[T], error) {
 }
 
 func (s *Stream[T]) Write(frame []T) error {
+	if s.obuf == nil {
+		return ErrNoOutput
+	}
 	if len(frame) != len(s.obuf) {
 		return errors.New("invalid frame size")
 	}
@@ -226,7 +224,7 @@ func (s *Stream[T]) Write(frame []T) error {
 	copy(s.obuf, frame)
 
 	err := s.stream.Write()
-	if errors.Is(err, portaudio.OutputUnderflowed) {
+	if errors.Is(err, ErrOutputOverflowed) {
 		return ErrOutputOverflowed
 	}
 	if err != nil {
@@ -0,0 +1,121 @@
+package audio
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Backend provides audio devices and streams.
+type Backend interface {
+	Initialize() error
+	Terminate() error
+	Devices() ([]*DeviceInfo, error)
+	DefaultInputDevice() (*DeviceInfo, error)
+	DefaultOutputDevice() (*DeviceInfo, error)
+	OpenStream(params StreamParameters) (BackendStream, error)
+}
+
+// BackendStream is a blocking stream opened by a Backend. Read and Write
+// transfer a buffer of StreamParameters, they return ErrInputOverflowed or
+// ErrOutputOverflowed if samples are lost.
+type BackendStream interface {
+	Start() error
+	Stop() error
+	Close() error
+	Read() error
+	Write() error
+}
+
+// DeviceInfo describes a device of a backend.
+type DeviceInfo struct {
+	Name                     string
+	MaxInputChannels         int
+	MaxOutputChannels        int
+	DefaultLowInputLatency   time.Duration
+	DefaultLowOutputLatency  time.Duration
+	DefaultHighInputLatency  time.Duration
+	DefaultHighOutputLatency time.Duration
+	DefaultSampleRate        float64
+
+	// Handle is a backend specific value.
+	Handle any
+}
+
+type StreamDeviceParameters struct {
+	Device   *DeviceInfo
+	Channels int
+	Latency  time.Duration
+}
+
+type StreamParameters struct {
+	Input           StreamDeviceParameters
+	Output          StreamDeviceParameters
+	SampleRate      float64
+	FramesPerBuffer int
+
+	// InputBuffer and OutputBuffer are interleaved sample slices ([]T of
+	// SampleType) transferred by Read and Write, or nil.
+	InputBuffer  any
+	OutputBuffer any
+}
+
+var (
+	backendMu          sync.Mutex
+	currentBackend     Backend = PortAudio()
+	initializedBackend Backend
+)
+
+var ErrBackendInitialized = errors.New("audio backend is already initialized")
+
+// SetBackend sets the backend used by the package. It must be called before
+// the backend is initialized. The default backend is PortAudio.
+func SetBackend(b Backend) error {
+	backendMu.Lock()
+	defer backendMu.Unlock()
+
+	if initializedBackend != nil {
+		return ErrBackendInitialized
+	}
+	currentBackend = b
+	return nil
+}
+
+// Initialize initializes the backend. It is called on the first use of the
+// backend, so calling it explicitly is optional.
+func Initialize() error {
+	_, err := backend()
+	return err
+}
+
+// Terminate terminates the backend if it is initialized.
+func Terminate() error {
+	backendMu.Lock()
+	defer backendMu.Unlock()
+
+	if initializedBackend == nil {
+		return nil
+	}
+	b := initializedBackend
+	initializedBackend = nil
+
+	if err := b.Terminate(); err != nil {
+		return fmt.Errorf("failed to terminate audio: %w", err)
+	}
+	return nil
+}
+
+func backend() (Backend, error) {
+	backendMu.Lock()
+	defer backendMu.Unlock()
+
+	if initializedBackend != nil {
+		return initializedBackend, nil
+	}
+	if err := currentBackend.Initialize(); err != nil {
+		return nil, fmt.Errorf("failed to initialize audio: %w", err)
+	}
+	initializedBackend = currentBackend
+	return initializedBackend, nil
+}
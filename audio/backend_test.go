@@ -0,0 +1,158 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kechako/goradio/wav"
+)
+
+// useBackend makes b the backend of the package until the test ends.
+func useBackend(t *testing.T, b Backend) {
+	t.Helper()
+
+	if err := SetBackend(b); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		Terminate()
+		SetBackend(PortAudio())
+	})
+}
+
+func TestNullBackend(t *testing.T) {
+	useBackend(t, Null())
+
+	device, err := GetDefaultOutputDevice()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if device.Name() != "null" || device.DefaultSampleRate() != nullSampleRate {
+		t.Errorf("default output device = %s at %d Hz, want null at %d Hz", device.Name(), device.DefaultSampleRate(), nullSampleRate)
+	}
+
+	const (
+		sampleRate    = 8000
+		bufferSamples = 80
+		frames        = 10
+	)
+	s, err := Open[int16](
+		WithDefaultInputDevice(),
+		WithDefaultOutputDevice(),
+		WithSampleRate(sampleRate),
+		WithBufferSamples(bufferSamples),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+
+	// paced at the sample rate, the first buffer is not waited for
+	start := time.Now()
+	frame := make([]int16, nullChannels*bufferSamples)
+	for i := 0; i < frames; i++ {
+		if err := s.Write(frame); err != nil {
+			t.Fatalf("Write() = %v", err)
+		}
+	}
+	if got, want := time.Since(start), (frames-1)*bufferSamples*time.Second/sampleRate; got < want {
+		t.Errorf("wrote %d frames in %v, want >= %v", frames, got, want)
+	}
+	if err := s.Write(frame[1:]); err == nil {
+		t.Error("Write() of a short frame = nil, want an error")
+	}
+
+	// input is silence
+	f, err := s.Read()
+	if err != nil {
+		t.Fatalf("Read() = %v", err)
+	}
+	for _, v := range f.Data() {
+		if v != 0 {
+			t.Fatalf("Read() = %v, want silence", f.Data())
+		}
+	}
+	f.Release()
+
+	// late by more than the latency
+	time.Sleep(nullLowLatency + 50*time.Millisecond)
+	if err := s.Write(frame); !errors.Is(err, ErrOutputOverflowed) {
+		t.Errorf("Write() after a stall = %v, want %v", err, ErrOutputOverflowed)
+	}
+}
+
+func TestFileBackend(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.wav")
+	useBackend(t, File(path))
+
+	const (
+		sampleRate    = 48000
+		bufferSamples = 48
+		frames        = 5
+	)
+	s, err := Open[int16](
+		WithDefaultOutputDevice(),
+		WithSampleRate(sampleRate),
+		WithBufferSamples(bufferSamples),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	var want []int16
+	frame := make([]int16, nullChannels*bufferSamples)
+	for i := 0; i < frames; i++ {
+		for j := range frame {
+			frame[j] = int16(i*1000 - j)
+		}
+		if err := s.Write(frame); err != nil {
+			t.Fatalf("Write() = %v", err)
+		}
+		want = append(want, frame...)
+	}
+	if err := s.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := wav.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantFormat := wav.Format{SampleRate: sampleRate, Channels: nullChannels, BitsPerSample: 16}
+	if got := r.Format(); got != wantFormat {
+		t.Errorf("format = %+v, want %+v", got, wantFormat)
+	}
+
+	b, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b) != 2*len(want) {
+		t.Fatalf("read %d bytes, want %d", len(b), 2*len(want))
+	}
+	for i, v := range want {
+		if got := int16(binary.LittleEndian.Uint16(b[2*i:])); got != v {
+			t.Fatalf("sample %d = %d, want %d", i, got, v)
+		}
+	}
+}
@@ -0,0 +1,6 @@
+//go:build audiodebug
+
+package audio
+
+// debug enables the detection of frame misuse.
+const debug = true
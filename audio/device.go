@@ -4,12 +4,10 @@ import (
 	"errors"
 	"fmt"
 	"time"
-
-	"github.com/gordonklaus/portaudio"
 )
 
 type Device struct {
-	info *portaudio.DeviceInfo
+	info *DeviceInfo
 }
 
 func (d *Device) Name() string                            { return d.info.Name }
@@ -22,7 +20,11 @@ func (d *Device) DefaultHighOutputLatency() time.Duration { return d.info.Defaul
 func (d *Device) DefaultSampleRate() int                  { return int(d.info.DefaultSampleRate) }
 
 func GetDevices() ([]*Device, error) {
-	infos, err := portaudio.Devices()
+	b, err := backend()
+	if err != nil {
+		return nil, err
+	}
+	infos, err := b.Devices()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get devices: %w", err)
 	}
@@ -40,7 +42,11 @@ func GetDevices() ([]*Device, error) {
 }
 
 func GetDefaultInputDevice() (*Device, error) {
-	info, err := portaudio.DefaultInputDevice()
+	b, err := backend()
+	if err != nil {
+		return nil, err
+	}
+	info, err := b.DefaultInputDevice()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get default input device: %w", err)
 	}
@@ -49,7 +55,11 @@ func GetDefaultInputDevice() (*Device, error) {
 }
 
 func GetDefaultOutputDevice() (*Device, error) {
-	info, err := portaudio.DefaultOutputDevice()
+	b, err := backend()
+	if err != nil {
+		return nil, err
+	}
+	info, err := b.DefaultOutputDevice()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get default output device: %w", err)
 	}
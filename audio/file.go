@@ -0,0 +1,142 @@
+package audio
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"os"
+
+	"github.com/kechako/goradio/wav"
+)
+
+type fileBackend struct {
+	nullBackend
+	path string
+}
+
+// File returns the backend that writes output samples to a WAV file at
+// path in real time instead of playing them. Input reads silence. It has
+// a device named "file".
+func File(path string) Backend {
+	return fileBackend{
+		nullBackend: nullBackend{name: "file"},
+		path:        path,
+	}
+}
+
+func (b fileBackend) OpenStream(params StreamParameters) (BackendStream, error) {
+	ns, err := newNullStream(params)
+	if err != nil {
+		return nil, err
+	}
+	if params.OutputBuffer == nil {
+		return ns, nil
+	}
+
+	bits, float := sampleFormat(params.OutputBuffer)
+	format := wav.Format{
+		SampleRate:    int(params.SampleRate),
+		Channels:      params.Output.Channels,
+		BitsPerSample: bits,
+		Float:         float,
+	}
+
+	file, err := os.Create(b.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create audio file: %w", err)
+	}
+	w, err := wav.NewWriter(file, format)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return &fileStream{
+		nullStream: ns,
+		file:       file,
+		w:          w,
+		obuf:       params.OutputBuffer,
+	}, nil
+}
+
+type fileStream struct {
+	*nullStream
+	file *os.File
+	w    *wav.Writer
+	obuf any
+	buf  []byte
+}
+
+func (s *fileStream) Write() error {
+	s.buf = encodeSamples(s.buf, s.obuf)
+	if _, err := s.w.Write(s.buf); err != nil {
+		return err
+	}
+	return s.nullStream.Write()
+}
+
+func (s *fileStream) Close() error {
+	err := s.w.Close()
+	if cerr := s.file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func sampleFormat(buf any) (bits int, float bool) {
+	switch buf.(type) {
+	case []float32:
+		return 32, true
+	case []int32:
+		return 32, false
+	case []Int24:
+		return 24, false
+	case []int16:
+		return 16, false
+	default:
+		return 8, false
+	}
+}
+
+// encodeSamples encodes samples as little endian WAV data reusing b.
+func encodeSamples(b []byte, samples any) []byte {
+	b = b[:0]
+	switch samples := samples.(type) {
+	case []float32:
+		b = grow(b, 4*len(samples))
+		for i, v := range samples {
+			binary.LittleEndian.PutUint32(b[4*i:], math.Float32bits(v))
+		}
+	case []int32:
+		b = grow(b, 4*len(samples))
+		for i, v := range samples {
+			binary.LittleEndian.PutUint32(b[4*i:], uint32(v))
+		}
+	case []Int24:
+		for _, v := range samples {
+			i32 := v.Int32()
+			b = append(b, byte(i32>>8), byte(i32>>16), byte(i32>>24))
+		}
+	case []int16:
+		b = grow(b, 2*len(samples))
+		for i, v := range samples {
+			binary.LittleEndian.PutUint16(b[2*i:], uint16(v))
+		}
+	case []int8:
+		// 8-bit WAV is unsigned
+		for _, v := range samples {
+			b = append(b, uint8(int(v)+128))
+		}
+	case []uint8:
+		b = append(b, samples...)
+	}
+	return b
+}
+
+// grow returns b with length n.
+func grow(b []byte, n int) []byte {
+	if cap(b) < n {
+		return make([]byte, n)
+	}
+	return b[:n]
+}
@@ -2,9 +2,13 @@ package audio
 
 import "sync"
 
+// Frame is a buffer of samples read from a stream. Release must be called
+// when the frame is no longer used, and the frame must not be used after
+// that. Misuse panics when built with the audiodebug tag.
 type Frame[T SampleType] struct {
-	data []T
-	pool *framePool[T]
+	data     []T
+	pool     *framePool[T]
+	released bool
 }
 
 func newFrame[T SampleType](size int, pool *framePool[T]) *Frame[T] {
@@ -15,10 +19,25 @@ func newFrame[T SampleType](size int, pool *framePool[T]) *Frame[T] {
 }
 
 func (f *Frame[T]) Data() []T {
+	if debug && f.released {
+		panic("audio: Data called on released frame")
+	}
 	return f.data
 }
 
+// Release returns the frame to the pool of the stream. Releasing a frame
+// twice does nothing.
 func (f *Frame[T]) Release() {
+	if f.released {
+		if debug {
+			panic("audio: frame released twice")
+		}
+		return
+	}
+	f.released = true
+	if f.pool != nil {
+		f.pool.Put(f)
+	}
 }
 
 type framePool[T SampleType] struct {
@@ -34,7 +53,9 @@ func newFramePool[T SampleType](size int) *framePool[T] {
 }
 
 func (p *framePool[T]) Get() *Frame[T] {
-	return p.pool.Get().(*Frame[T])
+	frame := p.pool.Get().(*Frame[T])
+	frame.released = false
+	return frame
 }
 
 func (p *framePool[T]) Put(frame *Frame[T]) {
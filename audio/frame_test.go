@@ -0,0 +1,33 @@
+//go:build !audiodebug
+
+package audio
+
+import "testing"
+
+func TestFrameReleaseTwice(t *testing.T) {
+	pool := newFramePool[int16](4)
+
+	f := pool.Get()
+	f.Release()
+	f.Release()
+
+	a := pool.Get()
+	b := pool.Get()
+	if a == b {
+		t.Fatal("frame released twice is shared by two reads")
+	}
+}
+
+func TestFrameReuse(t *testing.T) {
+	pool := newFramePool[int16](4)
+
+	f := pool.Get()
+	f.Release()
+	g := pool.Get()
+	if g.released {
+		t.Error("frame from pool is marked released")
+	}
+	if len(g.Data()) != 4 {
+		t.Errorf("len(Data()) = %d, want 4", len(g.Data()))
+	}
+}
@@ -0,0 +1,33 @@
+package audio
+
+import "unsafe"
+
+// Int24 holds the bytes of a 24-bit signed integer in native byte order.
+type Int24 [3]byte
+
+// PutInt32 puts the three most significant bytes of i32 into v.
+func (v *Int24) PutInt32(i32 int32) {
+	if littleEndian {
+		v[0] = byte(i32 >> 8)
+		v[1] = byte(i32 >> 16)
+		v[2] = byte(i32 >> 24)
+	} else {
+		v[2] = byte(i32 >> 8)
+		v[1] = byte(i32 >> 16)
+		v[0] = byte(i32 >> 24)
+	}
+}
+
+// Int32 returns v as the three most significant bytes of an int32, the
+// inverse of PutInt32.
+func (v Int24) Int32() int32 {
+	if littleEndian {
+		return int32(v[0])<<8 | int32(v[1])<<16 | int32(v[2])<<24
+	}
+	return int32(v[2])<<8 | int32(v[1])<<16 | int32(v[0])<<24
+}
+
+var littleEndian = func() bool {
+	x := uint16(1)
+	return *(*byte)(unsafe.Pointer(&x)) == 1
+}()
@@ -0,0 +1,52 @@
+package audio
+
+import (
+	"bytes"
+	"math"
+	"testing"
+)
+
+func TestInt24(t *testing.T) {
+	tests := []struct {
+		in   int32
+		want int32
+	}{
+		{0, 0},
+		{1 << 8, 1 << 8},
+		{-1 << 8, -1 << 8},
+		{math.MaxInt32, math.MaxInt32 &^ 0xff},
+		{math.MinInt32, math.MinInt32},
+		// the least significant byte is dropped
+		{0x123456ff, 0x12345600},
+	}
+	for _, tt := range tests {
+		var v Int24
+		v.PutInt32(tt.in)
+		if got := v.Int32(); got != tt.want {
+			t.Errorf("PutInt32(%#x).Int32() = %#x, want %#x", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestEncodeSamples(t *testing.T) {
+	var i24 Int24
+	i24.PutInt32(0x12345600)
+
+	tests := []struct {
+		name    string
+		samples any
+		want    []byte
+	}{
+		{"float32", []float32{1}, []byte{0x00, 0x00, 0x80, 0x3f}},
+		{"int32", []int32{0x12345678}, []byte{0x78, 0x56, 0x34, 0x12}},
+		{"int24", []Int24{i24}, []byte{0x56, 0x34, 0x12}},
+		{"int16", []int16{-2}, []byte{0xfe, 0xff}},
+		{"int8", []int8{-128, 0, 127}, []byte{0, 128, 255}},
+		{"uint8", []uint8{1, 2}, []byte{1, 2}},
+	}
+	for _, tt := range tests {
+		if got := encodeSamples(nil, tt.samples); !bytes.Equal(got, tt.want) {
+			t.Errorf("%s: encodeSamples() = % x, want % x", tt.name, got, tt.want)
+		}
+	}
+}
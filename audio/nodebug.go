@@ -0,0 +1,5 @@
+//go:build !audiodebug
+
+package audio
+
+const debug = false
@@ -0,0 +1,36 @@
+//go:build noportaudio
+
+package audio
+
+import "errors"
+
+// ErrNoPortAudio is returned by the PortAudio backend of a build with the
+// noportaudio tag.
+var ErrNoPortAudio = errors.New("portaudio is not available in this build")
+
+type portaudioBackend struct{}
+
+// PortAudio returns the backend using PortAudio. It is not available in
+// this build, use the null or file backend instead.
+func PortAudio() Backend {
+	return portaudioBackend{}
+}
+
+func (portaudioBackend) Initialize() error { return ErrNoPortAudio }
+func (portaudioBackend) Terminate() error  { return nil }
+
+func (portaudioBackend) Devices() ([]*DeviceInfo, error) {
+	return nil, ErrNoPortAudio
+}
+
+func (portaudioBackend) DefaultInputDevice() (*DeviceInfo, error) {
+	return nil, ErrNoPortAudio
+}
+
+func (portaudioBackend) DefaultOutputDevice() (*DeviceInfo, error) {
+	return nil, ErrNoPortAudio
+}
+
+func (portaudioBackend) OpenStream(params StreamParameters) (BackendStream, error) {
+	return nil, ErrNoPortAudio
+}
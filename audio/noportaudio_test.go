@@ -0,0 +1,19 @@
+//go:build noportaudio
+
+package audio
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPortAudioUnavailable(t *testing.T) {
+	useBackend(t, PortAudio())
+
+	if err := Initialize(); !errors.Is(err, ErrNoPortAudio) {
+		t.Errorf("Initialize() = %v, want %v", err, ErrNoPortAudio)
+	}
+	if _, err := Open[int16](WithDefaultOutputDevice()); !errors.Is(err, ErrNoPortAudio) {
+		t.Errorf("Open() = %v, want %v", err, ErrNoPortAudio)
+	}
+}
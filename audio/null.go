@@ -0,0 +1,136 @@
+package audio
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	nullSampleRate  = 48000
+	nullChannels    = 2
+	nullLowLatency  = 10 * time.Millisecond
+	nullHighLatency = 100 * time.Millisecond
+)
+
+type nullBackend struct {
+	name string
+}
+
+// Null returns the backend that discards output samples and reads silence
+// in real time. It has a device named "null".
+func Null() Backend {
+	return nullBackend{name: "null"}
+}
+
+func (nullBackend) Initialize() error { return nil }
+func (nullBackend) Terminate() error  { return nil }
+
+func (b nullBackend) Devices() ([]*DeviceInfo, error) {
+	return []*DeviceInfo{b.device()}, nil
+}
+
+func (b nullBackend) DefaultInputDevice() (*DeviceInfo, error) {
+	return b.device(), nil
+}
+
+func (b nullBackend) DefaultOutputDevice() (*DeviceInfo, error) {
+	return b.device(), nil
+}
+
+func (b nullBackend) OpenStream(params StreamParameters) (BackendStream, error) {
+	return newNullStream(params)
+}
+
+func (b nullBackend) device() *DeviceInfo {
+	return &DeviceInfo{
+		Name:                     b.name,
+		MaxInputChannels:         nullChannels,
+		MaxOutputChannels:        nullChannels,
+		DefaultLowInputLatency:   nullLowLatency,
+		DefaultLowOutputLatency:  nullLowLatency,
+		DefaultHighInputLatency:  nullHighLatency,
+		DefaultHighOutputLatency: nullHighLatency,
+		DefaultSampleRate:        nullSampleRate,
+	}
+}
+
+// nullStream paces Read and Write at the sample rate like a sound card.
+type nullStream struct {
+	sampleRate      float64
+	framesPerBuffer int
+	latency         time.Duration
+
+	running bool
+	start   time.Time
+	frames  int64
+}
+
+func newNullStream(params StreamParameters) (*nullStream, error) {
+	if params.SampleRate <= 0 {
+		return nil, errors.New("invalid sample rate")
+	}
+	if params.FramesPerBuffer <= 0 {
+		return nil, errors.New("invalid buffer size")
+	}
+
+	latency := params.Output.Latency
+	if params.Input.Latency > latency {
+		latency = params.Input.Latency
+	}
+	if latency <= 0 {
+		latency = nullLowLatency
+	}
+
+	return &nullStream{
+		sampleRate:      params.SampleRate,
+		framesPerBuffer: params.FramesPerBuffer,
+		latency:         latency,
+	}, nil
+}
+
+func (s *nullStream) Start() error {
+	s.running = true
+	s.start = time.Time{}
+	s.frames = 0
+	return nil
+}
+
+func (s *nullStream) Stop() error {
+	s.running = false
+	return nil
+}
+
+func (s *nullStream) Close() error {
+	return nil
+}
+
+func (s *nullStream) Read() error {
+	return s.wait(ErrInputOverflowed)
+}
+
+func (s *nullStream) Write() error {
+	return s.wait(ErrOutputOverflowed)
+}
+
+// wait blocks until the buffer is transferred. If the caller is late more
+// than the latency, the clock is reset and lost is returned.
+func (s *nullStream) wait(lost error) error {
+	if !s.running {
+		return errors.New("stream is not started")
+	}
+
+	now := time.Now()
+	if s.start.IsZero() {
+		s.start = now
+	}
+	s.frames += int64(s.framesPerBuffer)
+	due := s.start.Add(time.Duration(float64(s.frames) / s.sampleRate * float64(time.Second)))
+
+	if late := now.Sub(due); late > s.latency {
+		s.start = now
+		s.frames = 0
+		return lost
+	}
+	time.Sleep(due.Sub(now))
+	return nil
+}
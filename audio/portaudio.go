@@ -0,0 +1,131 @@
+//go:build !noportaudio
+
+package audio
+
+import (
+	"errors"
+	"unsafe"
+
+	"github.com/gordonklaus/portaudio"
+)
+
+type portaudioBackend struct{}
+
+// PortAudio returns the backend using PortAudio.
+func PortAudio() Backend {
+	return portaudioBackend{}
+}
+
+func (portaudioBackend) Initialize() error {
+	return portaudio.Initialize()
+}
+
+func (portaudioBackend) Terminate() error {
+	return portaudio.Terminate()
+}
+
+func (portaudioBackend) Devices() ([]*DeviceInfo, error) {
+	infos, err := portaudio.Devices()
+	if err != nil {
+		return nil, err
+	}
+
+	devices := make([]*DeviceInfo, len(infos))
+	for i, info := range infos {
+		devices[i] = portaudioDeviceInfo(info)
+	}
+	return devices, nil
+}
+
+func (portaudioBackend) DefaultInputDevice() (*DeviceInfo, error) {
+	info, err := portaudio.DefaultInputDevice()
+	if err != nil {
+		return nil, err
+	}
+	return portaudioDeviceInfo(info), nil
+}
+
+func (portaudioBackend) DefaultOutputDevice() (*DeviceInfo, error) {
+	info, err := portaudio.DefaultOutputDevice()
+	if err != nil {
+		return nil, err
+	}
+	return portaudioDeviceInfo(info), nil
+}
+
+func (portaudioBackend) OpenStream(params StreamParameters) (BackendStream, error) {
+	var args []any
+	if params.InputBuffer != nil {
+		args = append(args, portaudioBuffer(params.InputBuffer))
+	}
+	if params.OutputBuffer != nil {
+		args = append(args, portaudioBuffer(params.OutputBuffer))
+	}
+
+	stream, err := portaudio.OpenStream(portaudio.StreamParameters{
+		Input:           portaudioDeviceParameters(params.Input),
+		Output:          portaudioDeviceParameters(params.Output),
+		SampleRate:      params.SampleRate,
+		FramesPerBuffer: params.FramesPerBuffer,
+	}, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return portaudioStream{stream}, nil
+}
+
+type portaudioStream struct {
+	*portaudio.Stream
+}
+
+func (s portaudioStream) Read() error {
+	err := s.Stream.Read()
+	if errors.Is(err, portaudio.InputOverflowed) {
+		return ErrInputOverflowed
+	}
+	return err
+}
+
+func (s portaudioStream) Write() error {
+	err := s.Stream.Write()
+	if errors.Is(err, portaudio.OutputUnderflowed) {
+		return ErrOutputOverflowed
+	}
+	return err
+}
+
+func portaudioDeviceInfo(info *portaudio.DeviceInfo) *DeviceInfo {
+	return &DeviceInfo{
+		Name:                     info.Name,
+		MaxInputChannels:         info.MaxInputChannels,
+		MaxOutputChannels:        info.MaxOutputChannels,
+		DefaultLowInputLatency:   info.DefaultLowInputLatency,
+		DefaultLowOutputLatency:  info.DefaultLowOutputLatency,
+		DefaultHighInputLatency:  info.DefaultHighInputLatency,
+		DefaultHighOutputLatency: info.DefaultHighOutputLatency,
+		DefaultSampleRate:        info.DefaultSampleRate,
+		Handle:                   info,
+	}
+}
+
+func portaudioDeviceParameters(params StreamDeviceParameters) portaudio.StreamDeviceParameters {
+	var device *portaudio.DeviceInfo
+	if params.Device != nil {
+		device, _ = params.Device.Handle.(*portaudio.DeviceInfo)
+	}
+	return portaudio.StreamDeviceParameters{
+		Device:   device,
+		Channels: params.Channels,
+		Latency:  params.Latency,
+	}
+}
+
+// portaudioBuffer returns buf as a buffer accepted by portaudio. Int24 has
+// the same layout as portaudio.Int24, so the samples are shared.
+func portaudioBuffer(buf any) any {
+	if b, ok := buf.([]Int24); ok && len(b) > 0 {
+		return unsafe.Slice((*portaudio.Int24)(unsafe.Pointer(&b[0])), len(b))
+	}
+	return buf
+}
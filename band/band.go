@@ -0,0 +1,80 @@
+package band
+
+import (
+	"github.com/kechako/goradio/rtlfm"
+)
+
+// Band is a frequency band with its channel spacing.
+type Band struct {
+	Name       string
+	Min        rtlfm.Frequency
+	Max        rtlfm.Frequency
+	Spacing    rtlfm.Frequency
+	Modulation rtlfm.Modulation
+}
+
+func (b *Band) Contains(freq rtlfm.Frequency) bool {
+	return freq >= b.Min && freq <= b.Max
+}
+
+const (
+	kHz rtlfm.Frequency = 1000
+	MHz rtlfm.Frequency = 1000 * kHz
+)
+
+// Bands are common bands. Narrower bands come first when they overlap.
+var Bands = []*Band{
+	{Name: "AM broadcast", Min: 522 * kHz, Max: 1710 * kHz, Spacing: 9 * kHz, Modulation: rtlfm.AM},
+	{Name: "FM broadcast", Min: 76 * MHz, Max: 108 * MHz, Spacing: 100 * kHz, Modulation: rtlfm.WBFM},
+	{Name: "Airband", Min: 118 * MHz, Max: 137 * MHz, Spacing: 25 * kHz, Modulation: rtlfm.AM},
+	{Name: "2m amateur", Min: 144 * MHz, Max: 148 * MHz, Spacing: 12500, Modulation: rtlfm.FM},
+	{Name: "Marine VHF", Min: 156 * MHz, Max: 162*MHz + 25*kHz, Spacing: 25 * kHz, Modulation: rtlfm.FM},
+	{Name: "70cm amateur", Min: 430 * MHz, Max: 440 * MHz, Spacing: 12500, Modulation: rtlfm.FM},
+	{Name: "PMR446", Min: 446 * MHz, Max: 446*MHz + 200*kHz, Spacing: 12500, Modulation: rtlfm.FM},
+}
+
+// Find returns the band containing freq.
+func Find(freq rtlfm.Frequency) (*Band, bool) {
+	for _, b := range Bands {
+		if b.Contains(freq) {
+			return b, true
+		}
+	}
+	return nil, false
+}
+
+// Spacing returns the channel spacing at freq. Outside the known bands, it
+// depends on the modulation.
+func Spacing(freq rtlfm.Frequency, m rtlfm.Modulation) rtlfm.Frequency {
+	if b, ok := Find(freq); ok {
+		return b.Spacing
+	}
+	switch m {
+	case rtlfm.WBFM:
+		return 100 * kHz
+	case rtlfm.AM:
+		return 10 * kHz
+	case rtlfm.USB, rtlfm.LSB:
+		return kHz
+	default:
+		return 12500
+	}
+}
+
+// Step returns the frequency n channels away from freq. freq is snapped to
+// the channel grid of the band first.
+func Step(freq rtlfm.Frequency, m rtlfm.Modulation, n int) rtlfm.Frequency {
+	spacing := Spacing(freq, m)
+
+	var base rtlfm.Frequency
+	if b, ok := Find(freq); ok {
+		base = b.Min
+	}
+	ch := (freq - base + spacing/2) / spacing
+
+	next := base + (ch+rtlfm.Frequency(n))*spacing
+	if next <= 0 {
+		return freq
+	}
+	return next
+}
@@ -0,0 +1,88 @@
+package band
+
+import (
+	"testing"
+
+	"github.com/kechako/goradio/rtlfm"
+)
+
+func TestFind(t *testing.T) {
+	tests := []struct {
+		freq rtlfm.Frequency
+		want string
+	}{
+		{522 * kHz, "AM broadcast"},
+		{1710 * kHz, "AM broadcast"},
+		{80 * MHz, "FM broadcast"},
+		{118 * MHz, "Airband"},
+		{145500 * kHz, "2m amateur"},
+		{162*MHz + 25*kHz, "Marine VHF"},
+		{433 * MHz, "70cm amateur"},
+		{446100 * kHz, "PMR446"},
+		{521 * kHz, ""},
+		{50 * MHz, ""},
+		{440*MHz + 1, ""},
+	}
+	for _, tt := range tests {
+		b, ok := Find(tt.freq)
+		var got string
+		if ok {
+			got = b.Name
+		}
+		if got != tt.want {
+			t.Errorf("Find(%s) = %q, want %q", tt.freq, got, tt.want)
+		}
+	}
+}
+
+func TestSpacing(t *testing.T) {
+	tests := []struct {
+		freq rtlfm.Frequency
+		m    rtlfm.Modulation
+		want rtlfm.Frequency
+	}{
+		// the band takes precedence over the modulation
+		{80 * MHz, rtlfm.AM, 100 * kHz},
+		{1000 * kHz, rtlfm.WBFM, 9 * kHz},
+		{145 * MHz, rtlfm.WBFM, 12500},
+		{50 * MHz, rtlfm.WBFM, 100 * kHz},
+		{50 * MHz, rtlfm.AM, 10 * kHz},
+		{50 * MHz, rtlfm.USB, kHz},
+		{50 * MHz, rtlfm.LSB, kHz},
+		{50 * MHz, rtlfm.FM, 12500},
+	}
+	for _, tt := range tests {
+		if got := Spacing(tt.freq, tt.m); got != tt.want {
+			t.Errorf("Spacing(%s, %s) = %s, want %s", tt.freq, tt.m, got, tt.want)
+		}
+	}
+}
+
+func TestStep(t *testing.T) {
+	tests := []struct {
+		freq rtlfm.Frequency
+		m    rtlfm.Modulation
+		n    int
+		want rtlfm.Frequency
+	}{
+		{80 * MHz, rtlfm.WBFM, 1, 80100 * kHz},
+		{80 * MHz, rtlfm.WBFM, -1, 79900 * kHz},
+		{80 * MHz, rtlfm.WBFM, 10, 81 * MHz},
+		// snapped to the nearest channel
+		{80040 * kHz, rtlfm.WBFM, 0, 80 * MHz},
+		{80060 * kHz, rtlfm.WBFM, 0, 80100 * kHz},
+		// the grid of the AM band starts at its lower edge
+		{594 * kHz, rtlfm.AM, 1, 603 * kHz},
+		{145500 * kHz, rtlfm.FM, 2, 145525 * kHz},
+		// outside the bands, the grid starts at 0
+		{50 * MHz, rtlfm.FM, 1, 50*MHz + 12500},
+		{14200 * kHz, rtlfm.USB, -3, 14197 * kHz},
+		// a step below 0 Hz is ignored
+		{5 * kHz, rtlfm.USB, -10, 5 * kHz},
+	}
+	for _, tt := range tests {
+		if got := Step(tt.freq, tt.m, tt.n); got != tt.want {
+			t.Errorf("Step(%s, %s, %d) = %s, want %s", tt.freq, tt.m, tt.n, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/kechako/goradio/config"
+	cli "github.com/urfave/cli/v2"
+)
+
+const (
+	configMetadataKey      = "config"
+	commandLineMetadataKey = "commandLine"
+	flagsMetadataKey       = "flags"
+	stationMetadataKey     = "station"
+)
+
+func configFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:        "config",
+			Usage:       "config file",
+			EnvVars:     []string{"GORADIO_CONFIG"},
+			DefaultText: "$XDG_CONFIG_HOME/goradio/config.json",
+			Required:    false,
+		},
+		&cli.StringFlag{
+			Name:     "profile",
+			Usage:    "config profile to use",
+			EnvVars:  []string{"GORADIO_PROFILE"},
+			Required: false,
+		},
+	}
+}
+
+// loadConfig loads the config file and applies it to the global flags.
+func loadConfig(ctx *cli.Context) error {
+	path := ctx.String("config")
+	if path == "" {
+		var err error
+		path, err = config.DefaultPath()
+		if err != nil {
+			return err
+		}
+	}
+
+	c, err := config.Load(path)
+	if err != nil {
+		return err
+	}
+	values, err := c.Values(ctx.String("profile"))
+	if errors.Is(err, config.ErrProfileNotFound) {
+		return ArgumentError(err.Error())
+	}
+	if err != nil {
+		return err
+	}
+
+	if ctx.App.Metadata == nil {
+		ctx.App.Metadata = make(map[string]interface{})
+	}
+	ctx.App.Metadata[configMetadataKey] = values
+
+	return applyValues(ctx, ctx.App.Flags, values)
+}
+
+// setupCommands makes every command pick up the station preset and the
+// config values before its action. A flag takes the value of the command
+// line, the station, the config and the built-in default in this order.
+func setupCommands(cmds []*cli.Command) {
+	for _, cmd := range cmds {
+		cmd := cmd
+		cmd.Before = func(ctx *cli.Context) error {
+			// ctx.IsSet does not tell the flags set by the station or the
+			// config from the ones given on the command line
+			given := make(map[string]bool)
+			for _, flag := range cmd.Flags {
+				if name := flag.Names()[0]; ctx.IsSet(name) {
+					given[name] = true
+				}
+			}
+			if ctx.App.Metadata == nil {
+				ctx.App.Metadata = make(map[string]interface{})
+			}
+			ctx.App.Metadata[commandLineMetadataKey] = given
+
+			values, _ := ctx.App.Metadata[configMetadataKey].(config.Values)
+			if err := applyValues(ctx, cmd.Flags, values); err != nil {
+				return err
+			}
+
+			// the station overrides the config values, but not the flags
+			// of tunings other than the station
+			flags := make(flagValues)
+			for _, flag := range cmd.Flags {
+				name := flag.Names()[0]
+				flags[name] = ctx.Value(name)
+			}
+			ctx.App.Metadata[flagsMetadataKey] = flags
+
+			if hasFlag(cmd.Flags, "station") {
+				return applyStation(ctx)
+			}
+			return nil
+		}
+		setupCommands(cmd.Subcommands)
+	}
+}
+
+// applyValues sets flags that are not given on the command line.
+func applyValues(ctx *cli.Context, flags []cli.Flag, values config.Values) error {
+	for _, flag := range flags {
+		name := flag.Names()[0]
+		value, ok := values[name]
+		if !ok || ctx.IsSet(name) {
+			continue
+		}
+
+		var svalues []string
+		switch v := value.(type) {
+		case []any:
+			for _, e := range v {
+				svalues = append(svalues, formatValue(e))
+			}
+		default:
+			svalues = []string{formatValue(v)}
+		}
+		for _, s := range svalues {
+			if err := ctx.Set(name, s); err != nil {
+				return fmt.Errorf("invalid config value for %s: %w", name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// flagReader reads values of flags, e.g. *cli.Context.
+type flagReader interface {
+	String(name string) string
+	Int(name string) int
+	Bool(name string) bool
+}
+
+// flagValues are values of flags by name.
+type flagValues map[string]interface{}
+
+func (v flagValues) String(name string) string {
+	s, _ := v[name].(string)
+	return s
+}
+
+func (v flagValues) Int(name string) int {
+	i, _ := v[name].(int)
+	return i
+}
+
+func (v flagValues) Bool(name string) bool {
+	b, _ := v[name].(bool)
+	return b
+}
+
+// baseFlags returns the values of the flags without the station specified
+// by --station.
+func baseFlags(ctx *cli.Context) flagReader {
+	if flags, ok := ctx.App.Metadata[flagsMetadataKey].(flagValues); ok {
+		return flags
+	}
+	return ctx
+}
+
+// onCommandLine reports whether the flag is given on the command line, not
+// set by the station or the config.
+func onCommandLine(ctx *cli.Context, name string) bool {
+	given, ok := ctx.App.Metadata[commandLineMetadataKey].(map[string]bool)
+	if !ok {
+		return ctx.IsSet(name)
+	}
+	return given[name]
+}
+
+func formatValue(v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func hasFlag(flags []cli.Flag, name string) bool {
+	for _, flag := range flags {
+		for _, n := range flag.Names() {
+			if n == name {
+				return true
+			}
+		}
+	}
+	return false
+}
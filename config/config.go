@@ -0,0 +1,77 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const fileName = "config.json"
+
+var ErrProfileNotFound = errors.New("profile not found")
+
+// Values maps flag names to their default values.
+type Values map[string]any
+
+type Config struct {
+	// Profile is the profile used when no profile is specified.
+	Profile  string            `json:"profile,omitempty"`
+	Defaults Values            `json:"defaults,omitempty"`
+	Profiles map[string]Values `json:"profiles,omitempty"`
+}
+
+// DefaultPath returns the path of the config file under the user config
+// directory, e.g. $XDG_CONFIG_HOME/goradio/config.json.
+func DefaultPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get config directory: %w", err)
+	}
+	return filepath.Join(dir, "goradio", fileName), nil
+}
+
+// Load loads config from path. It returns empty config if the file does
+// not exist.
+func Load(path string) (*Config, error) {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &Config{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	var c Config
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+	return &c, nil
+}
+
+// Values returns the defaults overridden by the profile. If profile is
+// empty, the default profile of the config is used.
+func (c *Config) Values(profile string) (Values, error) {
+	if profile == "" {
+		profile = c.Profile
+	}
+
+	values := make(Values, len(c.Defaults))
+	for name, value := range c.Defaults {
+		values[name] = value
+	}
+
+	if profile == "" {
+		return values, nil
+	}
+	p, ok := c.Profiles[profile]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrProfileNotFound, profile)
+	}
+	for name, value := range p {
+		values[name] = value
+	}
+
+	return values, nil
+}
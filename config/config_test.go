@@ -0,0 +1,128 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want *Config
+		err  bool
+	}{
+		{
+			name: "empty",
+			data: `{}`,
+			want: &Config{},
+		},
+		{
+			name: "profiles",
+			data: `{
+				"profile": "night",
+				"defaults": {"ppm": 52, "deemp": true, "filter": ["highpass:80"]},
+				"profiles": {"night": {"volume": 0.3}}
+			}`,
+			want: &Config{
+				Profile: "night",
+				Defaults: Values{
+					"ppm":    float64(52),
+					"deemp":  true,
+					"filter": []any{"highpass:80"},
+				},
+				Profiles: map[string]Values{
+					"night": {"volume": 0.3},
+				},
+			},
+		},
+		{
+			name: "invalid",
+			data: `{"defaults": [1]}`,
+			err:  true,
+		},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), fileName)
+		if err := os.WriteFile(path, []byte(tt.data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := Load(path)
+		if (err != nil) != tt.err {
+			t.Errorf("%s: Load() error = %v, want error %v", tt.name, err, tt.err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: Load() = %#v, want %#v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestLoadNotExist(t *testing.T) {
+	c, err := Load(filepath.Join(t.TempDir(), fileName))
+	if err != nil || !reflect.DeepEqual(c, &Config{}) {
+		t.Errorf("Load() = %#v, %v, want empty config", c, err)
+	}
+}
+
+func TestValues(t *testing.T) {
+	c := &Config{
+		Defaults: Values{"ppm": 52, "mode": "fm"},
+		Profiles: map[string]Values{
+			"air":   {"mode": "am", "squelch": 30},
+			"empty": {},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		profile  string
+		fallback string
+		want     Values
+		err      error
+	}{
+		{"defaults", "", "", Values{"ppm": 52, "mode": "fm"}, nil},
+		{"profile", "air", "", Values{"ppm": 52, "mode": "am", "squelch": 30}, nil},
+		{"default profile", "", "air", Values{"ppm": 52, "mode": "am", "squelch": 30}, nil},
+		{"profile over default profile", "empty", "air", Values{"ppm": 52, "mode": "fm"}, nil},
+		{"missing profile", "none", "", nil, ErrProfileNotFound},
+		{"missing default profile", "", "none", nil, ErrProfileNotFound},
+	}
+	for _, tt := range tests {
+		c.Profile = tt.fallback
+		got, err := c.Values(tt.profile)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("%s: Values() error = %v, want %v", tt.name, err, tt.err)
+			continue
+		}
+		if err == nil && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: Values() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	// the defaults are not modified by profiles
+	if want := (Values{"ppm": 52, "mode": "fm"}); !reflect.DeepEqual(c.Defaults, want) {
+		t.Errorf("defaults = %v after Values(), want %v", c.Defaults, want)
+	}
+}
+
+func TestDefaultPath(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("XDG_CONFIG_HOME is used on Linux")
+	}
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+
+	path, err := DefaultPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(dir, "goradio", "config.json"); path != want {
+		t.Errorf("DefaultPath() = %q, want %q", path, want)
+	}
+}
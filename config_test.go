@@ -0,0 +1,150 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	cli "github.com/urfave/cli/v2"
+)
+
+// runWithConfig runs a command with the station and tuning flags and
+// returns its context.
+func runWithConfig(t *testing.T, config, stations string, args ...string) *cli.Context {
+	t.Helper()
+
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(configPath, []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	stationsPath := filepath.Join(dir, "stations.json")
+	if err := os.WriteFile(stationsPath, []byte(stations), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var got *cli.Context
+	app := &cli.App{
+		Name: "goradio",
+		Commands: []*cli.Command{
+			{
+				Name: "play",
+				Flags: concatFlags([]cli.Flag{
+					&cli.StringFlag{Name: "freq", Aliases: []string{"f"}},
+					&cli.StringFlag{Name: "station", Aliases: []string{"S"}},
+					filterFlag(),
+				}, tuningFlags()),
+				Action: func(ctx *cli.Context) error {
+					got = ctx
+					return nil
+				},
+			},
+		},
+		Flags: concatFlags(configFlags(), []cli.Flag{
+			&cli.StringFlag{Name: "stations"},
+		}),
+		Before: loadConfig,
+	}
+	setupCommands(app.Commands)
+
+	args = append([]string{"goradio", "--config", configPath, "--stations", stationsPath, "play"}, args...)
+	if err := app.Run(args); err != nil {
+		t.Fatal(err)
+	}
+	return got
+}
+
+func TestFlagPrecedence(t *testing.T) {
+	const config = `{"defaults": {"deemp": true, "ppm": 52, "mode": "fm", "filter": ["highpass:80"]}}`
+	const stations = `{"stations": [
+		{"name": "J-WAVE", "frequency": "81.3M"},
+		{"name": "Tokyo", "frequency": "80.0M", "mode": "wbfm", "ppm": 10, "filters": ["lowpass:3k"]}
+	]}`
+
+	tests := []struct {
+		name    string
+		args    []string
+		freq    string
+		mode    string
+		ppm     int
+		deemp   bool
+		filters string
+	}{
+		{
+			name:    "config",
+			args:    []string{"-f", "90M"},
+			freq:    "90M",
+			mode:    "fm",
+			ppm:     52,
+			deemp:   true,
+			filters: "highpass:80",
+		},
+		{
+			name: "station without settings",
+			args: []string{"--station", "J-WAVE"},
+			freq: "81.3M",
+			// settings the station does not have fall back to the config
+			mode:    "fm",
+			ppm:     52,
+			deemp:   true,
+			filters: "highpass:80",
+		},
+		{
+			name:    "station",
+			args:    []string{"--station", "Tokyo"},
+			freq:    "80.0M",
+			mode:    "wbfm",
+			ppm:     10,
+			deemp:   true,
+			filters: "lowpass:3000",
+		},
+		{
+			name:    "command line",
+			args:    []string{"--ppm", "3", "-M", "am", "--filter", "eq:1k:3", "--station", "Tokyo"},
+			freq:    "80.0M",
+			mode:    "am",
+			ppm:     3,
+			deemp:   true,
+			filters: "eq:1000:3",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := runWithConfig(t, config, stations, tt.args...)
+
+			if got := ctx.String("freq"); got != tt.freq {
+				t.Errorf("freq = %q, want %q", got, tt.freq)
+			}
+			if got := ctx.String("mode"); got != tt.mode {
+				t.Errorf("mode = %q, want %q", got, tt.mode)
+			}
+			if got := ctx.Int("ppm"); got != tt.ppm {
+				t.Errorf("ppm = %d, want %d", got, tt.ppm)
+			}
+			if got := ctx.Bool("deemp"); got != tt.deemp {
+				t.Errorf("deemp = %v, want %v", got, tt.deemp)
+			}
+
+			specs, err := stationFilters(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if specs == nil {
+				specs, err = parseFilters(ctx.StringSlice("filter"))
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			var got string
+			for i, s := range specs {
+				if i > 0 {
+					got += ","
+				}
+				got += s.String()
+			}
+			if got != tt.filters {
+				t.Errorf("filters = %q, want %q", got, tt.filters)
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package control
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+)
+
+const clientTimeout = 10 * time.Second
+
+// APIError is an error responded by the daemon.
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (err *APIError) Error() string {
+	return err.Message
+}
+
+// Client calls the control API of a daemon.
+type Client struct {
+	c       *http.Client
+	baseURL string
+}
+
+// NewClient returns a client of the daemon listening on the Unix socket at
+// path.
+func NewClient(path string) *Client {
+	d := &net.Dialer{}
+	return &Client{
+		c: &http.Client{
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					return d.DialContext(ctx, "unix", path)
+				},
+			},
+			Timeout: clientTimeout,
+		},
+		// the host is not used to connect
+		baseURL: "http://goradio",
+	}
+}
+
+func (c *Client) Status(ctx context.Context) (*Status, error) {
+	return c.call(ctx, http.MethodGet, "/status", nil)
+}
+
+func (c *Client) Tune(ctx context.Context, req TuneRequest) (*Status, error) {
+	return c.call(ctx, http.MethodPost, "/tune", req)
+}
+
+func (c *Client) Start(ctx context.Context) (*Status, error) {
+	return c.call(ctx, http.MethodPost, "/start", nil)
+}
+
+func (c *Client) Stop(ctx context.Context) (*Status, error) {
+	return c.call(ctx, http.MethodPost, "/stop", nil)
+}
+
+func (c *Client) SetMuted(ctx context.Context, muted bool) (*Status, error) {
+	return c.call(ctx, http.MethodPost, "/mute", MuteRequest{Muted: &muted})
+}
+
+func (c *Client) SetVolume(ctx context.Context, volume string) (*Status, error) {
+	return c.call(ctx, http.MethodPost, "/volume", VolumeRequest{Volume: volume})
+}
+
+func (c *Client) NextStation(ctx context.Context) (*Status, error) {
+	return c.call(ctx, http.MethodPost, "/station/next", nil)
+}
+
+func (c *Client) PrevStation(ctx context.Context) (*Status, error) {
+	return c.call(ctx, http.MethodPost, "/station/prev", nil)
+}
+
+func (c *Client) call(ctx context.Context, method, path string, body any) (*Status, error) {
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		r = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, r)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.c.Do(req)
+	if err != nil {
+		var opErr *net.OpError
+		if errors.As(err, &opErr) && opErr.Op == "dial" {
+			return nil, fmt.Errorf("failed to connect to daemon: %w", opErr.Err)
+		}
+		return nil, fmt.Errorf("failed to call daemon: %w", err)
+	}
+	defer resp.Body.Close()
+
+	dec := json.NewDecoder(io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode != http.StatusOK {
+		var e errorResponse
+		if err := dec.Decode(&e); err != nil || e.Error == "" {
+			e.Error = resp.Status
+		}
+		return nil, &APIError{StatusCode: resp.StatusCode, Message: e.Error}
+	}
+
+	var st Status
+	if err := dec.Decode(&st); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return &st, nil
+}
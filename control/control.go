@@ -0,0 +1,450 @@
+package control
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/kechako/goradio/player"
+	"github.com/kechako/goradio/rtlfm"
+	"github.com/kechako/goradio/station"
+	"github.com/kechako/goradio/volume"
+)
+
+// StationsFunc loads the station presets. It is called for each request so
+// that edits of presets take effect without restarting.
+type StationsFunc func() (*station.Presets, error)
+
+// Handler serves the HTTP/JSON API to control a player.
+//
+//	GET  /status   current status
+//	POST /tune     {"frequency": "81.3M"} or {"station": "NAME"}, optionally
+//	               with "mode" and "gain"
+//	POST /mode     {"mode": "fm"}
+//	POST /gain     {"gain": "49.6"} or {"gain": "auto"}
+//	POST /start
+//	POST /stop
+//	POST /mute     {"muted": false} to unmute
+//	POST /volume   {"volume": "-6dB"}
+//	POST /station/next
+//	POST /station/prev
+//	GET  /events   server-sent events
+//
+// POST requests respond with the status after the change.
+type Handler struct {
+	p        *player.Player
+	stations StationsFunc
+	mux      *http.ServeMux
+}
+
+func NewHandler(p *player.Player, opts ...Option) *Handler {
+	var options handlerOptions
+	for _, opt := range opts {
+		opt.apply(&options)
+	}
+
+	h := &Handler{
+		p:        p,
+		stations: options.stations,
+		mux:      http.NewServeMux(),
+	}
+	h.mux.HandleFunc("/status", h.handleStatus)
+	h.mux.HandleFunc("/tune", h.handleTune)
+	h.mux.HandleFunc("/mode", h.handleMode)
+	h.mux.HandleFunc("/gain", h.handleGain)
+	h.mux.HandleFunc("/start", h.handleStart)
+	h.mux.HandleFunc("/stop", h.handleStop)
+	h.mux.HandleFunc("/mute", h.handleMute)
+	h.mux.HandleFunc("/volume", h.handleVolume)
+	h.mux.HandleFunc("/station/next", h.handleStationStep(1))
+	h.mux.HandleFunc("/station/prev", h.handleStationStep(-1))
+	h.mux.HandleFunc("/events", h.handleEvents)
+
+	return h
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+func (h *Handler) handleStatus(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+	h.writeStatus(w)
+}
+
+// TuneRequest is the body of /tune. Empty fields are not changed.
+type TuneRequest struct {
+	Frequency string `json:"frequency,omitempty"`
+	Station   string `json:"station,omitempty"`
+	Mode      string `json:"mode,omitempty"`
+	Gain      string `json:"gain,omitempty"`
+}
+
+func (h *Handler) handleTune(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodPost) {
+		return
+	}
+
+	var req TuneRequest
+	if err := decodeRequest(r, &req); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	h.tune(w, req)
+}
+
+type ModeRequest struct {
+	Mode string `json:"mode"`
+}
+
+func (h *Handler) handleMode(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodPost) {
+		return
+	}
+
+	var req ModeRequest
+	if err := decodeRequest(r, &req); err != nil {
+		writeError(w, err)
+		return
+	}
+	if req.Mode == "" {
+		writeError(w, badRequest("mode is not specified"))
+		return
+	}
+
+	h.tune(w, TuneRequest{Mode: req.Mode})
+}
+
+type GainRequest struct {
+	Gain string `json:"gain"`
+}
+
+func (h *Handler) handleGain(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodPost) {
+		return
+	}
+
+	var req GainRequest
+	if err := decodeRequest(r, &req); err != nil {
+		writeError(w, err)
+		return
+	}
+	if req.Gain == "" {
+		writeError(w, badRequest("gain is not specified"))
+		return
+	}
+
+	h.tune(w, TuneRequest{Gain: req.Gain})
+}
+
+// tune tunes the player by req and writes the status.
+func (h *Handler) tune(w http.ResponseWriter, req TuneRequest) {
+	t, err := h.tuning(req)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if err := h.p.Tune(t); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	h.writeStatus(w)
+}
+
+// tuning returns the current tuning updated by req.
+func (h *Handler) tuning(req TuneRequest) (player.Tuning, error) {
+	t := h.p.Tuning()
+
+	switch {
+	case req.Station != "" && req.Frequency != "":
+		return t, badRequest("frequency and station cannot be specified together")
+	case req.Station != "":
+		st, err := h.findStation(req.Station)
+		if err != nil {
+			return t, err
+		}
+		t = t.WithStation(st)
+	case req.Frequency != "":
+		freq, err := rtlfm.ParseFrequency(req.Frequency)
+		if err != nil {
+			return t, badRequest("invalid frequency")
+		}
+		// settings of the station do not apply to other frequencies
+		t.Frequency = freq
+		t.Station = ""
+		t.Preset = nil
+		t.Filters = nil
+	}
+
+	if req.Mode != "" {
+		m, err := rtlfm.ParseModulation(req.Mode)
+		if err != nil {
+			return t, badRequest("invalid demodulation mode")
+		}
+		t.Modulation = m
+	}
+	if req.Gain != "" {
+		gain, auto, err := rtlfm.ParseGain(req.Gain)
+		if err != nil {
+			return t, badRequest("invalid gain")
+		}
+		if auto {
+			t.Gain = nil
+		} else {
+			t.Gain = &gain
+		}
+	}
+
+	if err := rtlfm.ValidateOptions(t.RTLFMOptions()...); err != nil {
+		return t, badRequest(err.Error())
+	}
+
+	return t, nil
+}
+
+func (h *Handler) findStation(name string) (*station.Station, error) {
+	presets, err := h.presets()
+	if err != nil {
+		return nil, err
+	}
+	st, err := presets.Find(name)
+	if errors.Is(err, station.ErrStationNotFound) {
+		return nil, notFound(fmt.Sprintf("station %q is not found", name))
+	}
+	return st, err
+}
+
+func (h *Handler) presets() (*station.Presets, error) {
+	if h.stations == nil {
+		return &station.Presets{}, nil
+	}
+	return h.stations()
+}
+
+// handleStationStep tunes to the station step positions away from the
+// current one in the presets. It wraps around at both ends.
+func (h *Handler) handleStationStep(step int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !allowMethod(w, r, http.MethodPost) {
+			return
+		}
+
+		presets, err := h.presets()
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		n := len(presets.Stations)
+		if n == 0 {
+			writeError(w, notFound("no stations"))
+			return
+		}
+
+		// start from the first or the last station if not tuned to a station
+		i := -1
+		if step < 0 {
+			i = n
+		}
+		if name := h.p.Tuning().Station; name != "" {
+			for j, st := range presets.Stations {
+				if strings.EqualFold(st.Name, name) {
+					i = j
+					break
+				}
+			}
+		}
+		i = ((i+step)%n + n) % n
+
+		t, err := h.tuning(TuneRequest{Station: presets.Stations[i].Name})
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		if err := h.p.Tune(t); err != nil {
+			writeError(w, err)
+			return
+		}
+
+		h.writeStatus(w)
+	}
+}
+
+func (h *Handler) handleStart(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodPost) {
+		return
+	}
+	if err := h.p.Start(); err != nil {
+		writeError(w, err)
+		return
+	}
+	h.writeStatus(w)
+}
+
+func (h *Handler) handleStop(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodPost) {
+		return
+	}
+	if err := h.p.Stop(); err != nil {
+		writeError(w, err)
+		return
+	}
+	h.writeStatus(w)
+}
+
+type MuteRequest struct {
+	Muted *bool `json:"muted,omitempty"`
+}
+
+func (h *Handler) handleMute(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodPost) {
+		return
+	}
+
+	var req MuteRequest
+	if err := decodeRequest(r, &req); err != nil {
+		writeError(w, err)
+		return
+	}
+	muted := true
+	if req.Muted != nil {
+		muted = *req.Muted
+	}
+	h.p.SetMuted(muted)
+
+	h.writeStatus(w)
+}
+
+type VolumeRequest struct {
+	Volume string `json:"volume"`
+}
+
+func (h *Handler) handleVolume(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodPost) {
+		return
+	}
+
+	var req VolumeRequest
+	if err := decodeRequest(r, &req); err != nil {
+		writeError(w, err)
+		return
+	}
+	if req.Volume == "" {
+		writeError(w, badRequest("volume is not specified"))
+		return
+	}
+	db, err := volume.ParseDB(req.Volume)
+	if err != nil {
+		writeError(w, badRequest(fmt.Sprintf("invalid volume, must be from %gdB to %gdB", volume.MinDB, volume.MaxDB)))
+		return
+	}
+	h.p.SetVolume(db)
+
+	h.writeStatus(w)
+}
+
+func (h *Handler) writeStatus(w http.ResponseWriter) {
+	writeJSON(w, http.StatusOK, newStatus(h.p.Status()))
+}
+
+// decodeRequest decodes the JSON body of r into v. An empty body is
+// allowed.
+func decodeRequest(r *http.Request, v any) error {
+	dec := json.NewDecoder(io.LimitReader(r.Body, 1<<16))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil && !errors.Is(err, io.EOF) {
+		return badRequest(fmt.Sprintf("invalid request: %v", err))
+	}
+	return nil
+}
+
+func allowMethod(w http.ResponseWriter, r *http.Request, method string) bool {
+	if r.Method == method || method == http.MethodGet && r.Method == http.MethodHead {
+		return true
+	}
+	allow := method
+	if method == http.MethodGet {
+		allow = "GET, HEAD"
+	}
+	w.Header().Set("Allow", allow)
+	writeJSON(w, http.StatusMethodNotAllowed, errorResponse{Error: strings.ToLower(http.StatusText(http.StatusMethodNotAllowed))})
+	return false
+}
+
+// requestError is an error with the status code of the response.
+type requestError struct {
+	status int
+	msg    string
+}
+
+func (err *requestError) Error() string {
+	return err.msg
+}
+
+func badRequest(msg string) error {
+	return &requestError{status: http.StatusBadRequest, msg: msg}
+}
+
+func notFound(msg string) error {
+	return &requestError{status: http.StatusNotFound, msg: msg}
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	var reqErr *requestError
+	switch {
+	case errors.As(err, &reqErr):
+		status = reqErr.status
+	case errors.Is(err, player.ErrNotTuned):
+		status = http.StatusConflict
+	case errors.Is(err, player.ErrClosed):
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	b = append(b, '\n')
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Length", strconv.Itoa(len(b)))
+	w.WriteHeader(status)
+	w.Write(b)
+}
+
+type handlerOptions struct {
+	stations StationsFunc
+}
+
+type Option interface {
+	apply(opts *handlerOptions)
+}
+
+type optionFunc func(opts *handlerOptions)
+
+func (f optionFunc) apply(opts *handlerOptions) {
+	f(opts)
+}
+
+// WithStations enables tuning to station presets.
+func WithStations(stations StationsFunc) Option {
+	return optionFunc(func(opts *handlerOptions) {
+		opts.stations = stations
+	})
+}
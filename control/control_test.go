@@ -0,0 +1,240 @@
+package control
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kechako/goradio/player"
+	"github.com/kechako/goradio/rtlfm"
+	"github.com/kechako/goradio/source"
+	"github.com/kechako/goradio/station"
+)
+
+// newTestPlayer returns a player that is never started, so it needs no
+// audio stream.
+func newTestPlayer(t *testing.T) *player.Player {
+	t.Helper()
+
+	open := func(player.Tuning) (source.Source, error) {
+		return nil, errors.New("not supported in tests")
+	}
+	p := player.New(nil, 48000, 480, open)
+	t.Cleanup(func() {
+		p.Close()
+	})
+	return p
+}
+
+// presets returns a StationsFunc loading stations.
+func presets(stations ...*station.Station) StationsFunc {
+	return func() (*station.Presets, error) {
+		return &station.Presets{Stations: stations}, nil
+	}
+}
+
+func serve(h http.Handler, method, path, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestHandler(t *testing.T) {
+	gain := 49.6
+	nhk := player.Tuning{Frequency: 82500 * rtlfm.KiloHertz, Station: "NHK-FM"}
+	interfm := player.Tuning{Frequency: 89700 * rtlfm.KiloHertz, Station: "InterFM"}
+	stations := presets(
+		&station.Station{Name: "NHK-FM", Frequency: nhk.Frequency},
+		&station.Station{Name: "InterFM", Frequency: interfm.Frequency, Mode: rtlfm.WBFM},
+	)
+
+	tests := []struct {
+		name   string
+		tuning player.Tuning
+		method string
+		path   string
+		body   string
+		want   int
+		allow  string
+		check  func(st Status) bool
+	}{
+		{"status", player.Tuning{}, "GET", "/status", "", http.StatusOK, "", func(st Status) bool {
+			return st.State == player.Stopped && st.Tuning == nil && st.Volume == 0
+		}},
+		{"status head", player.Tuning{}, "HEAD", "/status", "", http.StatusOK, "", nil},
+		{"status post", player.Tuning{}, "POST", "/status", "", http.StatusMethodNotAllowed, "GET, HEAD", nil},
+		{"events post", player.Tuning{}, "POST", "/events", "", http.StatusMethodNotAllowed, "GET, HEAD", nil},
+		{"tune get", player.Tuning{}, "GET", "/tune", "", http.StatusMethodNotAllowed, "POST", nil},
+
+		{"tune frequency", nhk, "POST", "/tune", `{"frequency": "81.3M"}`, http.StatusOK, "", func(st Status) bool {
+			return st.Tuning != nil && st.Tuning.Frequency == 81300*rtlfm.KiloHertz && st.Tuning.Station == ""
+		}},
+		{"tune station", player.Tuning{}, "POST", "/tune", `{"station": "interfm"}`, http.StatusOK, "", func(st Status) bool {
+			return st.Tuning != nil && st.Tuning.Frequency == interfm.Frequency && st.Tuning.Station == "InterFM"
+		}},
+		{"tune frequency and station", player.Tuning{}, "POST", "/tune", `{"frequency": "81.3M", "station": "InterFM"}`, http.StatusBadRequest, "", nil},
+		{"tune invalid frequency", player.Tuning{}, "POST", "/tune", `{"frequency": "FM"}`, http.StatusBadRequest, "", nil},
+		{"tune unknown station", player.Tuning{}, "POST", "/tune", `{"station": "J-WAVE"}`, http.StatusNotFound, "", nil},
+		{"tune unknown field", player.Tuning{}, "POST", "/tune", `{"freq": "81.3M"}`, http.StatusBadRequest, "", nil},
+		{"tune invalid JSON", player.Tuning{}, "POST", "/tune", `{`, http.StatusBadRequest, "", nil},
+
+		{"mode", nhk, "POST", "/mode", `{"mode": "am"}`, http.StatusOK, "", func(st Status) bool {
+			return st.Tuning != nil && st.Tuning.Mode == rtlfm.AM && st.Tuning.Frequency == nhk.Frequency
+		}},
+		{"invalid mode", nhk, "POST", "/mode", `{"mode": "dsb"}`, http.StatusBadRequest, "", nil},
+		{"no mode", nhk, "POST", "/mode", `{}`, http.StatusBadRequest, "", nil},
+		{"mode with frequency", nhk, "POST", "/mode", `{"mode": "am", "frequency": "81.3M"}`, http.StatusBadRequest, "", nil},
+		{"mode with station", nhk, "POST", "/mode", `{"station": "InterFM"}`, http.StatusBadRequest, "", nil},
+		{"gain", nhk, "POST", "/gain", `{"gain": "49.6"}`, http.StatusOK, "", func(st Status) bool {
+			return st.Tuning != nil && st.Tuning.Gain != nil && *st.Tuning.Gain == 49.6
+		}},
+		{"auto gain", player.Tuning{Frequency: 80 * rtlfm.MegaHertz, Gain: &gain}, "POST", "/gain", `{"gain": "auto"}`, http.StatusOK, "", func(st Status) bool {
+			return st.Tuning != nil && st.Tuning.Gain == nil
+		}},
+		{"unsupported gain", nhk, "POST", "/gain", `{"gain": "1.23"}`, http.StatusBadRequest, "", nil},
+		{"no gain", nhk, "POST", "/gain", `{}`, http.StatusBadRequest, "", nil},
+		{"gain with frequency", nhk, "POST", "/gain", `{"gain": "auto", "frequency": "81.3M"}`, http.StatusBadRequest, "", nil},
+		{"gain with mode", nhk, "POST", "/gain", `{"gain": "auto", "mode": "am"}`, http.StatusBadRequest, "", nil},
+
+		{"start not tuned", player.Tuning{}, "POST", "/start", "", http.StatusConflict, "", nil},
+		{"stop", nhk, "POST", "/stop", "", http.StatusOK, "", func(st Status) bool {
+			return st.State == player.Stopped
+		}},
+
+		{"mute", player.Tuning{}, "POST", "/mute", "", http.StatusOK, "", func(st Status) bool {
+			return st.Muted
+		}},
+		{"unmute", player.Tuning{}, "POST", "/mute", `{"muted": false}`, http.StatusOK, "", func(st Status) bool {
+			return !st.Muted
+		}},
+		{"volume", player.Tuning{}, "POST", "/volume", `{"volume": "-6dB"}`, http.StatusOK, "", func(st Status) bool {
+			return st.Volume == -6
+		}},
+		{"no volume", player.Tuning{}, "POST", "/volume", `{}`, http.StatusBadRequest, "", nil},
+		{"invalid volume", player.Tuning{}, "POST", "/volume", `{"volume": "loud"}`, http.StatusBadRequest, "", nil},
+		{"volume out of range", player.Tuning{}, "POST", "/volume", `{"volume": "+100dB"}`, http.StatusBadRequest, "", nil},
+
+		{"next station", player.Tuning{}, "POST", "/station/next", "", http.StatusOK, "", func(st Status) bool {
+			return st.Tuning != nil && st.Tuning.Station == "NHK-FM"
+		}},
+		{"next station wraps", interfm, "POST", "/station/next", "", http.StatusOK, "", func(st Status) bool {
+			return st.Tuning != nil && st.Tuning.Station == "NHK-FM"
+		}},
+		{"prev station", player.Tuning{}, "POST", "/station/prev", "", http.StatusOK, "", func(st Status) bool {
+			return st.Tuning != nil && st.Tuning.Station == "InterFM"
+		}},
+		{"prev station wraps", nhk, "POST", "/station/prev", "", http.StatusOK, "", func(st Status) bool {
+			return st.Tuning != nil && st.Tuning.Station == "InterFM"
+		}},
+		{"next station get", player.Tuning{}, "GET", "/station/next", "", http.StatusMethodNotAllowed, "POST", nil},
+	}
+	for _, tt := range tests {
+		p := newTestPlayer(t)
+		if tt.tuning.Frequency != 0 {
+			if err := p.Tune(tt.tuning); err != nil {
+				t.Fatal(err)
+			}
+		}
+		h := NewHandler(p, WithStations(stations))
+
+		w := serve(h, tt.method, tt.path, tt.body)
+		if w.Code != tt.want {
+			t.Errorf("%s: %s %s = %d, want %d: %s", tt.name, tt.method, tt.path, w.Code, tt.want, w.Body)
+			continue
+		}
+		if got := w.Header().Get("Allow"); got != tt.allow {
+			t.Errorf("%s: Allow = %q, want %q", tt.name, got, tt.allow)
+		}
+		if got := w.Header().Get("Content-Type"); got != "application/json" {
+			t.Errorf("%s: Content-Type = %q, want %q", tt.name, got, "application/json")
+		}
+		if tt.check == nil {
+			continue
+		}
+
+		var st Status
+		if err := json.NewDecoder(w.Body).Decode(&st); err != nil {
+			t.Errorf("%s: invalid status: %v", tt.name, err)
+			continue
+		}
+		if !tt.check(st) {
+			t.Errorf("%s: unexpected status %+v", tt.name, st)
+		}
+	}
+}
+
+func TestHandlerNoStations(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+	}{
+		{"without presets", nil},
+		{"empty presets", []Option{WithStations(presets())}},
+	}
+	for _, tt := range tests {
+		h := NewHandler(newTestPlayer(t), tt.opts...)
+		for _, path := range []string{"/station/next", "/station/prev"} {
+			if w := serve(h, "POST", path, ""); w.Code != http.StatusNotFound {
+				t.Errorf("%s: POST %s = %d, want %d", tt.name, path, w.Code, http.StatusNotFound)
+			}
+		}
+		if w := serve(h, "POST", "/tune", `{"station": "NHK-FM"}`); w.Code != http.StatusNotFound {
+			t.Errorf("%s: POST /tune = %d, want %d", tt.name, w.Code, http.StatusNotFound)
+		}
+	}
+}
+
+func TestHandlerClosed(t *testing.T) {
+	p := newTestPlayer(t)
+	p.Close()
+	h := NewHandler(p)
+
+	tests := []struct {
+		path string
+		body string
+	}{
+		{"/tune", `{"frequency": "80M"}`},
+		{"/start", ""},
+	}
+	for _, tt := range tests {
+		w := serve(h, "POST", tt.path, tt.body)
+		if w.Code != http.StatusServiceUnavailable {
+			t.Errorf("POST %s = %d, want %d", tt.path, w.Code, http.StatusServiceUnavailable)
+		}
+
+		var res errorResponse
+		if err := json.NewDecoder(w.Body).Decode(&res); err != nil || res.Error != player.ErrClosed.Error() {
+			t.Errorf("POST %s error = %q, %v, want %q", tt.path, res.Error, err, player.ErrClosed)
+		}
+	}
+}
+
+func TestHandlerEvents(t *testing.T) {
+	p := newTestPlayer(t)
+	srv := httptest.NewServer(NewHandler(p))
+	defer srv.Close()
+
+	res, err := http.Get(srv.URL + "/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	if got := res.Header.Get("Content-Type"); got != "text/event-stream" {
+		t.Errorf("Content-Type = %q, want %q", got, "text/event-stream")
+	}
+
+	// the current status comes first
+	line, err := bufio.NewReader(res.Body).ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := line; got != "event: status\n" {
+		t.Errorf("first line = %q, want %q", got, "event: status\n")
+	}
+}
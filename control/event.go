@@ -0,0 +1,207 @@
+package control
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/kechako/goradio/player"
+	"github.com/kechako/goradio/rtlfm"
+)
+
+const keepAliveInterval = 15 * time.Second
+
+// Tuning is the JSON representation of player.Tuning.
+type Tuning struct {
+	Frequency rtlfm.Frequency  `json:"frequency"`
+	Mode      rtlfm.Modulation `json:"mode"`
+	// Gain is null for auto gain.
+	Gain    *float64 `json:"gain"`
+	Station string   `json:"station,omitempty"`
+}
+
+func newTuning(t player.Tuning) Tuning {
+	mode := t.Modulation
+	if mode == 0 {
+		mode = rtlfm.WBFM
+	}
+	return Tuning{
+		Frequency: t.Frequency,
+		Mode:      mode,
+		Gain:      t.Gain,
+		Station:   t.Station,
+	}
+}
+
+// Status is the JSON representation of player.Status.
+type Status struct {
+	State  player.State `json:"state"`
+	Tuning *Tuning      `json:"tuning,omitempty"`
+	Muted  bool         `json:"muted"`
+	// Volume is the output volume in dB.
+	Volume      float64 `json:"volume"`
+	SquelchOpen bool    `json:"squelch_open"`
+	Restarts    int     `json:"restarts"`
+	// Level is the peak level of the latest audio from 0 to 1.
+	Level float64 `json:"level"`
+	// BufferedMs is the audio in the jitter buffer in milliseconds.
+	BufferedMs int64  `json:"buffered_ms"`
+	Prefills   int    `json:"prefills"`
+	Underruns  int    `json:"underruns"`
+	Overruns   int    `json:"overruns"`
+	Error      string `json:"error,omitempty"`
+}
+
+func newStatus(st player.Status) Status {
+	s := Status{
+		State:       st.State,
+		Muted:       st.Muted,
+		Volume:      st.Volume,
+		SquelchOpen: st.SquelchOpen,
+		Restarts:    st.Restarts,
+		Level:       st.Level,
+		BufferedMs:  st.Buffered.Milliseconds(),
+		Prefills:    st.Stats.Prefills,
+		Underruns:   st.Stats.Underruns,
+		Overruns:    st.Stats.Overruns,
+	}
+	if st.Tuning.Frequency != 0 {
+		t := newTuning(st.Tuning)
+		s.Tuning = &t
+	}
+	if st.Err != nil {
+		s.Error = st.Err.Error()
+	}
+	return s
+}
+
+// handleEvents streams events of the player as server-sent events. The
+// current status is sent first as a status event.
+func (h *Handler) handleEvents(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, fmt.Errorf("streaming is not supported"))
+		return
+	}
+
+	sub := h.p.Subscribe()
+	defer sub.Close()
+
+	header := w.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	if r.Method == http.MethodHead {
+		return
+	}
+
+	if err := writeEvent(w, "status", newStatus(h.p.Status())); err != nil {
+		return
+	}
+	flusher.Flush()
+
+	ticker := time.NewTicker(keepAliveInterval)
+	defer ticker.Stop()
+
+	ctx := r.Context()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		case ev, ok := <-sub.Events():
+			if !ok {
+				return
+			}
+			name, data := eventData(ev)
+			if name == "" {
+				continue
+			}
+			if err := writeEvent(w, name, data); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+func writeEvent(w http.ResponseWriter, name string, data any) error {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", name, b)
+	return err
+}
+
+type errorData struct {
+	Error string `json:"error,omitempty"`
+}
+
+func errorString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+// eventData returns the SSE name and the JSON data of ev. It returns an
+// empty name for events that are not sent.
+func eventData(ev player.Event) (string, any) {
+	switch ev := ev.(type) {
+	case player.StartedEvent:
+		return "started", newTuning(ev.Tuning)
+	case player.StoppedEvent:
+		return "stopped", errorData{Error: errorString(ev.Err)}
+	case player.TunedEvent:
+		return "tuned", newTuning(ev.Tuning)
+	case player.MutedEvent:
+		return "muted", struct {
+			Muted bool `json:"muted"`
+		}{ev.Muted}
+	case player.VolumeEvent:
+		return "volume", struct {
+			Volume float64 `json:"volume"`
+		}{ev.Volume}
+	case player.SquelchEvent:
+		return "squelch", struct {
+			Open bool `json:"open"`
+		}{ev.Open}
+	case player.UnderrunEvent:
+		return "underrun", struct {
+			Underruns int `json:"underruns"`
+		}{ev.Underruns}
+	case player.SourceEvent:
+		return sourceEventData(ev.Event)
+	}
+	return "", nil
+}
+
+func sourceEventData(ev rtlfm.Event) (string, any) {
+	switch ev := ev.(type) {
+	case rtlfm.RestartEvent:
+		return "restarted", struct {
+			Restarts int    `json:"restarts"`
+			Error    string `json:"error,omitempty"`
+		}{ev.Restarts, errorString(ev.Err)}
+	case rtlfm.DeviceOpenedEvent:
+		return "device", struct {
+			Index int    `json:"index"`
+			Name  string `json:"name"`
+		}{ev.Index, ev.Name}
+	case rtlfm.TunerEvent:
+		return "tuner", struct {
+			Tuner string `json:"tuner"`
+		}{ev.Tuner}
+	case rtlfm.ErrorEvent:
+		return "error", errorData{Error: errorString(ev.Err)}
+	}
+	return "", nil
+}
@@ -0,0 +1,76 @@
+package control
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+)
+
+// DefaultSocketPath returns the path of the Unix socket of the daemon,
+// $XDG_RUNTIME_DIR/goradio.sock or a per-user file in the temporary
+// directory.
+func DefaultSocketPath() string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, "goradio.sock")
+	}
+	return filepath.Join(os.TempDir(), fmt.Sprintf("goradio-%d.sock", os.Getuid()))
+}
+
+// ListenUnix listens on the Unix socket at path, which is accessible only by
+// the user. A socket left by a daemon that is not running is removed.
+func ListenUnix(path string) (net.Listener, error) {
+	if fi, err := os.Lstat(path); err == nil {
+		if fi.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("failed to listen: %s is not a socket", path)
+		}
+		if conn, err := net.Dial("unix", path); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("failed to listen: daemon is already running on %s", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("failed to remove stale socket: %w", err)
+		}
+	}
+
+	// the socket is created in a private directory and moved to path, so
+	// that it is never accessible by others
+	dir, err := os.MkdirTemp(filepath.Dir(path), ".goradio-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	tmp := filepath.Join(dir, "sock")
+	l, err := net.ListenUnix("unix", &net.UnixAddr{Name: tmp, Net: "unix"})
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen: %w", err)
+	}
+	// the socket is removed from path by Close
+	l.SetUnlinkOnClose(false)
+	if err := os.Chmod(tmp, 0o600); err != nil {
+		l.Close()
+		return nil, fmt.Errorf("failed to listen: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		l.Close()
+		return nil, fmt.Errorf("failed to listen: %w", err)
+	}
+
+	return &unixListener{UnixListener: l, path: path}, nil
+}
+
+type unixListener struct {
+	*net.UnixListener
+	path string
+}
+
+func (l *unixListener) Addr() net.Addr {
+	return &net.UnixAddr{Name: l.path, Net: "unix"}
+}
+
+func (l *unixListener) Close() error {
+	err := l.UnixListener.Close()
+	os.Remove(l.path)
+	return err
+}
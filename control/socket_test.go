@@ -0,0 +1,80 @@
+package control
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestListenUnix(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "goradio.sock")
+
+	l, err := ListenUnix(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fi, err := os.Lstat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode()&os.ModeSocket == 0 || fi.Mode().Perm() != 0o600 {
+		t.Errorf("mode = %v, want a socket with 0600", fi.Mode())
+	}
+	if got := l.Addr().String(); got != path {
+		t.Errorf("Addr() = %q, want %q", got, path)
+	}
+	// no private directory is left
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("%d files in the directory, want 1", len(entries))
+	}
+
+	go func() {
+		if conn, err := l.Accept(); err == nil {
+			conn.Close()
+		}
+	}()
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+
+	if _, err := ListenUnix(path); err == nil || !strings.Contains(err.Error(), "already running") {
+		t.Errorf("ListenUnix() of a running daemon = %v, want already running", err)
+	}
+
+	l.Close()
+	if _, err := os.Lstat(path); !os.IsNotExist(err) {
+		t.Errorf("socket after Close() = %v, want removed", err)
+	}
+}
+
+func TestListenUnixStale(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "goradio.sock")
+
+	// a socket left by a daemon that crashed
+	stale, err := net.ListenUnix("unix", &net.UnixAddr{Name: path, Net: "unix"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale.SetUnlinkOnClose(false)
+	stale.Close()
+
+	l, err := ListenUnix(path)
+	if err != nil {
+		t.Fatalf("ListenUnix() with a stale socket = %v", err)
+	}
+	l.Close()
+
+	notSocket := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(notSocket, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ListenUnix(notSocket); err == nil {
+		t.Error("ListenUnix() of a regular file = nil error")
+	}
+}
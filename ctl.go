@@ -0,0 +1,205 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/kechako/goradio/control"
+	"github.com/kechako/goradio/volume"
+	cli "github.com/urfave/cli/v2"
+)
+
+func socketFlag() cli.Flag {
+	return &cli.StringFlag{
+		Name:        "socket",
+		Usage:       "Unix socket of the daemon",
+		EnvVars:     []string{"GORADIO_SOCKET"},
+		DefaultText: "$XDG_RUNTIME_DIR/goradio.sock",
+		Required:    false,
+	}
+}
+
+func ctlClient(ctx *cli.Context) *control.Client {
+	path := ctx.String("socket")
+	if path == "" {
+		path = control.DefaultSocketPath()
+	}
+	return control.NewClient(path)
+}
+
+func ctlStatusCommand(ctx *cli.Context) error {
+	if ctx.NArg() > 0 {
+		return ArgumentError("too many arguments")
+	}
+
+	st, err := ctlClient(ctx).Status(ctx.Context)
+	if err != nil {
+		return ctlError(err)
+	}
+
+	fmt.Println(formatStatus(st))
+	fmt.Printf("volume: %s\n", formatVolume(st.Volume))
+	if st.Tuning != nil {
+		gain := "auto"
+		if st.Tuning.Gain != nil {
+			gain = fmt.Sprintf("%gdB", *st.Tuning.Gain)
+		}
+		fmt.Printf("gain: %s\n", gain)
+	}
+	if st.State == "playing" {
+		squelch := "closed"
+		if st.SquelchOpen {
+			squelch = "open"
+		}
+		fmt.Printf("squelch: %s\n", squelch)
+		fmt.Printf("buffer: %dms (%d prefills, %d underruns, %d overruns)\n", st.BufferedMs, st.Prefills, st.Underruns, st.Overruns)
+		fmt.Printf("restarts: %d\n", st.Restarts)
+	}
+	if st.Error != "" {
+		fmt.Printf("error: %s\n", st.Error)
+	}
+
+	return nil
+}
+
+func ctlTuneCommand(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return ArgumentError("frequency is not specified")
+	}
+
+	st, err := ctlClient(ctx).Tune(ctx.Context, control.TuneRequest{
+		Frequency: ctx.Args().First(),
+		Mode:      ctx.String("mode"),
+		Gain:      ctx.String("gain"),
+	})
+	if err != nil {
+		return ctlError(err)
+	}
+
+	fmt.Println(formatStatus(st))
+	return nil
+}
+
+func ctlStationCommand(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return ArgumentError("station is not specified")
+	}
+
+	c := ctlClient(ctx)
+	var (
+		st  *control.Status
+		err error
+	)
+	switch name := ctx.Args().First(); strings.ToLower(name) {
+	case "next":
+		st, err = c.NextStation(ctx.Context)
+	case "prev":
+		st, err = c.PrevStation(ctx.Context)
+	default:
+		st, err = c.Tune(ctx.Context, control.TuneRequest{Station: name})
+	}
+	if err != nil {
+		return ctlError(err)
+	}
+
+	fmt.Println(formatStatus(st))
+	return nil
+}
+
+func ctlStartCommand(ctx *cli.Context) error {
+	st, err := ctlClient(ctx).Start(ctx.Context)
+	if err != nil {
+		return ctlError(err)
+	}
+
+	fmt.Println(formatStatus(st))
+	return nil
+}
+
+func ctlStopCommand(ctx *cli.Context) error {
+	st, err := ctlClient(ctx).Stop(ctx.Context)
+	if err != nil {
+		return ctlError(err)
+	}
+
+	fmt.Println(formatStatus(st))
+	return nil
+}
+
+func ctlMuteCommand(muted bool) cli.ActionFunc {
+	return func(ctx *cli.Context) error {
+		st, err := ctlClient(ctx).SetMuted(ctx.Context, muted)
+		if err != nil {
+			return ctlError(err)
+		}
+
+		fmt.Println(formatStatus(st))
+		return nil
+	}
+}
+
+// ctlVolumeCommand sets the volume, or steps it up or down from the
+// current volume.
+func ctlVolumeCommand(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return ArgumentError("volume is not specified")
+	}
+
+	c := ctlClient(ctx)
+	v := ctx.Args().First()
+	if step, ok := ctlVolumeSteps[strings.ToLower(v)]; ok {
+		st, err := c.Status(ctx.Context)
+		if err != nil {
+			return ctlError(err)
+		}
+		db := math.Max(volume.MinDB, math.Min(volume.MaxDB, st.Volume+step))
+		v = volume.FormatDB(db)
+	}
+
+	st, err := c.SetVolume(ctx.Context, v)
+	if err != nil {
+		return ctlError(err)
+	}
+
+	fmt.Printf("volume: %s\n", formatVolume(st.Volume))
+	return nil
+}
+
+var ctlVolumeSteps = map[string]float64{
+	"up":   3,
+	"down": -3,
+}
+
+func formatVolume(db float64) string {
+	if db <= volume.MinDB {
+		return "off"
+	}
+	return volume.FormatDB(db)
+}
+
+// formatStatus formats st in a line, e.g. "playing 81.3M wbfm (J-WAVE)".
+func formatStatus(st *control.Status) string {
+	var b strings.Builder
+	b.WriteString(string(st.State))
+	if t := st.Tuning; t != nil {
+		fmt.Fprintf(&b, " %s %s", t.Frequency, t.Mode)
+		if t.Station != "" {
+			fmt.Fprintf(&b, " (%s)", t.Station)
+		}
+	}
+	if st.Muted {
+		b.WriteString(" muted")
+	}
+	return b.String()
+}
+
+// ctlError converts errors of bad requests to ArgumentError.
+func ctlError(err error) error {
+	var apiErr *control.APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode >= 400 && apiErr.StatusCode < 500 {
+		return ArgumentError(apiErr.Message)
+	}
+	return err
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/kechako/goradio/control"
+	"github.com/kechako/goradio/player"
+	"github.com/kechako/goradio/source"
+	"github.com/kechako/goradio/station"
+	cli "github.com/urfave/cli/v2"
+)
+
+// daemonCommand plays radio in the background and serves the control API.
+// It starts playing if a frequency or a station is specified.
+func daemonCommand(ctx *cli.Context) error {
+	device, err := outputDevice(ctx)
+	if err != nil {
+		return err
+	}
+	sampleRate := ctx.Int("sample-rate")
+	if sampleRate == 0 {
+		sampleRate = device.DefaultSampleRate()
+	}
+	quality, err := resampleQuality(ctx)
+	if err != nil {
+		return err
+	}
+	db, err := volumeDB(ctx)
+	if err != nil {
+		return err
+	}
+	filters, err := flagFilters(ctx, sampleRate)
+	if err != nil {
+		return err
+	}
+
+	var t player.Tuning
+	if ctx.String("freq") != "" {
+		t, err = tuning(ctx)
+	} else {
+		t, err = tuningSettings(ctx)
+	}
+	if err != nil {
+		return err
+	}
+	t.Filters, err = stationFilters(ctx)
+	if err != nil {
+		return err
+	}
+
+	listeners, err := daemonListeners(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		for _, l := range listeners {
+			l.Close()
+		}
+	}()
+
+	stream, bufferSamples, err := openOutputStream(ctx, device, sampleRate)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	open := func(t player.Tuning) (source.Source, error) {
+		// the source runs at its native rate and is converted by the player
+		return openTunedSource(ctx, t, 0, true)
+	}
+	p := player.New(stream, sampleRate, bufferSamples, open,
+		player.WithLatency(ctx.Duration("latency")),
+		player.WithResampleQuality(quality),
+		player.WithVolume(db),
+		player.WithFade(ctx.Duration("fade")),
+		player.WithFilters(filters...),
+	)
+	defer p.Close()
+
+	go printPlayerEvents(p.Subscribe())
+
+	pctx, cancel := context.WithCancel(ctx.Context)
+	defer cancel()
+
+	var icecastErr <-chan error
+	if ctx.String("icecast") != "" {
+		push, err := pushPlayer(pctx, ctx, p, sampleRate)
+		if err != nil {
+			return err
+		}
+		go printIcecastEvents(push.events)
+		p.SetTap(push.write)
+		icecastErr = push.err
+	}
+
+	if err := p.Tune(t); err != nil {
+		return err
+	}
+	if t.Frequency != 0 {
+		if err := p.Start(); err != nil {
+			return err
+		}
+	}
+
+	stations := func() (*station.Presets, error) {
+		presets, _, err := loadPresets(ctx)
+		return presets, err
+	}
+	srv := &http.Server{
+		Handler:           control.NewHandler(p, control.WithStations(stations)),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	srvErr := make(chan error, len(listeners))
+	for _, l := range listeners {
+		l := l
+		go func() {
+			srvErr <- srv.Serve(l)
+		}()
+		fmt.Fprintf(os.Stderr, "control API on %s %s\n", l.Addr().Network(), l.Addr())
+	}
+
+	select {
+	case <-ctx.Done():
+		err = nil
+	case err = <-srvErr:
+		if errors.Is(err, http.ErrServerClosed) {
+			err = nil
+		}
+	case err = <-icecastErr:
+	}
+
+	// closing the player ends event streams of clients
+	cancel()
+	p.Close()
+	srv.Close()
+
+	return err
+}
+
+// daemonListeners listens on the Unix socket, and on the TCP address if
+// --listen is specified.
+func daemonListeners(ctx *cli.Context) ([]net.Listener, error) {
+	path := ctx.String("socket")
+	if path == "" {
+		path = control.DefaultSocketPath()
+	}
+	ul, err := control.ListenUnix(path)
+	if err != nil {
+		return nil, err
+	}
+	listeners := []net.Listener{ul}
+
+	if addr := ctx.String("listen"); addr != "" {
+		l, err := net.Listen("tcp", addr)
+		if err != nil {
+			ul.Close()
+			return nil, fmt.Errorf("failed to listen: %w", err)
+		}
+		listeners = append(listeners, l)
+	}
+
+	return listeners, nil
+}
+
+func printPlayerEvents(sub *player.Subscription) {
+	for ev := range sub.Events() {
+		switch ev := ev.(type) {
+		case player.StartedEvent:
+			fmt.Fprintf(os.Stderr, "started %s\n", ev.Tuning.Frequency)
+		case player.StoppedEvent:
+			if ev.Err != nil {
+				fmt.Fprintf(os.Stderr, "stopped: %v\n", ev.Err)
+			} else {
+				fmt.Fprintln(os.Stderr, "stopped")
+			}
+		case player.TunedEvent:
+			if ev.Tuning.Frequency != 0 {
+				fmt.Fprintf(os.Stderr, "tuned to %s (%s)\n", ev.Tuning.Frequency, ev.Tuning.Modulation)
+			}
+		case player.MutedEvent:
+			if ev.Muted {
+				fmt.Fprintln(os.Stderr, "muted")
+			} else {
+				fmt.Fprintln(os.Stderr, "unmuted")
+			}
+		case player.SourceEvent:
+			printEvent(ev.Event)
+		}
+	}
+}
@@ -0,0 +1,338 @@
+package demod
+
+import (
+	"errors"
+	"math"
+	"math/cmplx"
+	"time"
+
+	"github.com/kechako/goradio/rtlfm"
+)
+
+const (
+	DefaultInputRate = 1024000
+
+	maxTaps = 255
+
+	// center of SSB passband
+	ssbOffset = 1500.0
+)
+
+var ErrUnsupportedModulation = errors.New("unsupported modulation")
+
+type modeParams struct {
+	channelRate    float64
+	audioBandwidth float64
+	deviation      float64
+}
+
+func paramsOf(modulation rtlfm.Modulation) (modeParams, error) {
+	switch modulation {
+	case rtlfm.WBFM:
+		return modeParams{channelRate: 240000, audioBandwidth: 15000, deviation: 75000}, nil
+	case rtlfm.FM:
+		return modeParams{channelRate: 24000, audioBandwidth: 4000, deviation: 5000}, nil
+	case rtlfm.AM:
+		return modeParams{channelRate: 24000, audioBandwidth: 5000}, nil
+	case rtlfm.USB, rtlfm.LSB:
+		return modeParams{channelRate: 12000, audioBandwidth: 3000}, nil
+	default:
+		return modeParams{}, ErrUnsupportedModulation
+	}
+}
+
+// Demodulator demodulates unsigned 8-bit interleaved IQ samples, as
+// produced by rtl_sdr and rtl_tcp, into 16-bit PCM audio.
+type Demodulator struct {
+	modulation  rtlfm.Modulation
+	inputRate   int
+	outputRate  int
+	channelRate float64
+	chFactor    int
+	fmScale     float64
+	level       float64
+
+	channel   *decimator[complex128]
+	ssbFilter *decimator[complex128]
+	ssbPhase  float64
+	ssbStep   float64
+	audio     *decimator[float64]
+	resampler *resampler
+	agc       *agc
+	deemp     *deEmphasis
+	dc        *dcBlocker
+
+	prev    complex128
+	pending []byte
+	iq      []complex128
+	ch      []complex128
+	ssb     []complex128
+	ssbOut  []complex128
+	demod   []float64
+	dec     []float64
+	pcm     []float64
+}
+
+func New(modulation rtlfm.Modulation, opts ...Option) (*Demodulator, error) {
+	options := demodOptions{
+		inputRate: DefaultInputRate,
+	}
+	for _, opt := range opts {
+		opt.apply(&options)
+	}
+
+	params, err := paramsOf(modulation)
+	if err != nil {
+		return nil, err
+	}
+	if options.inputRate <= 0 {
+		return nil, errors.New("invalid input rate")
+	}
+	outputRate := options.outputRate
+	if outputRate == 0 {
+		outputRate = modulation.DefaultOutputRate()
+	}
+	if outputRate < 0 {
+		return nil, errors.New("invalid output rate")
+	}
+
+	inputRate := float64(options.inputRate)
+
+	// channel filter and decimation
+	chFactor := int(inputRate / params.channelRate)
+	if chFactor < 1 {
+		chFactor = 1
+	}
+	channelRate := inputRate / float64(chFactor)
+	chTaps := taps(chFactor)
+	chCutoff := 0.5 * params.channelRate / inputRate
+	if chCutoff > 0.45 {
+		chCutoff = 0.45
+	}
+	toComplex := func(v float64) complex128 { return complex(v, 0) }
+
+	// audio filter and decimation
+	audioFactor := int(channelRate / float64(outputRate))
+	if audioFactor < 1 {
+		audioFactor = 1
+	}
+	audioRate := channelRate / float64(audioFactor)
+	audioCutoff := params.audioBandwidth
+	if limit := 0.45 * math.Min(audioRate, float64(outputRate)); audioCutoff > limit {
+		audioCutoff = limit
+	}
+	toFloat := func(v float64) float64 { return v }
+
+	d := &Demodulator{
+		modulation:  modulation,
+		inputRate:   options.inputRate,
+		outputRate:  outputRate,
+		channelRate: channelRate,
+		chFactor:    chFactor,
+		channel:     newDecimator(lowpass(chTaps, chCutoff), chFactor, toComplex),
+		audio:       newDecimator(lowpass(taps(audioFactor), audioCutoff/channelRate), audioFactor, toFloat),
+		resampler:   newResampler(audioRate, float64(outputRate)),
+	}
+
+	switch modulation {
+	case rtlfm.WBFM, rtlfm.FM:
+		// full deviation is mapped to 0.8 of full scale
+		d.fmScale = 0.8 * channelRate / (2 * math.Pi * params.deviation)
+		if options.deEmphasis > 0 {
+			d.deemp = newDeEmphasis(float64(outputRate), options.deEmphasis.Seconds())
+		}
+	case rtlfm.AM:
+		d.agc = newAGC(float64(outputRate))
+		d.dc = newDCBlocker(float64(outputRate))
+	case rtlfm.USB, rtlfm.LSB:
+		d.ssbFilter = newDecimator(lowpass(maxTaps/2, ssbOffset/channelRate), 1, toComplex)
+		d.ssbStep = 2 * math.Pi * ssbOffset / channelRate
+		if modulation == rtlfm.LSB {
+			d.ssbStep = -d.ssbStep
+		}
+		d.agc = newAGC(float64(outputRate))
+	}
+	if options.dcBlocking && d.dc == nil {
+		d.dc = newDCBlocker(float64(outputRate))
+	}
+
+	return d, nil
+}
+
+func taps(factor int) int {
+	n := 8*factor + 1
+	if n > maxTaps {
+		n = maxTaps
+	}
+	if n < 31 {
+		n = 31
+	}
+	return n
+}
+
+func (d *Demodulator) Modulation() rtlfm.Modulation { return d.modulation }
+func (d *Demodulator) InputRate() int               { return d.inputRate }
+func (d *Demodulator) OutputRate() int              { return d.outputRate }
+
+// Level returns the signal level of the channel in the last call of
+// Demodulate, the RMS of the IQ samples of the channel in 8-bit units
+// scaled by the decimation. It approximates the level rtl_fm compares with
+// its squelch level, but the filters differ, so a level that suits rtl_fm
+// may need to be adjusted.
+func (d *Demodulator) Level() float64 {
+	return d.level
+}
+
+// Demodulate demodulates iq and appends the PCM samples to out. The state
+// is kept between calls, so a stream can be passed in chunks of any size.
+func (d *Demodulator) Demodulate(iq []byte, out []int16) []int16 {
+	if len(d.pending) > 0 {
+		d.pending = append(d.pending, iq...)
+		iq = d.pending
+	}
+
+	d.iq = d.iq[:0]
+	i := 0
+	for ; i+1 < len(iq); i += 2 {
+		d.iq = append(d.iq, complex(
+			(float64(iq[i])-127.5)/127.5,
+			(float64(iq[i+1])-127.5)/127.5,
+		))
+	}
+	d.pending = append(d.pending[:0], iq[i:]...)
+
+	d.ch = d.channel.process(d.iq, d.ch[:0])
+	if len(d.ch) > 0 {
+		d.level = d.channelLevel(d.ch)
+	}
+
+	d.demod = d.demod[:0]
+	switch d.modulation {
+	case rtlfm.WBFM, rtlfm.FM:
+		d.demod = d.demodulateFM(d.ch, d.demod)
+	case rtlfm.AM:
+		d.demod = d.demodulateAM(d.ch, d.demod)
+	case rtlfm.USB, rtlfm.LSB:
+		d.demod = d.demodulateSSB(d.ch, d.demod)
+	}
+
+	d.dec = d.audio.process(d.demod, d.dec[:0])
+	d.pcm = d.resampler.process(d.dec, d.pcm[:0])
+
+	if d.dc != nil {
+		d.dc.process(d.pcm)
+	}
+	if d.agc != nil {
+		d.agc.process(d.pcm)
+	}
+	if d.deemp != nil {
+		d.deemp.process(d.pcm)
+	}
+
+	for _, v := range d.pcm {
+		out = append(out, toInt16(v))
+	}
+	return out
+}
+
+func (d *Demodulator) channelLevel(ch []complex128) float64 {
+	var sum float64
+	for _, x := range ch {
+		sum += real(x)*real(x) + imag(x)*imag(x)
+	}
+	rms := math.Sqrt(sum / float64(2*len(ch)))
+	// rtl_fm sums the samples of 8-bit IQ in its low-pass filter
+	return rms * 127.5 * float64(d.chFactor)
+}
+
+func (d *Demodulator) demodulateFM(in []complex128, out []float64) []float64 {
+	for _, x := range in {
+		p := x * cmplx.Conj(d.prev)
+		d.prev = x
+		out = append(out, math.Atan2(imag(p), real(p))*d.fmScale)
+	}
+	return out
+}
+
+func (d *Demodulator) demodulateAM(in []complex128, out []float64) []float64 {
+	for _, x := range in {
+		out = append(out, cmplx.Abs(x))
+	}
+	return out
+}
+
+func (d *Demodulator) demodulateSSB(in []complex128, out []float64) []float64 {
+	// move the center of the sideband to 0 Hz, filter out the other
+	// sideband and move it back
+	d.ssb = d.ssb[:0]
+	phase := d.ssbPhase
+	for _, x := range in {
+		d.ssb = append(d.ssb, x*cmplx.Rect(1, -phase))
+		phase += d.ssbStep
+	}
+
+	d.ssbOut = d.ssbFilter.process(d.ssb, d.ssbOut[:0])
+
+	phase = d.ssbPhase
+	for _, x := range d.ssbOut {
+		out = append(out, real(x*cmplx.Rect(1, phase)))
+		phase += d.ssbStep
+	}
+	d.ssbPhase = math.Mod(phase, 2*math.Pi)
+
+	return out
+}
+
+func toInt16(v float64) int16 {
+	v *= math.MaxInt16
+	if v > math.MaxInt16 {
+		return math.MaxInt16
+	}
+	if v < math.MinInt16 {
+		return math.MinInt16
+	}
+	return int16(v)
+}
+
+type demodOptions struct {
+	inputRate  int
+	outputRate int
+	deEmphasis time.Duration
+	dcBlocking bool
+}
+
+type Option interface {
+	apply(opts *demodOptions)
+}
+
+type optionFunc func(opts *demodOptions)
+
+func (f optionFunc) apply(opts *demodOptions) {
+	f(opts)
+}
+
+func WithInputRate(rate int) Option {
+	return optionFunc(func(opts *demodOptions) {
+		opts.inputRate = rate
+	})
+}
+
+func WithOutputRate(rate int) Option {
+	return optionFunc(func(opts *demodOptions) {
+		opts.outputRate = rate
+	})
+}
+
+// WithDeEmphasis enables de-emphasis filter for FM with the time constant
+// tau (50us in Japan and Europe, 75us in America).
+func WithDeEmphasis(tau time.Duration) Option {
+	return optionFunc(func(opts *demodOptions) {
+		opts.deEmphasis = tau
+	})
+}
+
+func EnableDCBlockingFilter() Option {
+	return optionFunc(func(opts *demodOptions) {
+		opts.dcBlocking = true
+	})
+}
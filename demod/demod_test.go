@@ -0,0 +1,298 @@
+package demod
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"math/cmplx"
+	"testing"
+
+	"github.com/kechako/goradio/rtlfm"
+)
+
+// iqSignal returns seconds of unsigned 8-bit IQ samples of the baseband
+// signal fn at rate.
+func iqSignal(rate int, seconds float64, fn func(t float64) complex128) []byte {
+	n := int(float64(rate) * seconds)
+	iq := make([]byte, 0, 2*n)
+	for i := 0; i < n; i++ {
+		x := fn(float64(i) / float64(rate))
+		iq = append(iq, quantize(real(x)), quantize(imag(x)))
+	}
+	return iq
+}
+
+func quantize(v float64) byte {
+	return byte(math.Round(127.5 + 127.5*v))
+}
+
+// tone returns the frequency and the amplitude of the sine wave in pcm,
+// the amplitude is normalized to full scale.
+func tone(pcm []int16, rate int) (freq, amplitude float64) {
+	// count rising zero crossings, interpolated between samples
+	first, last := -1.0, -1.0
+	crossings := 0
+	for i := 1; i < len(pcm); i++ {
+		a, b := float64(pcm[i-1]), float64(pcm[i])
+		if a < 0 && b >= 0 {
+			t := float64(i-1) + a/(a-b)
+			if first < 0 {
+				first = t
+			} else {
+				crossings++
+			}
+			last = t
+		}
+	}
+	if crossings == 0 {
+		return 0, 0
+	}
+	freq = float64(crossings) * float64(rate) / (last - first)
+
+	var sum complex128
+	for i, v := range pcm[int(first):int(last)] {
+		sum += complex(float64(v)/32768, 0) * cmplx.Rect(1, -2*math.Pi*freq*float64(i)/float64(rate))
+	}
+	amplitude = 2 * cmplx.Abs(sum) / (last - first)
+	return freq, amplitude
+}
+
+// demodulate demodulates iq in chunks and returns the PCM samples after
+// half a second, when filters and AGC have settled.
+func demodulate(t *testing.T, d *Demodulator, iq []byte) []int16 {
+	t.Helper()
+
+	var pcm []int16
+	// an odd chunk size splits IQ pairs
+	const chunk = 4095
+	for len(iq) > 0 {
+		n := chunk
+		if n > len(iq) {
+			n = len(iq)
+		}
+		pcm = d.Demodulate(iq[:n], pcm)
+		iq = iq[n:]
+	}
+
+	settle := d.OutputRate() / 2
+	if len(pcm) <= settle {
+		t.Fatalf("got %d samples, want more than %d", len(pcm), settle)
+	}
+	return pcm[settle:]
+}
+
+func TestDemodulatorTone(t *testing.T) {
+	const toneFreq = 1000.0
+	fm := func(deviation float64) func(t float64) complex128 {
+		// instantaneous frequency of deviation * cos(2 pi f t)
+		return func(t float64) complex128 {
+			return cmplx.Rect(0.9, deviation/toneFreq*math.Sin(2*math.Pi*toneFreq*t))
+		}
+	}
+
+	tests := []struct {
+		name          string
+		modulation    rtlfm.Modulation
+		inputRate     int
+		signal        func(t float64) complex128
+		wantAmplitude float64
+		tolerance     float64
+	}{
+		{
+			// half of the full deviation is 0.4 of full scale
+			name:          "wbfm",
+			modulation:    rtlfm.WBFM,
+			inputRate:     DefaultInputRate,
+			signal:        fm(37500),
+			wantAmplitude: 0.4,
+			tolerance:     0.02,
+		},
+		{
+			name:          "fm",
+			modulation:    rtlfm.FM,
+			inputRate:     240000,
+			signal:        fm(2500),
+			wantAmplitude: 0.4,
+			tolerance:     0.02,
+		},
+		{
+			// AGC normalizes the peak to about half of full scale
+			name:       "am",
+			modulation: rtlfm.AM,
+			inputRate:  240000,
+			signal: func(t float64) complex128 {
+				return complex(0.4*(1+0.5*math.Cos(2*math.Pi*toneFreq*t)), 0)
+			},
+			wantAmplitude: 0.5,
+			tolerance:     0.1,
+		},
+		{
+			name:       "usb",
+			modulation: rtlfm.USB,
+			inputRate:  240000,
+			signal: func(t float64) complex128 {
+				return cmplx.Rect(0.5, 2*math.Pi*toneFreq*t)
+			},
+			wantAmplitude: 0.5,
+			tolerance:     0.1,
+		},
+		{
+			name:       "lsb",
+			modulation: rtlfm.LSB,
+			inputRate:  240000,
+			signal: func(t float64) complex128 {
+				return cmplx.Rect(0.5, -2*math.Pi*toneFreq*t)
+			},
+			wantAmplitude: 0.5,
+			tolerance:     0.1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := New(tt.modulation, WithInputRate(tt.inputRate))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			pcm := demodulate(t, d, iqSignal(tt.inputRate, 1, tt.signal))
+			freq, amplitude := tone(pcm, d.OutputRate())
+			if math.Abs(freq-toneFreq) > toneFreq*0.01 {
+				t.Errorf("tone frequency = %.1f Hz, want %.1f Hz", freq, toneFreq)
+			}
+			if math.Abs(amplitude-tt.wantAmplitude) > tt.tolerance {
+				t.Errorf("tone amplitude = %.3f, want %.3f ± %.3f", amplitude, tt.wantAmplitude, tt.tolerance)
+			}
+		})
+	}
+}
+
+func TestDemodulatorSSBRejection(t *testing.T) {
+	const toneFreq = 1000.0
+	tests := []struct {
+		modulation rtlfm.Modulation
+		// tone in the opposite sideband
+		offset float64
+	}{
+		{rtlfm.USB, -toneFreq},
+		{rtlfm.LSB, toneFreq},
+	}
+	for _, tt := range tests {
+		const inputRate = 240000
+
+		// the AGC raises the level of the residue, compare the levels
+		// before it
+		level := func(offset float64) float64 {
+			d, err := New(tt.modulation, WithInputRate(inputRate))
+			if err != nil {
+				t.Fatal(err)
+			}
+			d.agc = nil
+			pcm := demodulate(t, d, iqSignal(inputRate, 1, func(t float64) complex128 {
+				return cmplx.Rect(0.5, 2*math.Pi*offset*t)
+			}))
+			var peak float64
+			for _, v := range pcm {
+				peak = math.Max(peak, math.Abs(float64(v)/32768))
+			}
+			return peak
+		}
+
+		wanted, rejected := level(-tt.offset), level(tt.offset)
+		if wanted == 0 {
+			t.Fatalf("%v: no tone in the sideband", tt.modulation)
+		}
+		if db := 20 * math.Log10(rejected/wanted); db > -40 {
+			t.Errorf("%v: opposite sideband at %.1f dB, want below -40 dB", tt.modulation, db)
+		}
+	}
+}
+
+func TestDemodulatorOutputRate(t *testing.T) {
+	tests := []struct {
+		modulation rtlfm.Modulation
+		outputRate int
+		want       int
+	}{
+		{rtlfm.WBFM, 0, 48000},
+		{rtlfm.FM, 0, 24000},
+		{rtlfm.AM, 0, 24000},
+		{rtlfm.USB, 0, 12000},
+		{rtlfm.FM, 44100, 44100},
+		{rtlfm.WBFM, 32000, 32000},
+	}
+	for _, tt := range tests {
+		d, err := New(tt.modulation, WithOutputRate(tt.outputRate))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := d.OutputRate(); got != tt.want {
+			t.Errorf("%v: OutputRate() = %d, want %d", tt.modulation, got, tt.want)
+		}
+
+		// one second of IQ yields one second of audio
+		pcm := d.Demodulate(make([]byte, 2*DefaultInputRate), nil)
+		if n := len(pcm); math.Abs(float64(n-tt.want)) > float64(tt.want)/100 {
+			t.Errorf("%v at %d Hz: got %d samples per second", tt.modulation, tt.want, n)
+		}
+	}
+}
+
+func TestReader(t *testing.T) {
+	iq := iqSignal(240000, 0.2, func(t float64) complex128 {
+		return cmplx.Rect(0.9, 2.5*math.Sin(2*math.Pi*1000*t))
+	})
+
+	d, err := New(rtlfm.FM, WithInputRate(240000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := d.Demodulate(iq, nil)
+
+	d, err = New(rtlfm.FM, WithInputRate(240000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := io.ReadAll(NewReader(bytes.NewReader(iq), d))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make([]int16, len(b)/2)
+	binary.Read(bytes.NewReader(b), binary.LittleEndian, got)
+
+	if len(got) != len(want) {
+		t.Fatalf("read %d samples, want %d", len(got), len(want))
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Fatalf("sample %d = %d, want %d", i, got[i], want[i])
+		}
+	}
+}
+
+func TestDemodulatorLevel(t *testing.T) {
+	const inputRate = 240000
+	tests := []struct {
+		name string
+		// amplitude of the carrier
+		amplitude float64
+		min, max  float64
+	}{
+		// amplitude / sqrt(2) * 127.5 * decimation by 10
+		{"full", 0.9, 770, 850},
+		{"weak", 0.1, 80, 100},
+		{"none", 0, 0, 10},
+	}
+	for _, tt := range tests {
+		d, err := New(rtlfm.FM, WithInputRate(inputRate))
+		if err != nil {
+			t.Fatal(err)
+		}
+		d.Demodulate(iqSignal(inputRate, 0.1, func(t float64) complex128 {
+			return cmplx.Rect(tt.amplitude, 2*math.Pi*1000*t)
+		}), nil)
+		if level := d.Level(); level < tt.min || level > tt.max {
+			t.Errorf("%s: Level() = %.1f, want between %.0f and %.0f", tt.name, level, tt.min, tt.max)
+		}
+	}
+}
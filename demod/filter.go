@@ -0,0 +1,182 @@
+package demod
+
+import "math"
+
+// lowpass designs a Hamming windowed-sinc low-pass filter. cutoff is
+// normalized to the sample rate (0 < cutoff < 0.5).
+func lowpass(taps int, cutoff float64) []float64 {
+	if taps%2 == 0 {
+		taps++
+	}
+
+	h := make([]float64, taps)
+	m := float64(taps - 1)
+	var sum float64
+	for i := range h {
+		x := float64(i) - m/2
+		var v float64
+		if x == 0 {
+			v = 2 * cutoff
+		} else {
+			v = math.Sin(2*math.Pi*cutoff*x) / (math.Pi * x)
+		}
+		v *= 0.54 - 0.46*math.Cos(2*math.Pi*float64(i)/m)
+		h[i] = v
+		sum += v
+	}
+	for i := range h {
+		h[i] /= sum
+	}
+	return h
+}
+
+type sample interface {
+	float64 | complex128
+}
+
+// decimator filters and downsamples a stream by an integer factor.
+type decimator[T sample] struct {
+	taps   []T
+	factor int
+	buf    []T
+}
+
+func newDecimator[T sample](taps []float64, factor int, conv func(float64) T) *decimator[T] {
+	t := make([]T, len(taps))
+	for i, v := range taps {
+		t[i] = conv(v)
+	}
+	return &decimator[T]{
+		taps:   t,
+		factor: factor,
+		buf:    make([]T, len(taps)-1),
+	}
+}
+
+func (d *decimator[T]) process(in []T, out []T) []T {
+	d.buf = append(d.buf, in...)
+
+	n := len(d.taps)
+	i := 0
+	for ; i+n <= len(d.buf); i += d.factor {
+		var acc T
+		for k, t := range d.taps {
+			acc += t * d.buf[i+k]
+		}
+		out = append(out, acc)
+	}
+
+	d.buf = d.buf[:copy(d.buf, d.buf[i:])]
+	return out
+}
+
+// resampler converts the sample rate by linear interpolation.
+type resampler struct {
+	step float64
+	pos  float64
+	last float64
+}
+
+func newResampler(inRate, outRate float64) *resampler {
+	return &resampler{
+		step: inRate / outRate,
+	}
+}
+
+func (r *resampler) process(in []float64, out []float64) []float64 {
+	if len(in) == 0 {
+		return out
+	}
+
+	// position 0 is the last sample of the previous input
+	for r.pos < float64(len(in)) {
+		i := int(r.pos)
+		frac := r.pos - float64(i)
+		a := r.last
+		if i > 0 {
+			a = in[i-1]
+		}
+		out = append(out, a+(in[i]-a)*frac)
+		r.pos += r.step
+	}
+
+	r.pos -= float64(len(in))
+	r.last = in[len(in)-1]
+	return out
+}
+
+// deEmphasis is a single pole low-pass filter.
+type deEmphasis struct {
+	alpha float64
+	y     float64
+}
+
+func newDeEmphasis(rate float64, tau float64) *deEmphasis {
+	return &deEmphasis{
+		alpha: 1 - math.Exp(-1/(rate*tau)),
+	}
+}
+
+func (f *deEmphasis) process(buf []float64) {
+	for i, x := range buf {
+		f.y += f.alpha * (x - f.y)
+		buf[i] = f.y
+	}
+}
+
+const dcCutoff = 10.0
+
+type dcBlocker struct {
+	r  float64
+	x1 float64
+	y1 float64
+}
+
+func newDCBlocker(rate float64) *dcBlocker {
+	return &dcBlocker{
+		r: math.Exp(-2 * math.Pi * dcCutoff / rate),
+	}
+}
+
+func (f *dcBlocker) process(buf []float64) {
+	for i, x := range buf {
+		y := x - f.x1 + f.r*f.y1
+		f.x1 = x
+		f.y1 = y
+		buf[i] = y
+	}
+}
+
+// agc normalizes the level of AM and SSB audio.
+type agc struct {
+	attack  float64
+	decay   float64
+	level   float64
+	target  float64
+	minimum float64
+}
+
+func newAGC(rate float64) *agc {
+	return &agc{
+		attack:  1 - math.Exp(-1/(rate*0.01)),
+		decay:   1 - math.Exp(-1/(rate*1.0)),
+		level:   1e-3,
+		target:  0.5,
+		minimum: 1e-6,
+	}
+}
+
+func (a *agc) process(buf []float64) {
+	for i, x := range buf {
+		v := math.Abs(x)
+		if v > a.level {
+			a.level += a.attack * (v - a.level)
+		} else {
+			a.level += a.decay * (v - a.level)
+		}
+		if a.level < a.minimum {
+			a.level = a.minimum
+		}
+		buf[i] = x * a.target / a.level
+	}
+}
@@ -0,0 +1,52 @@
+package demod
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+const readSize = 16384
+
+type reader struct {
+	r   io.Reader
+	d   *Demodulator
+	iq  []byte
+	pcm []int16
+	buf []byte
+	off int
+}
+
+// NewReader returns a reader that demodulates IQ samples read from r and
+// yields 16-bit little endian PCM, the same format as rtl_fm outputs.
+func NewReader(r io.Reader, d *Demodulator) io.Reader {
+	return &reader{
+		r:  r,
+		d:  d,
+		iq: make([]byte, readSize),
+	}
+}
+
+func (r *reader) Read(b []byte) (int, error) {
+	for r.off == len(r.buf) {
+		n, err := r.r.Read(r.iq)
+		if n > 0 {
+			r.pcm = r.d.Demodulate(r.iq[:n], r.pcm[:0])
+			size := 2 * len(r.pcm)
+			if cap(r.buf) < size {
+				r.buf = make([]byte, size)
+			}
+			r.buf = r.buf[:size]
+			for i, v := range r.pcm {
+				binary.LittleEndian.PutUint16(r.buf[2*i:], uint16(v))
+			}
+			r.off = 0
+		}
+		if err != nil && r.off == len(r.buf) {
+			return 0, err
+		}
+	}
+
+	n := copy(b, r.buf[r.off:])
+	r.off += n
+	return n, nil
+}
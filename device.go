@@ -2,11 +2,33 @@ package main
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/kechako/goradio/audio"
 	cli "github.com/urfave/cli/v2"
 )
 
+func setAudioBackend(ctx *cli.Context) error {
+	kind, arg, _ := strings.Cut(ctx.String("audio-backend"), ":")
+
+	var b audio.Backend
+	switch kind {
+	case "portaudio":
+		b = audio.PortAudio()
+	case "null":
+		b = audio.Null()
+	case "file":
+		if arg == "" {
+			return ArgumentError("audio file is not specified")
+		}
+		b = audio.File(arg)
+	default:
+		return ArgumentError("invalid audio backend")
+	}
+
+	return audio.SetBackend(b)
+}
+
 func deviceListCommand(ctx *cli.Context) error {
 	if ctx.NArg() != 0 {
 		return ArgumentError("invalid argument")
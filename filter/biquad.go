@@ -0,0 +1,88 @@
+package filter
+
+import (
+	"math"
+	"math/cmplx"
+)
+
+// Butterworth is the Q of a maximally flat second order filter.
+const Butterworth = 1 / math.Sqrt2
+
+// Biquad is a second order IIR filter. Coefficients are normalized by a0.
+type Biquad struct {
+	b0, b1, b2 float64
+	a1, a2     float64
+
+	// transposed direct form II state
+	z1, z2 float64
+}
+
+// NewHighPass returns a second order high-pass filter, e.g. to remove hum.
+func NewHighPass(sampleRate int, cutoff, q float64) *Biquad {
+	cosw, alpha := biquadParams(sampleRate, cutoff, q)
+	return newBiquad(
+		(1+cosw)/2, -(1 + cosw), (1+cosw)/2,
+		1+alpha, -2*cosw, 1-alpha,
+	)
+}
+
+// NewLowPass returns a second order low-pass filter.
+func NewLowPass(sampleRate int, cutoff, q float64) *Biquad {
+	cosw, alpha := biquadParams(sampleRate, cutoff, q)
+	return newBiquad(
+		(1-cosw)/2, 1-cosw, (1-cosw)/2,
+		1+alpha, -2*cosw, 1-alpha,
+	)
+}
+
+// NewPeaking returns a peaking EQ boosting or cutting gain dB around freq.
+// q sets the bandwidth.
+func NewPeaking(sampleRate int, freq, gain, q float64) *Biquad {
+	cosw, alpha := biquadParams(sampleRate, freq, q)
+	a := math.Pow(10, gain/40)
+	return newBiquad(
+		1+alpha*a, -2*cosw, 1-alpha*a,
+		1+alpha/a, -2*cosw, 1-alpha/a,
+	)
+}
+
+// biquadParams returns the cosine of the angular frequency and alpha of the
+// Audio EQ Cookbook.
+func biquadParams(sampleRate int, freq, q float64) (cosw, alpha float64) {
+	w := 2 * math.Pi * freq / float64(sampleRate)
+	return math.Cos(w), math.Sin(w) / (2 * q)
+}
+
+func newBiquad(b0, b1, b2, a0, a1, a2 float64) *Biquad {
+	return &Biquad{
+		b0: b0 / a0,
+		b1: b1 / a0,
+		b2: b2 / a0,
+		a1: a1 / a0,
+		a2: a2 / a0,
+	}
+}
+
+func (f *Biquad) Process(samples []float64) {
+	for i, x := range samples {
+		y := f.b0*x + f.z1
+		f.z1 = f.b1*x - f.a1*y + f.z2
+		f.z2 = f.b2*x - f.a2*y
+		samples[i] = y
+	}
+}
+
+func (f *Biquad) Reset() {
+	f.z1, f.z2 = 0, 0
+}
+
+// Response returns the gain of the filter at freq.
+func (f *Biquad) Response(sampleRate int, freq float64) float64 {
+	w := 2 * math.Pi * freq / float64(sampleRate)
+	// z^-1 and z^-2
+	z1 := complex(math.Cos(w), -math.Sin(w))
+	z2 := z1 * z1
+	num := complex(f.b0, 0) + complex(f.b1, 0)*z1 + complex(f.b2, 0)*z2
+	den := 1 + complex(f.a1, 0)*z1 + complex(f.a2, 0)*z2
+	return cmplx.Abs(num / den)
+}
@@ -0,0 +1,131 @@
+package filter
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	gateAttack  = time.Millisecond
+	gateRelease = 50 * time.Millisecond
+	gateHold    = 100 * time.Millisecond
+	// decay of the peak level detected by the gate
+	gateDecay = 10 * time.Millisecond
+
+	compressorAttack  = 5 * time.Millisecond
+	compressorRelease = 100 * time.Millisecond
+
+	// level regarded as silence by log conversions
+	minLevel = 1e-9
+)
+
+// coefficient returns the per sample coefficient of a one-pole smoother
+// with time constant d.
+func coefficient(sampleRate int, d time.Duration) float64 {
+	return 1 - math.Exp(-1/(d.Seconds()*float64(sampleRate)))
+}
+
+func dbToLinear(db float64) float64 {
+	return math.Pow(10, db/20)
+}
+
+// Gate is a noise gate silencing audio below a threshold, e.g. the hiss of
+// NFM between transmissions.
+type Gate struct {
+	threshold float64
+	hold      int
+	decay     float64
+	attack    float64
+	release   float64
+
+	level    float64
+	gain     float64
+	holdLeft int
+}
+
+// NewGate returns a gate opening at threshold dBFS. It closes after the
+// level stays below the threshold for a while.
+func NewGate(sampleRate int, threshold float64) *Gate {
+	return &Gate{
+		threshold: dbToLinear(threshold),
+		hold:      int(gateHold.Seconds() * float64(sampleRate)),
+		decay:     coefficient(sampleRate, gateDecay),
+		attack:    coefficient(sampleRate, gateAttack),
+		release:   coefficient(sampleRate, gateRelease),
+	}
+}
+
+func (g *Gate) Process(samples []float64) {
+	for i, x := range samples {
+		if a := math.Abs(x); a > g.level {
+			g.level = a
+		} else {
+			g.level -= g.level * g.decay
+		}
+
+		target := 0.0
+		if g.level >= g.threshold {
+			g.holdLeft = g.hold
+			target = 1
+		} else if g.holdLeft > 0 {
+			g.holdLeft--
+			target = 1
+		}
+
+		if target > g.gain {
+			g.gain += (target - g.gain) * g.attack
+		} else {
+			g.gain += (target - g.gain) * g.release
+		}
+		samples[i] = x * g.gain
+	}
+}
+
+func (g *Gate) Reset() {
+	g.level, g.gain, g.holdLeft = 0, 0, 0
+}
+
+// Compressor reduces the level above a threshold by a ratio, evening out
+// the loudness of stations.
+type Compressor struct {
+	threshold float64
+	slope     float64
+	makeup    float64
+	attack    float64
+	release   float64
+
+	level float64
+}
+
+// NewCompressor returns a compressor above threshold dBFS by ratio, with
+// makeup gain in dB.
+func NewCompressor(sampleRate int, threshold, ratio, makeup float64) *Compressor {
+	return &Compressor{
+		threshold: threshold,
+		slope:     1 - 1/ratio,
+		makeup:    makeup,
+		attack:    coefficient(sampleRate, compressorAttack),
+		release:   coefficient(sampleRate, compressorRelease),
+	}
+}
+
+func (c *Compressor) Process(samples []float64) {
+	for i, x := range samples {
+		a := math.Abs(x)
+		if a > c.level {
+			c.level += (a - c.level) * c.attack
+		} else {
+			c.level += (a - c.level) * c.release
+		}
+
+		gain := c.makeup
+		if over := 20*math.Log10(c.level+minLevel) - c.threshold; over > 0 {
+			gain -= over * c.slope
+		}
+		samples[i] = x * dbToLinear(gain)
+	}
+}
+
+func (c *Compressor) Reset() {
+	c.level = 0
+}
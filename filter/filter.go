@@ -0,0 +1,144 @@
+package filter
+
+import (
+	"math"
+
+	"github.com/kechako/goradio/audio"
+)
+
+// Filter processes mono frames in place.
+type Filter[T audio.SampleType] interface {
+	Process(frame []T)
+	// Reset clears the state, e.g. when the source is switched.
+	Reset()
+}
+
+// Stage is a filter of samples normalized to full scale, from -1 to 1.
+type Stage interface {
+	Process(samples []float64)
+	Reset()
+}
+
+// Chain is a Filter applying stages in order.
+type Chain[T audio.SampleType] struct {
+	stages []Stage
+	buf    []float64
+}
+
+var _ Filter[int16] = (*Chain[int16])(nil)
+
+func NewChain[T audio.SampleType](stages ...Stage) *Chain[T] {
+	return &Chain[T]{
+		stages: stages,
+	}
+}
+
+// Build returns a chain of the filters of specs at sampleRate.
+func Build[T audio.SampleType](sampleRate int, specs ...Spec) (*Chain[T], error) {
+	stages := make([]Stage, 0, len(specs))
+	for _, spec := range specs {
+		s, err := spec.New(sampleRate)
+		if err != nil {
+			return nil, err
+		}
+		stages = append(stages, s)
+	}
+	return NewChain[T](stages...), nil
+}
+
+func (c *Chain[T]) Process(frame []T) {
+	if len(c.stages) == 0 {
+		return
+	}
+
+	c.buf = load(c.buf[:0], frame)
+	for _, s := range c.stages {
+		s.Process(c.buf)
+	}
+	store(frame, c.buf)
+}
+
+func (c *Chain[T]) Reset() {
+	for _, s := range c.stages {
+		s.Reset()
+	}
+}
+
+// full scale of integer samples
+const (
+	scale32 = 1 << 31
+	scale24 = 1 << 23
+	scale16 = 1 << 15
+	scale8  = 1 << 7
+)
+
+func load[T audio.SampleType](buf []float64, in []T) []float64 {
+	switch in := any(in).(type) {
+	case []float32:
+		for _, v := range in {
+			buf = append(buf, float64(v))
+		}
+	case []int32:
+		for _, v := range in {
+			buf = append(buf, float64(v)/scale32)
+		}
+	case []audio.Int24:
+		for _, v := range in {
+			buf = append(buf, float64(v.Int32()>>8)/scale24)
+		}
+	case []int16:
+		for _, v := range in {
+			buf = append(buf, float64(v)/scale16)
+		}
+	case []int8:
+		for _, v := range in {
+			buf = append(buf, float64(v)/scale8)
+		}
+	case []uint8:
+		for _, v := range in {
+			buf = append(buf, (float64(v)-128)/scale8)
+		}
+	}
+	return buf
+}
+
+func store[T audio.SampleType](out []T, buf []float64) {
+	switch out := any(out).(type) {
+	case []float32:
+		for i, v := range buf {
+			out[i] = float32(v)
+		}
+	case []int32:
+		for i, v := range buf {
+			out[i] = int32(clamp(v*scale32, scale32))
+		}
+	case []audio.Int24:
+		for i, v := range buf {
+			out[i].PutInt32(int32(clamp(v*scale24, scale24)) << 8)
+		}
+	case []int16:
+		for i, v := range buf {
+			out[i] = int16(clamp(v*scale16, scale16))
+		}
+	case []int8:
+		for i, v := range buf {
+			out[i] = int8(clamp(v*scale8, scale8))
+		}
+	case []uint8:
+		for i, v := range buf {
+			out[i] = uint8(clamp(v*scale8, scale8) + 128)
+		}
+	}
+}
+
+// clamp rounds v and clamps it to a signed integer of full scale.
+func clamp(v, scale float64) float64 {
+	v = math.Round(v)
+	if v < -scale {
+		return -scale
+	}
+	if v > scale-1 {
+		return scale - 1
+	}
+	return v
+}
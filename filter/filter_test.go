@@ -0,0 +1,327 @@
+package filter
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/kechako/goradio/audio"
+)
+
+const testRate = 48000
+
+// sine returns a second of a tone at freq with amplitude relative to full
+// scale.
+func sine(freq, amplitude float64) []int16 {
+	s := make([]int16, testRate)
+	for i := range s {
+		s[i] = int16(math.Round(amplitude * 32767 * math.Sin(2*math.Pi*freq*float64(i)/testRate)))
+	}
+	return s
+}
+
+// level returns the amplitude of the tone at freq in s, relative to full
+// scale.
+func level(s []int16, freq float64) float64 {
+	var re, im float64
+	for i, v := range s {
+		w := 2 * math.Pi * freq * float64(i) / testRate
+		re += float64(v) * math.Cos(w)
+		im += float64(v) * math.Sin(w)
+	}
+	return 2 * math.Hypot(re, im) / float64(len(s)) / 32767
+}
+
+func toDB(v float64) float64 {
+	return 20 * math.Log10(v)
+}
+
+// measure returns the gain in dB of the tone at freq through chain, after
+// the filter settles.
+func measure(chain *Chain[int16], freq, amplitude float64) float64 {
+	s := sine(freq, amplitude)
+	// 10ms frames like the player
+	for i := 0; i < len(s); i += testRate / 100 {
+		chain.Process(s[i : i+testRate/100])
+	}
+	settled := s[testRate/2:]
+	return toDB(level(settled, freq) / amplitude)
+}
+
+// butterworth returns the gain in dB of a second order Butterworth filter
+// at freq. Frequencies are prewarped like the bilinear transform.
+func butterworth(highPass bool, cutoff, freq float64) float64 {
+	r := math.Tan(math.Pi*freq/testRate) / math.Tan(math.Pi*cutoff/testRate)
+	r4 := r * r * r * r
+	if highPass {
+		return 10 * math.Log10(r4/(1+r4))
+	}
+	return 10 * math.Log10(1/(1+r4))
+}
+
+func TestBiquadResponse(t *testing.T) {
+	const cutoff = 1000
+	freqs := []float64{10, 100, 500, 900, 1000, 1100, 2000, 10000, 20000}
+
+	for _, freq := range freqs {
+		hp := toDB(NewHighPass(testRate, cutoff, Butterworth).Response(testRate, freq))
+		if want := butterworth(true, cutoff, freq); math.Abs(hp-want) > 0.01 {
+			t.Errorf("highpass: response at %gHz = %.2fdB, want %.2fdB", freq, hp, want)
+		}
+		lp := toDB(NewLowPass(testRate, cutoff, Butterworth).Response(testRate, freq))
+		if want := butterworth(false, cutoff, freq); math.Abs(lp-want) > 0.01 {
+			t.Errorf("lowpass: response at %gHz = %.2fdB, want %.2fdB", freq, lp, want)
+		}
+	}
+
+	// -3dB at the cutoff
+	for _, f := range []*Biquad{
+		NewHighPass(testRate, cutoff, Butterworth),
+		NewLowPass(testRate, cutoff, Butterworth),
+	} {
+		if got := toDB(f.Response(testRate, cutoff)); math.Abs(got+3.01) > 0.01 {
+			t.Errorf("response at cutoff = %.2fdB, want -3.01dB", got)
+		}
+	}
+
+	// a higher Q peaks at the cutoff
+	if got := toDB(NewLowPass(testRate, cutoff, 2).Response(testRate, cutoff)); math.Abs(got-6.02) > 0.01 {
+		t.Errorf("lowpass Q 2: response at cutoff = %.2fdB, want 6.02dB", got)
+	}
+
+	for _, gain := range []float64{-12, -3, 6, 20} {
+		f := NewPeaking(testRate, cutoff, gain, 1)
+		if got := toDB(f.Response(testRate, cutoff)); math.Abs(got-gain) > 0.01 {
+			t.Errorf("eq %gdB: response at %dHz = %.2fdB, want %gdB", gain, cutoff, got, gain)
+		}
+		for _, freq := range []float64{10, 20000} {
+			if got := toDB(f.Response(testRate, freq)); math.Abs(got) > 0.1 {
+				t.Errorf("eq %gdB: response at %gHz = %.2fdB, want 0dB", gain, freq, got)
+			}
+		}
+		// the gain is symmetric around the center on a log scale
+		lower := toDB(f.Response(testRate, cutoff/2))
+		upper := toDB(f.Response(testRate, cutoff*2))
+		if math.Abs(lower-upper) > 0.1 || math.Abs(lower) >= math.Abs(gain) {
+			t.Errorf("eq %gdB: response an octave away = %.2fdB and %.2fdB", gain, lower, upper)
+		}
+	}
+}
+
+func TestChainFrequencyResponse(t *testing.T) {
+	tests := []struct {
+		name  string
+		f     func() *Biquad
+		freqs []float64
+	}{
+		{
+			name:  "highpass",
+			f:     func() *Biquad { return NewHighPass(testRate, 300, Butterworth) },
+			freqs: []float64{100, 200, 300, 450, 600, 3000},
+		},
+		{
+			name:  "lowpass",
+			f:     func() *Biquad { return NewLowPass(testRate, 3000, Butterworth) },
+			freqs: []float64{300, 1500, 2000, 3000, 4500, 6000},
+		},
+		{
+			name:  "eq",
+			f:     func() *Biquad { return NewPeaking(testRate, 1000, 6, 1) },
+			freqs: []float64{100, 500, 700, 1000, 1400, 2000, 10000},
+		},
+	}
+	for _, tt := range tests {
+		for _, freq := range tt.freqs {
+			t.Run(fmt.Sprintf("%s/%gHz", tt.name, freq), func(t *testing.T) {
+				want := toDB(tt.f().Response(testRate, freq))
+				got := measure(NewChain[int16](tt.f()), freq, 0.25)
+				if math.Abs(got-want) > 0.1 {
+					t.Errorf("gain = %.2fdB, want %.2fdB", got, want)
+				}
+			})
+		}
+	}
+}
+
+func TestGate(t *testing.T) {
+	tests := []struct {
+		name      string
+		amplitude float64
+		want      float64
+	}{
+		{"open", dbToLinear(-20), 0},
+		{"closed", dbToLinear(-60), math.Inf(-1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chain := NewChain[int16](NewGate(testRate, -40))
+			got := measure(chain, 1000, tt.amplitude)
+			if math.IsInf(tt.want, -1) {
+				if got > -80 {
+					t.Errorf("gain = %.2fdB, want silence", got)
+				}
+			} else if math.Abs(got-tt.want) > 0.1 {
+				t.Errorf("gain = %.2fdB, want %.2fdB", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("hold and release", func(t *testing.T) {
+		chain := NewChain[int16](NewGate(testRate, -40))
+		chain.Process(sine(1000, dbToLinear(-20)))
+
+		// noise after a transmission is heard for the hold time, then
+		// faded out by the release
+		quiet := sine(1000, dbToLinear(-50))
+		chain.Process(quiet)
+		ms := testRate / 1000
+		if v := level(quiet[20*ms:80*ms], 1000); v < dbToLinear(-51) {
+			t.Errorf("level during hold = %.2fdB, want -50dB", toDB(v))
+		}
+		if v := level(quiet[500*ms:], 1000); v > dbToLinear(-90) {
+			t.Errorf("level after release = %.2fdB, want silence", toDB(v))
+		}
+	})
+}
+
+func TestCompressor(t *testing.T) {
+	t.Run("below threshold", func(t *testing.T) {
+		chain := NewChain[int16](NewCompressor(testRate, -20, 4, 0))
+		if got := measure(chain, 1000, dbToLinear(-40)); math.Abs(got) > 0.1 {
+			t.Errorf("gain = %.2fdB, want 0dB", got)
+		}
+	})
+
+	t.Run("makeup", func(t *testing.T) {
+		chain := NewChain[int16](NewCompressor(testRate, -20, 4, 6))
+		if got := measure(chain, 1000, dbToLinear(-40)); math.Abs(got-6) > 0.1 {
+			t.Errorf("gain = %.2fdB, want 6dB", got)
+		}
+	})
+
+	for _, ratio := range []float64{2, 4, 10} {
+		t.Run(fmt.Sprintf("ratio %g", ratio), func(t *testing.T) {
+			// levels above the threshold rise by 1/ratio of the input
+			out := func(in float64) float64 {
+				chain := NewChain[int16](NewCompressor(testRate, -30, ratio, 0))
+				return in + measure(chain, 1000, dbToLinear(in))
+			}
+			low, high := out(-16), out(-6)
+			if got, want := high-low, 10/ratio; math.Abs(got-want) > 0.2 {
+				t.Errorf("output rise = %.2fdB, want %.2fdB", got, want)
+			}
+			if high > -6 {
+				t.Errorf("output level = %.2fdB, want below -6dB", high)
+			}
+		})
+	}
+}
+
+func TestChainSampleTypes(t *testing.T) {
+	// 0dB peaking filter passes samples unchanged
+	identity := func() Stage { return NewPeaking(testRate, 1000, 0, 1) }
+
+	t.Run("float32", func(t *testing.T) {
+		in := []float32{-1, -0.5, 0, 0.25, 0.999}
+		frame := append([]float32(nil), in...)
+		NewChain[float32](identity()).Process(frame)
+		for i := range in {
+			if math.Abs(float64(frame[i]-in[i])) > 1e-6 {
+				t.Errorf("frame[%d] = %v, want %v", i, frame[i], in[i])
+			}
+		}
+	})
+	t.Run("int32", func(t *testing.T) {
+		in := []int32{math.MinInt32, -1 << 20, 0, 1 << 20, math.MaxInt32}
+		frame := append([]int32(nil), in...)
+		NewChain[int32](identity()).Process(frame)
+		for i := range in {
+			if d := int64(frame[i]) - int64(in[i]); d < -1 || d > 1 {
+				t.Errorf("frame[%d] = %v, want %v", i, frame[i], in[i])
+			}
+		}
+	})
+	t.Run("int24", func(t *testing.T) {
+		in := []int32{math.MinInt32, -1 << 20, 0, 1 << 20, math.MaxInt32 &^ 0xff}
+		frame := make([]audio.Int24, len(in))
+		for i, v := range in {
+			frame[i].PutInt32(v)
+		}
+		NewChain[audio.Int24](identity()).Process(frame)
+		for i := range in {
+			if got := frame[i].Int32(); got != in[i] {
+				t.Errorf("frame[%d] = %#x, want %#x", i, got, in[i])
+			}
+		}
+	})
+	t.Run("int8", func(t *testing.T) {
+		in := []int8{math.MinInt8, -1, 0, 1, math.MaxInt8}
+		frame := append([]int8(nil), in...)
+		NewChain[int8](identity()).Process(frame)
+		for i := range in {
+			if frame[i] != in[i] {
+				t.Errorf("frame[%d] = %v, want %v", i, frame[i], in[i])
+			}
+		}
+	})
+	t.Run("uint8", func(t *testing.T) {
+		in := []uint8{0, 127, 128, 129, 255}
+		frame := append([]uint8(nil), in...)
+		NewChain[uint8](identity()).Process(frame)
+		for i := range in {
+			if frame[i] != in[i] {
+				t.Errorf("frame[%d] = %v, want %v", i, frame[i], in[i])
+			}
+		}
+	})
+}
+
+func TestChainClips(t *testing.T) {
+	frame := sine(1000, 0.9)
+	NewChain[int16](NewPeaking(testRate, 1000, 12, 1)).Process(frame)
+
+	var peak int16
+	for _, v := range frame {
+		if v < 0 {
+			v = -(v + 1)
+		}
+		if v > peak {
+			peak = v
+		}
+	}
+	if peak != math.MaxInt16 {
+		t.Errorf("peak = %d, want %d", peak, math.MaxInt16)
+	}
+	// a wrapped sample would make the tone level drop
+	if l := level(frame[testRate/2:], 1000); l < 0.9 {
+		t.Errorf("level = %.2f, want clipped above 0.9", l)
+	}
+}
+
+func TestBuild(t *testing.T) {
+	chain, err := Build[int16](testRate,
+		Spec{Kind: KindHighPass, Params: []float64{300}},
+		Spec{Kind: KindLowPass, Params: []float64{3000}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := measure(chain, 1000, 0.25); math.Abs(got) > 0.5 {
+		t.Errorf("passband gain = %.2fdB, want 0dB", got)
+	}
+
+	// the empty chain is a no-op
+	empty, err := Build[int16](testRate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	frame := []int16{1, 2, 3}
+	empty.Process(frame)
+	if frame[0] != 1 || frame[1] != 2 || frame[2] != 3 {
+		t.Errorf("frame = %v, want unchanged", frame)
+	}
+
+	if _, err := Build[int16](8000, Spec{Kind: KindLowPass, Params: []float64{5000}}); err == nil {
+		t.Error("Build with cutoff above Nyquist succeeded")
+	}
+}
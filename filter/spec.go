@@ -0,0 +1,225 @@
+package filter
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidFilter = errors.New("invalid filter")
+
+type Kind int
+
+const (
+	KindHighPass Kind = iota + 1
+	KindLowPass
+	KindEQ
+	KindGate
+	KindCompressor
+)
+
+type kindInfo struct {
+	name   string
+	usage  string
+	params int
+	// defaults of the optional parameters following the required ones
+	defaults []float64
+}
+
+var kinds = map[Kind]kindInfo{
+	KindHighPass:   {name: "highpass", usage: "highpass:FREQ[:Q]", params: 1, defaults: []float64{Butterworth}},
+	KindLowPass:    {name: "lowpass", usage: "lowpass:FREQ[:Q]", params: 1, defaults: []float64{Butterworth}},
+	KindEQ:         {name: "eq", usage: "eq:FREQ:GAIN[:Q]", params: 2, defaults: []float64{1}},
+	KindGate:       {name: "gate", usage: "gate:THRESHOLD", params: 1},
+	KindCompressor: {name: "compressor", usage: "compressor:THRESHOLD[:RATIO[:MAKEUP]]", params: 1, defaults: []float64{4, 0}},
+}
+
+const maxEQGain = 40
+
+func (k Kind) String() string {
+	if info, ok := kinds[k]; ok {
+		return info.name
+	}
+	return "Kind(" + strconv.Itoa(int(k)) + ")"
+}
+
+// Spec is the text specification of a filter, e.g. "highpass:80" or
+// "compressor:-20dB:4". Frequencies are in Hz and levels in dB.
+type Spec struct {
+	Kind Kind
+	// Params are the parameters given, optional ones may be omitted.
+	Params []float64
+}
+
+// Usage returns the syntax of the filter specs.
+func Usage() string {
+	usages := make([]string, 0, len(kinds))
+	for k := KindHighPass; k <= KindCompressor; k++ {
+		usages = append(usages, kinds[k].usage)
+	}
+	return strings.Join(usages, ", ")
+}
+
+func Parse(s string) (Spec, error) {
+	fields := strings.Split(strings.TrimSpace(s), ":")
+
+	var spec Spec
+	for k, info := range kinds {
+		if strings.EqualFold(fields[0], info.name) {
+			spec.Kind = k
+			break
+		}
+	}
+	if spec.Kind == 0 {
+		return Spec{}, fmt.Errorf("%w: unknown filter %q", ErrInvalidFilter, fields[0])
+	}
+
+	for _, f := range fields[1:] {
+		v, err := parseParam(f)
+		if err != nil {
+			return Spec{}, fmt.Errorf("%w: invalid parameter %q of %s", ErrInvalidFilter, f, spec.Kind)
+		}
+		spec.Params = append(spec.Params, v)
+	}
+
+	if err := spec.Validate(); err != nil {
+		return Spec{}, err
+	}
+	return spec, nil
+}
+
+// parseParam parses a number with an optional unit, e.g. "80Hz", "3k",
+// "-20dB".
+func parseParam(s string) (float64, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	mult := 1.0
+	for _, unit := range []struct {
+		suffix string
+		mult   float64
+	}{
+		{"khz", 1000},
+		{"hz", 1},
+		{"k", 1000},
+		{"db", 1},
+	} {
+		if strings.HasSuffix(s, unit.suffix) {
+			s = strings.TrimSuffix(s, unit.suffix)
+			mult = unit.mult
+			break
+		}
+	}
+
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0, errors.New("invalid number")
+	}
+	return v * mult, nil
+}
+
+// Validate validates the kind and the parameters of the spec.
+func (s Spec) Validate() error {
+	info, ok := kinds[s.Kind]
+	if !ok {
+		return fmt.Errorf("%w: unknown filter %d", ErrInvalidFilter, int(s.Kind))
+	}
+	if n := len(s.Params); n < info.params || n > info.params+len(info.defaults) {
+		return fmt.Errorf("%w: %s must be %s", ErrInvalidFilter, s.Kind, info.usage)
+	}
+
+	p := s.params()
+	switch s.Kind {
+	case KindHighPass, KindLowPass:
+		if p[0] <= 0 {
+			return fmt.Errorf("%w: %s frequency must be positive", ErrInvalidFilter, s.Kind)
+		}
+		if p[1] <= 0 {
+			return fmt.Errorf("%w: %s Q must be positive", ErrInvalidFilter, s.Kind)
+		}
+	case KindEQ:
+		if p[0] <= 0 {
+			return fmt.Errorf("%w: eq frequency must be positive", ErrInvalidFilter)
+		}
+		if p[1] < -maxEQGain || p[1] > maxEQGain {
+			return fmt.Errorf("%w: eq gain must be between %ddB and %ddB", ErrInvalidFilter, -maxEQGain, maxEQGain)
+		}
+		if p[2] <= 0 {
+			return fmt.Errorf("%w: eq Q must be positive", ErrInvalidFilter)
+		}
+	case KindGate:
+		if p[0] > 0 {
+			return fmt.Errorf("%w: gate threshold must be <= 0dB", ErrInvalidFilter)
+		}
+	case KindCompressor:
+		if p[0] > 0 {
+			return fmt.Errorf("%w: compressor threshold must be <= 0dB", ErrInvalidFilter)
+		}
+		if p[1] < 1 {
+			return fmt.Errorf("%w: compressor ratio must be >= 1", ErrInvalidFilter)
+		}
+	}
+	return nil
+}
+
+// params returns the parameters with defaults of the omitted ones.
+func (s Spec) params() []float64 {
+	info := kinds[s.Kind]
+	p := append([]float64(nil), s.Params...)
+	if n := len(p) - info.params; n >= 0 && n < len(info.defaults) {
+		p = append(p, info.defaults[n:]...)
+	}
+	return p
+}
+
+// New returns the filter of the spec at sampleRate.
+func (s Spec) New(sampleRate int) (Stage, error) {
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+
+	p := s.params()
+	switch s.Kind {
+	case KindHighPass, KindLowPass, KindEQ:
+		if nyquist := float64(sampleRate) / 2; p[0] >= nyquist {
+			return nil, fmt.Errorf("%w: %s frequency must be below %gHz", ErrInvalidFilter, s.Kind, nyquist)
+		}
+	}
+
+	switch s.Kind {
+	case KindHighPass:
+		return NewHighPass(sampleRate, p[0], p[1]), nil
+	case KindLowPass:
+		return NewLowPass(sampleRate, p[0], p[1]), nil
+	case KindEQ:
+		return NewPeaking(sampleRate, p[0], p[1], p[2]), nil
+	case KindGate:
+		return NewGate(sampleRate, p[0]), nil
+	default:
+		return NewCompressor(sampleRate, p[0], p[1], p[2]), nil
+	}
+}
+
+func (s Spec) String() string {
+	fields := []string{s.Kind.String()}
+	for _, p := range s.Params {
+		fields = append(fields, strconv.FormatFloat(p, 'f', -1, 64))
+	}
+	return strings.Join(fields, ":")
+}
+
+func (s Spec) MarshalText() ([]byte, error) {
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+	return []byte(s.String()), nil
+}
+
+func (s *Spec) UnmarshalText(b []byte) error {
+	spec, err := Parse(string(b))
+	if err != nil {
+		return err
+	}
+	*s = spec
+	return nil
+}
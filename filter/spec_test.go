@@ -0,0 +1,152 @@
+package filter
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Spec
+	}{
+		{"highpass:80", Spec{Kind: KindHighPass, Params: []float64{80}}},
+		{"highpass:80Hz:0.5", Spec{Kind: KindHighPass, Params: []float64{80, 0.5}}},
+		{"LowPass:3k", Spec{Kind: KindLowPass, Params: []float64{3000}}},
+		{"lowpass:3.5kHz", Spec{Kind: KindLowPass, Params: []float64{3500}}},
+		{" eq:1000:-6dB ", Spec{Kind: KindEQ, Params: []float64{1000, -6}}},
+		{"eq:1k:+3:2", Spec{Kind: KindEQ, Params: []float64{1000, 3, 2}}},
+		{"gate:-40dB", Spec{Kind: KindGate, Params: []float64{-40}}},
+		{"compressor:-20dB", Spec{Kind: KindCompressor, Params: []float64{-20}}},
+		{"compressor:-20dB:4", Spec{Kind: KindCompressor, Params: []float64{-20, 4}}},
+		{"compressor:-20:4:6dB", Spec{Kind: KindCompressor, Params: []float64{-20, 4, 6}}},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.in)
+		if err != nil {
+			t.Errorf("Parse(%q) error = %v", tt.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Parse(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	tests := []string{
+		"",
+		"notch:50",
+		"highpass",
+		"highpass:",
+		"highpass:abc",
+		"highpass:0",
+		"highpass:-80",
+		"highpass:80:0",
+		"highpass:80:1:2",
+		"lowpass:NaN",
+		"lowpass:Inf",
+		"eq:1000",
+		"eq:1000:41",
+		"eq:1000:-41",
+		"eq:1000:6:0",
+		"gate",
+		"gate:6dB",
+		"gate:-40:1",
+		"compressor:6",
+		"compressor:-20:0.5",
+		"compressor:-20:4:6:1",
+	}
+	for _, in := range tests {
+		if spec, err := Parse(in); !errors.Is(err, ErrInvalidFilter) {
+			t.Errorf("Parse(%q) = %+v, %v, want %v", in, spec, err, ErrInvalidFilter)
+		}
+	}
+}
+
+func TestSpecString(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"highpass:80Hz", "highpass:80"},
+		{"lowpass:3k:1", "lowpass:3000:1"},
+		{"eq:1k:-6dB", "eq:1000:-6"},
+		{"compressor:-20dB:4", "compressor:-20:4"},
+	}
+	for _, tt := range tests {
+		spec, err := Parse(tt.in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := spec.String(); got != tt.want {
+			t.Errorf("Parse(%q).String() = %q, want %q", tt.in, got, tt.want)
+		}
+		// String is parsed back to the same spec
+		again, err := Parse(spec.String())
+		if err != nil || !reflect.DeepEqual(again, spec) {
+			t.Errorf("Parse(%q) = %+v, %v, want %+v", spec.String(), again, err, spec)
+		}
+	}
+}
+
+func TestSpecJSON(t *testing.T) {
+	specs := []Spec{
+		{Kind: KindHighPass, Params: []float64{80}},
+		{Kind: KindCompressor, Params: []float64{-20, 4}},
+	}
+	b, err := json.Marshal(specs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(b), `["highpass:80","compressor:-20:4"]`; got != want {
+		t.Errorf("json.Marshal() = %s, want %s", got, want)
+	}
+
+	var got []Spec
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, specs) {
+		t.Errorf("json.Unmarshal() = %+v, want %+v", got, specs)
+	}
+
+	if err := json.Unmarshal([]byte(`["notch:50"]`), &got); !errors.Is(err, ErrInvalidFilter) {
+		t.Errorf("json.Unmarshal() error = %v, want %v", err, ErrInvalidFilter)
+	}
+	if _, err := json.Marshal(Spec{Kind: KindGate}); err == nil {
+		t.Error("json.Marshal() of invalid spec succeeded")
+	}
+}
+
+func TestSpecNew(t *testing.T) {
+	tests := []struct {
+		in      string
+		wantErr bool
+	}{
+		{"highpass:80", false},
+		{"lowpass:23999", false},
+		{"lowpass:24000", true},
+		{"eq:30k:6", true},
+		{"gate:-40", false},
+		{"compressor:-20", false},
+	}
+	for _, tt := range tests {
+		spec, err := Parse(tt.in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := spec.New(48000); (err != nil) != tt.wantErr {
+			t.Errorf("Parse(%q).New(48000) error = %v, want error %v", tt.in, err, tt.wantErr)
+		}
+	}
+}
+
+func TestUsage(t *testing.T) {
+	want := "highpass:FREQ[:Q], lowpass:FREQ[:Q], eq:FREQ:GAIN[:Q], gate:THRESHOLD, compressor:THRESHOLD[:RATIO[:MAKEUP]]"
+	if got := Usage(); got != want {
+		t.Errorf("Usage() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"github.com/kechako/goradio/filter"
+	"github.com/kechako/goradio/volume"
+	cli "github.com/urfave/cli/v2"
+)
+
+func filterFlag() cli.Flag {
+	return &cli.StringSliceFlag{
+		Name:     "filter",
+		Usage:    "audio filter applied in order, can be repeated (" + filter.Usage() + ")",
+		Required: false,
+	}
+}
+
+func parseFilters(values []string) ([]filter.Spec, error) {
+	var specs []filter.Spec
+	for _, v := range values {
+		// an empty value clears filters of a station
+		if v == "" {
+			continue
+		}
+		spec, err := filter.Parse(v)
+		if err != nil {
+			return nil, ArgumentError(err.Error())
+		}
+		specs = append(specs, spec)
+	}
+	return specs, nil
+}
+
+// flagFilters returns the filters specified by --filter. They are checked
+// at sampleRate.
+func flagFilters(ctx *cli.Context, sampleRate int) ([]filter.Spec, error) {
+	specs, err := parseFilters(ctx.StringSlice("filter"))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := filter.Build[int16](sampleRate, specs...); err != nil {
+		return nil, ArgumentError(err.Error())
+	}
+	return specs, nil
+}
+
+// stationFilters returns the filters of the station specified by
+// --station, or nil if it has no filters. --filter given on the command
+// line takes precedence over the station, and the station over the config.
+func stationFilters(ctx *cli.Context) ([]filter.Spec, error) {
+	name := ctx.String("station")
+	if name == "" || onCommandLine(ctx, "filter") {
+		return nil, nil
+	}
+	st, err := findStation(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return st.Filters, nil
+}
+
+// outputProcess returns the processing of frames before they are written
+// to the audio device: the filters of the station or --filter, then the
+// volume. The volume control is returned to fade out when stopping.
+func outputProcess(ctx *cli.Context, sampleRate int) (func(frame []int16), *volume.Control, error) {
+	specs, err := stationFilters(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if specs == nil {
+		specs, err = flagFilters(ctx, sampleRate)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	chain, err := filter.Build[int16](sampleRate, specs...)
+	if err != nil {
+		return nil, nil, ArgumentError(err.Error())
+	}
+
+	vol, err := outputVolume(ctx, sampleRate)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return func(frame []int16) {
+		chain.Process(frame)
+		vol.Process(frame)
+	}, vol, nil
+}
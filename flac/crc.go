@@ -0,0 +1,82 @@
+package flac
+
+var (
+	crc8Table  = makeCRC8Table(0x07)
+	crc16Table = makeCRC16Table(0x8005)
+	oggTable   = makeCRC32Table(0x04c11db7)
+)
+
+func makeCRC8Table(poly uint8) *[256]uint8 {
+	var t [256]uint8
+	for i := range t {
+		c := uint8(i)
+		for j := 0; j < 8; j++ {
+			if c&0x80 != 0 {
+				c = c<<1 ^ poly
+			} else {
+				c <<= 1
+			}
+		}
+		t[i] = c
+	}
+	return &t
+}
+
+func makeCRC16Table(poly uint16) *[256]uint16 {
+	var t [256]uint16
+	for i := range t {
+		c := uint16(i) << 8
+		for j := 0; j < 8; j++ {
+			if c&0x8000 != 0 {
+				c = c<<1 ^ poly
+			} else {
+				c <<= 1
+			}
+		}
+		t[i] = c
+	}
+	return &t
+}
+
+func makeCRC32Table(poly uint32) *[256]uint32 {
+	var t [256]uint32
+	for i := range t {
+		c := uint32(i) << 24
+		for j := 0; j < 8; j++ {
+			if c&0x80000000 != 0 {
+				c = c<<1 ^ poly
+			} else {
+				c <<= 1
+			}
+		}
+		t[i] = c
+	}
+	return &t
+}
+
+// crc8 is the CRC of FLAC frame headers.
+func crc8(b []byte) uint8 {
+	var c uint8
+	for _, v := range b {
+		c = crc8Table[c^v]
+	}
+	return c
+}
+
+// crc16 is the CRC of FLAC frames.
+func crc16(b []byte) uint16 {
+	var c uint16
+	for _, v := range b {
+		c = c<<8 ^ crc16Table[byte(c>>8)^v]
+	}
+	return c
+}
+
+// oggCRC is the CRC of Ogg pages, not reflected unlike hash/crc32.
+func oggCRC(b []byte) uint32 {
+	var c uint32
+	for _, v := range b {
+		c = c<<8 ^ oggTable[byte(c>>24)^v]
+	}
+	return c
+}
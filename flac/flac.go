@@ -0,0 +1,529 @@
+// Package flac encodes 16-bit audio losslessly as FLAC encapsulated in Ogg,
+// which streaming servers such as Icecast can relay to listeners joining at
+// any time.
+package flac
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+const (
+	// BlockSize is the number of samples per channel in a frame.
+	BlockSize = 4096
+
+	bitsPerSample = 16
+	maxChannels   = 8
+
+	maxFixedOrder     = 4
+	maxPartitionOrder = 8
+	// rice parameters are coded in 4 bits, 15 is the escape code
+	maxRiceParam = 14
+	escapeParam  = 15
+
+	subframeConstant = 0x00
+	subframeVerbatim = 0x01
+	subframeFixed    = 0x08
+)
+
+// sample rates of the frame header coded in 4 bits
+var sampleRateCodes = map[int]byte{
+	88200:  0x1,
+	176400: 0x2,
+	192000: 0x3,
+	8000:   0x4,
+	16000:  0x5,
+	22050:  0x6,
+	24000:  0x7,
+	32000:  0x8,
+	44100:  0x9,
+	48000:  0xa,
+	96000:  0xb,
+}
+
+func validate(sampleRate, channels int) error {
+	if sampleRate <= 0 || sampleRate >= 1<<20 {
+		return errors.New("invalid sample rate")
+	}
+	if channels <= 0 || channels > maxChannels {
+		return errors.New("invalid channels")
+	}
+	return nil
+}
+
+// streamInfo returns the STREAMINFO metadata block, including its header.
+// The frame sizes, the total samples and the MD5 signature are unknown.
+func streamInfo(sampleRate, channels int, last bool) []byte {
+	b := make([]byte, 4+34)
+	b[0] = 0 // STREAMINFO
+	if last {
+		b[0] |= 0x80
+	}
+	b[3] = 34
+
+	info := b[4:]
+	binary.BigEndian.PutUint16(info[0:], BlockSize)
+	binary.BigEndian.PutUint16(info[2:], BlockSize)
+	// sample rate in 20 bits, channels - 1 in 3 bits, bits per sample - 1
+	// in 5 bits, then 36 bits of total samples
+	v := uint64(sampleRate)<<44 | uint64(channels-1)<<41 | uint64(bitsPerSample-1)<<36
+	binary.BigEndian.PutUint64(info[10:], v)
+	return b
+}
+
+// vorbisComment returns the VORBIS_COMMENT metadata block, including its
+// header.
+func vorbisComment(vendor string, last bool) []byte {
+	size := 4 + len(vendor) + 4
+	b := make([]byte, 4, 4+size)
+	b[0] = 4 // VORBIS_COMMENT
+	if last {
+		b[0] |= 0x80
+	}
+	b[1], b[2], b[3] = byte(size>>16), byte(size>>8), byte(size)
+
+	var l [4]byte
+	binary.LittleEndian.PutUint32(l[:], uint32(len(vendor)))
+	b = append(b, l[:]...)
+	b = append(b, vendor...)
+	// no comments
+	return append(b, 0, 0, 0, 0)
+}
+
+// encoder encodes frames of 16-bit samples.
+type encoder struct {
+	sampleRate int
+	channels   int
+	frames     uint64
+
+	bw       bitWriter
+	samples  []int32
+	residual []int32
+	// rice costs of the partitions of the finest order
+	sums []uint64
+	bits []uint8
+}
+
+func newEncoder(sampleRate, channels int) *encoder {
+	return &encoder{
+		sampleRate: sampleRate,
+		channels:   channels,
+	}
+}
+
+// encode encodes interleaved samples as the next frame. The returned slice
+// is valid until the next call.
+func (e *encoder) encode(samples []int16) []byte {
+	n := len(samples) / e.channels
+
+	e.bw.reset()
+	e.writeHeader(n)
+	for ch := 0; ch < e.channels; ch++ {
+		if cap(e.samples) < n {
+			e.samples = make([]int32, n)
+		}
+		s := e.samples[:n]
+		for i := range s {
+			s[i] = int32(samples[i*e.channels+ch])
+		}
+		e.writeSubframe(s)
+	}
+	e.bw.align()
+
+	b := e.bw.buf
+	crc := crc16(b)
+	e.bw.buf = append(b, byte(crc>>8), byte(crc))
+	e.frames++
+	return e.bw.buf
+}
+
+func (e *encoder) writeHeader(n int) {
+	b := e.bw.buf
+
+	// sync code and fixed block size
+	b = append(b, 0xff, 0xf8)
+
+	blockCode := byte(0x7)
+	switch n {
+	case 192:
+		blockCode = 0x1
+	case 576, 1152, 2304, 4608:
+		blockCode = 0x2 + byte(log2(n/576))
+	case 256, 512, 1024, 2048, 4096, 8192, 16384, 32768:
+		blockCode = 0x8 + byte(log2(n/256))
+	default:
+		if n <= 256 {
+			blockCode = 0x6
+		}
+	}
+	rateCode, ok := sampleRateCodes[e.sampleRate]
+	if !ok {
+		if e.sampleRate < 1<<16 {
+			rateCode = 0xd
+		} else {
+			// from STREAMINFO
+			rateCode = 0x0
+		}
+	}
+	b = append(b, blockCode<<4|rateCode)
+	// independent channels, 16 bits per sample
+	b = append(b, byte(e.channels-1)<<4|0x4<<1)
+	b = appendCodedNumber(b, e.frames)
+
+	switch blockCode {
+	case 0x6:
+		b = append(b, byte(n-1))
+	case 0x7:
+		b = append(b, byte((n-1)>>8), byte(n-1))
+	}
+	if rateCode == 0xd {
+		b = append(b, byte(e.sampleRate>>8), byte(e.sampleRate))
+	}
+
+	e.bw.buf = append(b, crc8(b))
+}
+
+// writeSubframe writes s with the fixed predictor of the smallest residual,
+// or verbatim if it does not compress.
+func (e *encoder) writeSubframe(s []int32) {
+	n := len(s)
+
+	constant := true
+	for _, v := range s[1:] {
+		if v != s[0] {
+			constant = false
+			break
+		}
+	}
+	if constant {
+		e.bw.writeBits(subframeConstant<<1, 8)
+		e.bw.writeBits(uint64(s[0]), bitsPerSample)
+		return
+	}
+
+	order := bestFixedOrder(s)
+	if cap(e.residual) < n {
+		e.residual = make([]int32, n)
+	}
+	r := e.residual[:n-order]
+	fixedResidual(r, s, order)
+
+	partitionOrder, cost := e.bestPartition(r, n, order)
+	if 8+order*bitsPerSample+cost >= 8+n*bitsPerSample {
+		e.bw.writeBits(subframeVerbatim<<1, 8)
+		for _, v := range s {
+			e.bw.writeBits(uint64(v), bitsPerSample)
+		}
+		return
+	}
+
+	e.bw.writeBits(uint64(subframeFixed|order)<<1, 8)
+	for _, v := range s[:order] {
+		e.bw.writeBits(uint64(v), bitsPerSample)
+	}
+	e.writeResidual(r, n, order, partitionOrder)
+}
+
+// bestFixedOrder returns the order of the fixed predictor whose residual
+// has the smallest sum of absolute values.
+func bestFixedOrder(s []int32) int {
+	maxOrder := maxFixedOrder
+	if maxOrder > len(s)-1 {
+		maxOrder = len(s) - 1
+	}
+
+	var sums [maxFixedOrder + 1]uint64
+	for i := maxOrder; i < len(s); i++ {
+		e0 := int64(s[i])
+		e1 := e0 - int64(s[i-1])
+		sums[0] += abs(e0)
+		sums[1] += abs(e1)
+		if maxOrder < 2 {
+			continue
+		}
+		e2 := e1 - (int64(s[i-1]) - int64(s[i-2]))
+		sums[2] += abs(e2)
+		if maxOrder < 3 {
+			continue
+		}
+		e3 := e2 - (int64(s[i-1]) - 2*int64(s[i-2]) + int64(s[i-3]))
+		sums[3] += abs(e3)
+		if maxOrder < 4 {
+			continue
+		}
+		e4 := e3 - (int64(s[i-1]) - 3*int64(s[i-2]) + 3*int64(s[i-3]) - int64(s[i-4]))
+		sums[4] += abs(e4)
+	}
+
+	best := 0
+	for order := 1; order <= maxOrder; order++ {
+		if sums[order] < sums[best] {
+			best = order
+		}
+	}
+	return best
+}
+
+// fixedResidual sets r to the residual of s predicted by the fixed
+// predictor of order.
+func fixedResidual(r, s []int32, order int) {
+	for i := range r {
+		j := i + order
+		switch order {
+		case 0:
+			r[i] = s[j]
+		case 1:
+			r[i] = s[j] - s[j-1]
+		case 2:
+			r[i] = s[j] - 2*s[j-1] + s[j-2]
+		case 3:
+			r[i] = s[j] - 3*s[j-1] + 3*s[j-2] - s[j-3]
+		case 4:
+			r[i] = s[j] - 4*s[j-1] + 6*s[j-2] - 4*s[j-3] + s[j-4]
+		}
+	}
+}
+
+// partitionSize returns the number of residuals in partition i of order p
+// of a block of n samples predicted with order.
+func partitionSize(n, order, p, i int) int {
+	size := n >> p
+	if i == 0 {
+		size -= order
+	}
+	return size
+}
+
+// maxPartition returns the largest partition order usable for a block of
+// n samples predicted with order.
+func maxPartition(n, order int) int {
+	p := 0
+	for p < maxPartitionOrder && n%(1<<(p+1)) == 0 && n>>(p+1) > order {
+		p++
+	}
+	return p
+}
+
+// bestPartition returns the partition order that codes r in the fewest
+// bits, and the number of bits of the residual.
+func (e *encoder) bestPartition(r []int32, n, order int) (int, int) {
+	maxOrder := maxPartition(n, order)
+	parts := 1 << maxOrder
+
+	// sums of u>>k of every partition of the finest order, and the bits of
+	// the largest residual
+	size := parts * (maxRiceParam + 1)
+	if cap(e.sums) < size {
+		e.sums = make([]uint64, size)
+		e.bits = make([]uint8, parts)
+	}
+	sums, bits := e.sums[:size], e.bits[:parts]
+	for i := range sums {
+		sums[i] = 0
+	}
+	off := 0
+	for i := 0; i < parts; i++ {
+		part := r[off : off+partitionSize(n, order, maxOrder, i)]
+		off += len(part)
+
+		ps := sums[i*(maxRiceParam+1) : (i+1)*(maxRiceParam+1)]
+		bits[i] = 0
+		for _, v := range part {
+			u := zigzag(v)
+			for k := range ps {
+				ps[k] += uint64(u >> k)
+			}
+			if b := signedBits(v); b > bits[i] {
+				bits[i] = b
+			}
+		}
+	}
+
+	bestOrder, bestCost := 0, -1
+	for p := maxOrder; p >= 0; p-- {
+		if p < maxOrder {
+			// merge pairs of partitions
+			for i := 0; i < 1<<p; i++ {
+				dst := sums[i*(maxRiceParam+1) : (i+1)*(maxRiceParam+1)]
+				a := sums[2*i*(maxRiceParam+1) : (2*i+1)*(maxRiceParam+1)]
+				b := sums[(2*i+1)*(maxRiceParam+1) : (2*i+2)*(maxRiceParam+1)]
+				for k := range dst {
+					dst[k] = a[k] + b[k]
+				}
+				bits[i] = max8(bits[2*i], bits[2*i+1])
+			}
+		}
+
+		// coding method and partition order
+		cost := 2 + 4
+		for i := 0; i < 1<<p; i++ {
+			_, c := riceParam(sums[i*(maxRiceParam+1):(i+1)*(maxRiceParam+1)], partitionSize(n, order, p, i), bits[i])
+			cost += c
+		}
+		if bestCost < 0 || cost < bestCost {
+			bestOrder, bestCost = p, cost
+		}
+	}
+	return bestOrder, bestCost
+}
+
+// riceParam returns the rice parameter of the partition of size residuals
+// whose sums of u>>k are sums, and the number of bits of the partition.
+// bits are the bits of the largest residual, used if it is escaped.
+func riceParam(sums []uint64, size int, bits uint8) (int, int) {
+	best, bestCost := escapeParam, 4+5+size*int(bits)
+	for k, sum := range sums {
+		cost := 4 + size*(k+1) + int(sum)
+		if cost < bestCost {
+			best, bestCost = k, cost
+		}
+	}
+	return best, bestCost
+}
+
+func (e *encoder) writeResidual(r []int32, n, order, p int) {
+	// rice coding with 4-bit parameters
+	e.bw.writeBits(0, 2)
+	e.bw.writeBits(uint64(p), 4)
+
+	var sums [maxRiceParam + 1]uint64
+	off := 0
+	for i := 0; i < 1<<p; i++ {
+		part := r[off : off+partitionSize(n, order, p, i)]
+		off += len(part)
+
+		for k := range sums {
+			sums[k] = 0
+		}
+		var bits uint8
+		for _, v := range part {
+			u := zigzag(v)
+			for k := range sums {
+				sums[k] += uint64(u >> k)
+			}
+			if b := signedBits(v); b > bits {
+				bits = b
+			}
+		}
+
+		k, _ := riceParam(sums[:], len(part), bits)
+		e.bw.writeBits(uint64(k), 4)
+		if k == escapeParam {
+			e.bw.writeBits(uint64(bits), 5)
+			for _, v := range part {
+				e.bw.writeBits(uint64(v), uint(bits))
+			}
+			continue
+		}
+		for _, v := range part {
+			e.bw.writeRice(zigzag(v), uint(k))
+		}
+	}
+}
+
+// appendCodedNumber appends v coded like UTF-8, extended to 36 bits.
+func appendCodedNumber(b []byte, v uint64) []byte {
+	if v < 0x80 {
+		return append(b, byte(v))
+	}
+	n := 2
+	for v >= 1<<(5*n+1) {
+		n++
+	}
+	b = append(b, byte(uint(0xff00)>>n)|byte(v>>(6*(n-1))))
+	for i := n - 2; i >= 0; i-- {
+		b = append(b, 0x80|byte(v>>(6*i))&0x3f)
+	}
+	return b
+}
+
+func zigzag(v int32) uint32 {
+	return uint32(v<<1) ^ uint32(v>>31)
+}
+
+// signedBits returns the number of bits to code v in two's complement.
+func signedBits(v int32) uint8 {
+	if v == 0 {
+		return 0
+	}
+	if v < 0 {
+		v = ^v
+	}
+	b := uint8(1)
+	for v != 0 {
+		v >>= 1
+		b++
+	}
+	return b
+}
+
+func abs(v int64) uint64 {
+	if v < 0 {
+		return uint64(-v)
+	}
+	return uint64(v)
+}
+
+func max8(a, b uint8) uint8 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func log2(n int) int {
+	l := 0
+	for n > 1 {
+		n >>= 1
+		l++
+	}
+	return l
+}
+
+type bitWriter struct {
+	buf []byte
+	acc uint64
+	n   uint
+}
+
+func (w *bitWriter) reset() {
+	w.buf = w.buf[:0]
+	w.acc = 0
+	w.n = 0
+}
+
+// writeBits writes the low n bits of v, n must be 32 or less.
+func (w *bitWriter) writeBits(v uint64, n uint) {
+	if n == 0 {
+		return
+	}
+	w.acc = w.acc<<n | v&(1<<n-1)
+	w.n += n
+	for w.n >= 8 {
+		w.n -= 8
+		w.buf = append(w.buf, byte(w.acc>>w.n))
+	}
+}
+
+// writeRice writes u with the rice parameter k: u>>k in unary, then the
+// low k bits.
+func (w *bitWriter) writeRice(u uint32, k uint) {
+	q := uint(u >> k)
+	for q >= 32 {
+		w.writeBits(0, 32)
+		q -= 32
+	}
+	if q+1+k <= 32 {
+		w.writeBits(uint64(1)<<k|uint64(u)&(1<<k-1), q+1+k)
+		return
+	}
+	w.writeBits(1, q+1)
+	w.writeBits(uint64(u), k)
+}
+
+// align pads zero bits to the byte boundary.
+func (w *bitWriter) align() {
+	if w.n > 0 {
+		w.writeBits(0, 8-w.n)
+	}
+}
@@ -0,0 +1,493 @@
+package flac
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestOggWriter(t *testing.T) {
+	sine := func(n, channels int) []int16 {
+		s := make([]int16, n*channels)
+		for i := range s {
+			s[i] = int16(10000 * math.Sin(2*math.Pi*440*float64(i/channels)/48000+float64(i%channels)))
+		}
+		return s
+	}
+	noise := func(n, channels int) []int16 {
+		r := rand.New(rand.NewSource(1))
+		s := make([]int16, n*channels)
+		for i := range s {
+			s[i] = int16(r.Intn(1 << 16))
+		}
+		return s
+	}
+
+	tests := []struct {
+		name       string
+		sampleRate int
+		channels   int
+		samples    []int16
+		// number of samples per WriteInt16
+		chunk int
+	}{
+		{"sine", 48000, 1, sine(3*BlockSize, 1), 480},
+		{"short", 48000, 1, sine(1000, 1), 480},
+		{"partial", 44100, 2, sine(2*BlockSize+333, 2), 441 * 2},
+		{"silence", 48000, 1, make([]int16, BlockSize+1), 480},
+		{"noise", 32000, 1, noise(BlockSize, 1), BlockSize},
+		// partitions of zeros are escaped
+		{"burst", 48000, 1, append(noise(100, 1), make([]int16, BlockSize-100)...), 480},
+		{"page spanning", 48000, 8, noise(BlockSize, 8), 8 * 100},
+		{"uncommon rate", 11025, 1, sine(BlockSize, 1), 100},
+		{"rate from stream info", 100000, 1, sine(BlockSize, 1), 1000},
+		{"empty", 48000, 1, nil, 1},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		w, err := NewOggWriter(&buf, tt.sampleRate, tt.channels)
+		if err != nil {
+			t.Fatalf("%s: NewOggWriter() error: %v", tt.name, err)
+		}
+		for i := 0; i < len(tt.samples); i += tt.chunk {
+			end := i + tt.chunk
+			if end > len(tt.samples) {
+				end = len(tt.samples)
+			}
+			if err := w.WriteInt16(tt.samples[i:end]); err != nil {
+				t.Fatalf("%s: WriteInt16() error: %v", tt.name, err)
+			}
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("%s: Close() error: %v", tt.name, err)
+		}
+
+		s, err := decodeOggFLAC(buf.Bytes())
+		if err != nil {
+			t.Errorf("%s: invalid stream: %v", tt.name, err)
+			continue
+		}
+		if s.sampleRate != tt.sampleRate || s.channels != tt.channels {
+			t.Errorf("%s: stream of %d Hz %d channels, want %d Hz %d channels", tt.name, s.sampleRate, s.channels, tt.sampleRate, tt.channels)
+		}
+		if !equalSamples(s.samples, tt.samples) {
+			t.Errorf("%s: decoded %d samples do not match %d samples", tt.name, len(s.samples), len(tt.samples))
+		}
+		if want := int64(len(tt.samples) / tt.channels); s.granule != want {
+			t.Errorf("%s: last granule position = %d, want %d", tt.name, s.granule, want)
+		}
+	}
+}
+
+func TestOggWriterCompresses(t *testing.T) {
+	samples := make([]int16, 10*BlockSize)
+	for i := range samples {
+		samples[i] = int16(8000 * math.Sin(2*math.Pi*1000*float64(i)/48000))
+	}
+
+	var buf bytes.Buffer
+	w, err := NewOggWriter(&buf, 48000, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.WriteInt16(samples); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+
+	if raw := 2 * len(samples); buf.Len() > raw/2 {
+		t.Errorf("encoded %d bytes of %d bytes of a sine wave", buf.Len(), raw)
+	}
+}
+
+func TestNewOggWriterInvalid(t *testing.T) {
+	tests := []struct {
+		sampleRate int
+		channels   int
+	}{
+		{0, 1},
+		{1 << 20, 1},
+		{48000, 0},
+		{48000, 9},
+	}
+	for _, tt := range tests {
+		if _, err := NewOggWriter(&bytes.Buffer{}, tt.sampleRate, tt.channels); err == nil {
+			t.Errorf("NewOggWriter(%d, %d) = nil error", tt.sampleRate, tt.channels)
+		}
+	}
+}
+
+func TestAppendCodedNumber(t *testing.T) {
+	tests := []struct {
+		v    uint64
+		want []byte
+	}{
+		{0, []byte{0x00}},
+		{0x7f, []byte{0x7f}},
+		{0x80, []byte{0xc2, 0x80}},
+		{0x7ff, []byte{0xdf, 0xbf}},
+		{0x800, []byte{0xe0, 0xa0, 0x80}},
+		{0xffff, []byte{0xef, 0xbf, 0xbf}},
+		{0x10000, []byte{0xf0, 0x90, 0x80, 0x80}},
+		{1<<36 - 1, []byte{0xfe, 0xbf, 0xbf, 0xbf, 0xbf, 0xbf, 0xbf}},
+	}
+	for _, tt := range tests {
+		if got := appendCodedNumber(nil, tt.v); !bytes.Equal(got, tt.want) {
+			t.Errorf("appendCodedNumber(%#x) = % x, want % x", tt.v, got, tt.want)
+		}
+	}
+}
+
+func TestCRC(t *testing.T) {
+	// check values of the catalogue of parametrised CRC algorithms
+	check := []byte("123456789")
+	if got := crc8(check); got != 0xf4 {
+		t.Errorf("crc8() = %#x, want %#x", got, 0xf4)
+	}
+	if got := crc16(check); got != 0xfee8 {
+		t.Errorf("crc16() = %#x, want %#x", got, 0xfee8)
+	}
+	if got := oggCRC(check); got != 0x89a1897f {
+		t.Errorf("oggCRC() = %#x, want %#x", got, 0x89a1897f)
+	}
+}
+
+func equalSamples(a, b []int16) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+type decodedStream struct {
+	sampleRate int
+	channels   int
+	samples    []int16
+	granule    int64
+}
+
+// decodeOggFLAC decodes a stream written by OggWriter, checking the pages
+// and the frames.
+func decodeOggFLAC(b []byte) (*decodedStream, error) {
+	packets, granule, err := readOggPackets(b)
+	if err != nil {
+		return nil, err
+	}
+	if len(packets) < 2 {
+		return nil, errors.New("no header packets")
+	}
+
+	first := packets[0]
+	if len(first) != 51 || !bytes.Equal(first[:9], []byte{0x7f, 'F', 'L', 'A', 'C', 1, 0, 0, 1}) || string(first[9:13]) != "fLaC" {
+		return nil, fmt.Errorf("invalid first packet % x", first)
+	}
+	if first[13] != 0 || first[16] != 34 {
+		return nil, errors.New("invalid STREAMINFO header")
+	}
+	info := first[17:]
+	v := binary.BigEndian.Uint64(info[10:])
+	s := &decodedStream{
+		sampleRate: int(v >> 44),
+		channels:   int(v>>41&0x7) + 1,
+		granule:    granule,
+	}
+	if bps := int(v>>36&0x1f) + 1; bps != 16 {
+		return nil, fmt.Errorf("%d bits per sample", bps)
+	}
+	if min, max := binary.BigEndian.Uint16(info[0:]), binary.BigEndian.Uint16(info[2:]); min != BlockSize || max != BlockSize {
+		return nil, fmt.Errorf("block size %d to %d", min, max)
+	}
+
+	comment := packets[1]
+	if comment[0] != 0x84 || int(comment[1])<<16|int(comment[2])<<8|int(comment[3]) != len(comment)-4 {
+		return nil, fmt.Errorf("invalid VORBIS_COMMENT % x", comment)
+	}
+
+	for i, frame := range packets[2:] {
+		samples, err := decodeFrame(frame, s.sampleRate, s.channels, uint64(i))
+		if err != nil {
+			return nil, fmt.Errorf("frame %d: %w", i, err)
+		}
+		s.samples = append(s.samples, samples...)
+	}
+	return s, nil
+}
+
+// readOggPackets returns the packets of the pages of b, and the granule
+// position of the last page.
+func readOggPackets(b []byte) ([][]byte, int64, error) {
+	var (
+		packets [][]byte
+		packet  []byte
+		granule int64
+		serial  uint32
+	)
+	for seq := uint32(0); len(b) > 0; seq++ {
+		if len(b) < pageHeaderSize || string(b[:4]) != "OggS" || b[4] != 0 {
+			return nil, 0, errors.New("invalid page header")
+		}
+		segments := int(b[26])
+		size := pageHeaderSize + segments
+		for _, l := range b[pageHeaderSize : pageHeaderSize+segments] {
+			size += int(l)
+		}
+		page := append([]byte(nil), b[:size]...)
+		b = b[size:]
+
+		crc := binary.LittleEndian.Uint32(page[22:])
+		binary.LittleEndian.PutUint32(page[22:], 0)
+		if oggCRC(page) != crc {
+			return nil, 0, fmt.Errorf("page %d: CRC mismatch", seq)
+		}
+		if got := binary.LittleEndian.Uint32(page[18:]); got != seq {
+			return nil, 0, fmt.Errorf("page sequence %d, want %d", got, seq)
+		}
+		typ := page[5]
+		if seq == 0 {
+			serial = binary.LittleEndian.Uint32(page[14:])
+			if typ != pageBOS {
+				return nil, 0, errors.New("first page is not BOS")
+			}
+		} else if typ&pageBOS != 0 || binary.LittleEndian.Uint32(page[14:]) != serial {
+			return nil, 0, fmt.Errorf("page %d: invalid BOS or serial", seq)
+		}
+		if (typ&pageContinued != 0) != (packet != nil) {
+			return nil, 0, fmt.Errorf("page %d: invalid continued flag", seq)
+		}
+		if eos := typ&pageEOS != 0; eos != (len(b) == 0) {
+			return nil, 0, fmt.Errorf("page %d: EOS is %v", seq, eos)
+		}
+
+		ended := false
+		body := page[pageHeaderSize+segments:]
+		for _, l := range page[pageHeaderSize : pageHeaderSize+segments] {
+			packet = append(packet, body[:l]...)
+			body = body[l:]
+			if l < 255 {
+				packets = append(packets, packet)
+				packet = nil
+				ended = true
+			} else if packet == nil {
+				packet = []byte{}
+			}
+		}
+		pageGranule := int64(binary.LittleEndian.Uint64(page[6:]))
+		if !ended && segments > 0 && pageGranule != -1 {
+			return nil, 0, fmt.Errorf("page %d: granule position %d without packets ending", seq, pageGranule)
+		}
+		if pageGranule != -1 {
+			granule = pageGranule
+		}
+	}
+	if packet != nil {
+		return nil, 0, errors.New("last packet is not complete")
+	}
+	return packets, granule, nil
+}
+
+func decodeFrame(b []byte, sampleRate, channels int, number uint64) ([]int16, error) {
+	if len(b) < 6 {
+		return nil, errors.New("frame too short")
+	}
+	if crc := crc16(b[:len(b)-2]); crc != binary.BigEndian.Uint16(b[len(b)-2:]) {
+		return nil, errors.New("frame CRC mismatch")
+	}
+	if b[0] != 0xff || b[1] != 0xf8 {
+		return nil, fmt.Errorf("invalid sync code % x", b[:2])
+	}
+	blockCode, rateCode := b[2]>>4, b[2]&0xf
+	if int(b[3]>>4)+1 != channels || b[3]&0xf != 0x8 {
+		return nil, fmt.Errorf("invalid channels or sample size %#x", b[3])
+	}
+
+	r := &bitReader{b: b, pos: 32}
+	if got := r.codedNumber(); got != number {
+		return nil, fmt.Errorf("frame number %d, want %d", got, number)
+	}
+
+	var n int
+	switch {
+	case blockCode == 0x1:
+		n = 192
+	case blockCode >= 0x2 && blockCode <= 0x5:
+		n = 576 << (blockCode - 2)
+	case blockCode == 0x6:
+		n = int(r.bits(8)) + 1
+	case blockCode == 0x7:
+		n = int(r.bits(16)) + 1
+	case blockCode >= 0x8:
+		n = 256 << (blockCode - 8)
+	default:
+		return nil, errors.New("reserved block size")
+	}
+
+	rate := 0
+	switch rateCode {
+	case 0x0:
+		rate = sampleRate
+	case 0xd:
+		rate = int(r.bits(16))
+	default:
+		for sr, code := range sampleRateCodes {
+			if code == rateCode {
+				rate = sr
+			}
+		}
+	}
+	if rate != sampleRate {
+		return nil, fmt.Errorf("sample rate %d, want %d", rate, sampleRate)
+	}
+
+	if crc := crc8(b[:r.pos/8]); crc != b[r.pos/8] {
+		return nil, errors.New("header CRC mismatch")
+	}
+	r.pos += 8
+
+	samples := make([]int16, n*channels)
+	for ch := 0; ch < channels; ch++ {
+		s, err := r.subframe(n)
+		if err != nil {
+			return nil, fmt.Errorf("channel %d: %w", ch, err)
+		}
+		for i, v := range s {
+			samples[i*channels+ch] = int16(v)
+		}
+	}
+	if (r.pos+7)/8 != len(b)-2 {
+		return nil, fmt.Errorf("%d bytes left in frame", len(b)-2-(r.pos+7)/8)
+	}
+	return samples, nil
+}
+
+type bitReader struct {
+	b   []byte
+	pos int
+}
+
+func (r *bitReader) bits(n int) uint64 {
+	var v uint64
+	for i := 0; i < n; i++ {
+		bit := r.b[r.pos/8] >> (7 - r.pos%8) & 1
+		v = v<<1 | uint64(bit)
+		r.pos++
+	}
+	return v
+}
+
+func (r *bitReader) signed(n int) int32 {
+	if n == 0 {
+		return 0
+	}
+	v := r.bits(n)
+	return int32(int64(v<<(64-n)) >> (64 - n))
+}
+
+func (r *bitReader) codedNumber() uint64 {
+	first := r.bits(8)
+	if first < 0x80 {
+		return first
+	}
+	n := 0
+	for first&(0x80>>n) != 0 {
+		n++
+	}
+	v := first & (0xff >> (n + 1))
+	for i := 1; i < n; i++ {
+		v = v<<6 | r.bits(8)&0x3f
+	}
+	return v
+}
+
+func (r *bitReader) subframe(n int) ([]int32, error) {
+	if r.bits(1) != 0 {
+		return nil, errors.New("invalid subframe padding")
+	}
+	typ := int(r.bits(6))
+	if r.bits(1) != 0 {
+		return nil, errors.New("unexpected wasted bits")
+	}
+
+	s := make([]int32, n)
+	switch {
+	case typ == subframeConstant:
+		v := r.signed(16)
+		for i := range s {
+			s[i] = v
+		}
+	case typ == subframeVerbatim:
+		for i := range s {
+			s[i] = r.signed(16)
+		}
+	case typ >= subframeFixed && typ <= subframeFixed+maxFixedOrder:
+		order := typ - subframeFixed
+		for i := 0; i < order; i++ {
+			s[i] = r.signed(16)
+		}
+		residual, err := r.residual(n, order)
+		if err != nil {
+			return nil, err
+		}
+		for i, e := range residual {
+			j := i + order
+			switch order {
+			case 0:
+				s[j] = e
+			case 1:
+				s[j] = e + s[j-1]
+			case 2:
+				s[j] = e + 2*s[j-1] - s[j-2]
+			case 3:
+				s[j] = e + 3*s[j-1] - 3*s[j-2] + s[j-3]
+			case 4:
+				s[j] = e + 4*s[j-1] - 6*s[j-2] + 4*s[j-3] - s[j-4]
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unexpected subframe type %#x", typ)
+	}
+	return s, nil
+}
+
+func (r *bitReader) residual(n, order int) ([]int32, error) {
+	if method := r.bits(2); method != 0 {
+		return nil, fmt.Errorf("unexpected coding method %d", method)
+	}
+	p := int(r.bits(4))
+	if n%(1<<p) != 0 || n>>p < order {
+		return nil, fmt.Errorf("invalid partition order %d", p)
+	}
+
+	var residual []int32
+	for i := 0; i < 1<<p; i++ {
+		size := n >> p
+		if i == 0 {
+			size -= order
+		}
+		k := int(r.bits(4))
+		if k == escapeParam {
+			bits := int(r.bits(5))
+			for j := 0; j < size; j++ {
+				residual = append(residual, r.signed(bits))
+			}
+			continue
+		}
+		for j := 0; j < size; j++ {
+			q := 0
+			for r.bits(1) == 0 {
+				q++
+			}
+			u := uint32(q)<<k | uint32(r.bits(k))
+			residual = append(residual, int32(u>>1)^-int32(u&1))
+		}
+	}
+	return residual, nil
+}
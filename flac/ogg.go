@@ -0,0 +1,194 @@
+package flac
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	pageContinued = 0x01
+	pageBOS       = 0x02
+	pageEOS       = 0x04
+
+	pageHeaderSize = 27
+	maxSegments    = 255
+
+	vendor = "goradio"
+)
+
+// OggWriter writes 16-bit samples as an Ogg FLAC stream. The headers are
+// written by NewOggWriter, and every frame of BlockSize samples is written
+// on its own page as soon as it is complete.
+type OggWriter struct {
+	w        io.Writer
+	channels int
+	enc      *encoder
+
+	serial  uint32
+	seq     uint32
+	granule int64
+	page    []byte
+
+	// interleaved samples of the frame not complete yet
+	pending []int16
+	closed  bool
+}
+
+// NewOggWriter writes the headers of a stream of sampleRate and channels to
+// w.
+func NewOggWriter(w io.Writer, sampleRate, channels int) (*OggWriter, error) {
+	if err := validate(sampleRate, channels); err != nil {
+		return nil, err
+	}
+
+	var serial [4]byte
+	if _, err := rand.Read(serial[:]); err != nil {
+		return nil, fmt.Errorf("failed to generate ogg serial number: %w", err)
+	}
+
+	ow := &OggWriter{
+		w:        w,
+		channels: channels,
+		enc:      newEncoder(sampleRate, channels),
+		serial:   binary.LittleEndian.Uint32(serial[:]),
+		pending:  make([]int16, 0, BlockSize*channels),
+	}
+
+	// the first packet of the Ogg FLAC mapping 1.0, followed by one
+	// header packet
+	first := []byte{0x7f, 'F', 'L', 'A', 'C', 1, 0, 0, 1, 'f', 'L', 'a', 'C'}
+	first = append(first, streamInfo(sampleRate, channels, false)...)
+	if err := ow.writePacket(first, pageBOS); err != nil {
+		return nil, err
+	}
+	if err := ow.writePacket(vorbisComment(vendor, true), 0); err != nil {
+		return nil, err
+	}
+
+	return ow, nil
+}
+
+// WriteInt16 writes interleaved samples.
+func (w *OggWriter) WriteInt16(samples []int16) error {
+	if w.closed {
+		return errors.New("flac writer is closed")
+	}
+
+	for len(samples) > 0 {
+		n := cap(w.pending) - len(w.pending)
+		if n > len(samples) {
+			n = len(samples)
+		}
+		w.pending = append(w.pending, samples[:n]...)
+		samples = samples[n:]
+
+		if len(w.pending) == cap(w.pending) {
+			if err := w.writeFrame(0); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Close writes the samples of the frame not complete yet, and ends the
+// stream. It does not close the underlying writer.
+func (w *OggWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+
+	// whole samples of all channels
+	w.pending = w.pending[:len(w.pending)/w.channels*w.channels]
+	if len(w.pending) > 0 {
+		return w.writeFrame(pageEOS)
+	}
+	// a page without packets
+	return w.writePage(nil, nil, w.granule, pageEOS)
+}
+
+func (w *OggWriter) writeFrame(flags byte) error {
+	frame := w.enc.encode(w.pending)
+	w.granule += int64(len(w.pending) / w.channels)
+	w.pending = w.pending[:0]
+
+	return w.writePacket(frame, flags)
+}
+
+// writePacket writes packet on new pages. The granule position is of the
+// samples written so far. BOS is set on the first page and EOS on the last
+// page if they are in flags.
+func (w *OggWriter) writePacket(packet []byte, flags byte) error {
+	var lacing [maxSegments]byte
+	first := true
+	for {
+		segments, size := 0, 0
+		done := false
+		for segments < maxSegments {
+			n := len(packet) - size
+			if n >= 255 {
+				n = 255
+			} else {
+				done = true
+			}
+			lacing[segments] = byte(n)
+			segments++
+			size += n
+			if done {
+				break
+			}
+		}
+
+		var typ byte
+		if first {
+			typ |= flags & pageBOS
+		} else {
+			typ |= pageContinued
+		}
+		// no packet ends on the page
+		granule := int64(-1)
+		if done {
+			typ |= flags & pageEOS
+			granule = w.granule
+		}
+
+		if err := w.writePage(lacing[:segments], packet[:size], granule, typ); err != nil {
+			return err
+		}
+		packet = packet[size:]
+		first = false
+		if done {
+			return nil
+		}
+	}
+}
+
+func (w *OggWriter) writePage(lacing, body []byte, granule int64, typ byte) error {
+	size := pageHeaderSize + len(lacing) + len(body)
+	if cap(w.page) < size {
+		w.page = make([]byte, size)
+	}
+	p := w.page[:size]
+
+	copy(p[0:], "OggS")
+	p[4] = 0 // version
+	p[5] = typ
+	binary.LittleEndian.PutUint64(p[6:], uint64(granule))
+	binary.LittleEndian.PutUint32(p[14:], w.serial)
+	binary.LittleEndian.PutUint32(p[18:], w.seq)
+	binary.LittleEndian.PutUint32(p[22:], 0)
+	p[26] = byte(len(lacing))
+	copy(p[pageHeaderSize:], lacing)
+	copy(p[pageHeaderSize+len(lacing):], body)
+	binary.LittleEndian.PutUint32(p[22:], oggCRC(p))
+	w.seq++
+
+	if _, err := w.w.Write(p); err != nil {
+		return fmt.Errorf("failed to write ogg page: %w", err)
+	}
+	return nil
+}
@@ -2,10 +2,15 @@ module github.com/kechako/goradio
 
 go 1.18
 
+require (
+	github.com/gordonklaus/portaudio v0.0.0-20200911161147-bb74aa485641
+	github.com/urfave/cli/v2 v2.3.0
+	golang.org/x/term v0.10.0
+)
+
 require (
 	github.com/cpuguy83/go-md2man/v2 v2.0.0-20190314233015-f79a8a8ca69d // indirect
-	github.com/gordonklaus/portaudio v0.0.0-20200911161147-bb74aa485641 // indirect
 	github.com/russross/blackfriday/v2 v2.0.1 // indirect
 	github.com/shurcooL/sanitized_anchor_name v1.0.0 // indirect
-	github.com/urfave/cli/v2 v2.3.0 // indirect
+	golang.org/x/sys v0.10.0 // indirect
 )
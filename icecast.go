@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/kechako/goradio/icecast"
+	"github.com/kechako/goradio/player"
+	"github.com/kechako/goradio/resample"
+	"github.com/kechako/goradio/source"
+	"github.com/kechako/goradio/stream"
+	"github.com/kechako/goradio/wav"
+	cli "github.com/urfave/cli/v2"
+)
+
+// audio buffered while the connection to the server stalls
+const icecastBufferFrames = 200
+
+// pushRadio streams the audio to the Icecast mount specified by --icecast
+// instead of playing it. The filters and the volume are applied as when
+// playing.
+func pushRadio(ctx *cli.Context) error {
+	if ctx.String("output") != "" {
+		return ArgumentError("--icecast cannot be used with --output")
+	}
+	sampleRate := ctx.Int("sample-rate")
+	if sampleRate < 0 {
+		return ArgumentError("invalid sample rate")
+	}
+	quality, err := resampleQuality(ctx)
+	if err != nil {
+		return err
+	}
+	name, err := streamName(ctx)
+	if err != nil {
+		return err
+	}
+
+	src, err := openSource(ctx, 0, true)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	go printEvents(src.Events())
+
+	if sampleRate > 0 {
+		src, err = source.Resample(src, sampleRate, resample.WithQuality(quality))
+		if err != nil {
+			return err
+		}
+	}
+	process, _, err := outputProcess(ctx, src.SampleRate())
+	if err != nil {
+		return err
+	}
+
+	pctx, cancel := context.WithCancel(ctx.Context)
+	defer cancel()
+
+	b := stream.NewBroadcaster()
+	defer b.Close()
+	clientErr, err := pushBroadcast(pctx, ctx, b, src.SampleRate(), name, cancel)
+	if err != nil {
+		return err
+	}
+
+	frameSize := src.SampleRate() * 10 / 1000
+	err = runPipeline(pctx, src, src, frameSize, func(frame []int16) error {
+		process(frame)
+		return b.Write(frame)
+	})
+
+	b.Close()
+	if cerr := <-clientErr; cerr != nil {
+		return cerr
+	}
+	return err
+}
+
+// pushBroadcast streams the frames written to b to the Icecast mount
+// specified by --icecast, titled name. If the client gives up, cancel is
+// called to stop the writer. The returned channel receives the result of
+// the client, which stops when ctx is done or b is closed.
+func pushBroadcast(ctx context.Context, cctx *cli.Context, b *stream.Broadcaster, sampleRate int, name string, cancel context.CancelFunc) (<-chan error, error) {
+	client, err := newIcecastClient(cctx, sampleRate, name)
+	if err != nil {
+		return nil, err
+	}
+	client.SetTitle(name)
+
+	go printIcecastEvents(client.Events())
+
+	sub := b.Subscribe(icecastBufferFrames)
+	clientErr := make(chan error, 1)
+	go func() {
+		err := client.Run(ctx, sub.Frames())
+		cancel()
+		clientErr <- err
+	}()
+	return clientErr, nil
+}
+
+// playerPush streams the audio played by a player to Icecast. The frames
+// are passed by write, from the tap of the player.
+type playerPush struct {
+	// events of the client, closed when it stops
+	events <-chan icecast.Event
+	// result of the client
+	err <-chan error
+	b   *stream.Broadcaster
+}
+
+// pushPlayer streams the audio played by p to the Icecast mount specified by
+// --icecast. The title follows the station tuned. The client stops when ctx
+// is done.
+func pushPlayer(ctx context.Context, cctx *cli.Context, p *player.Player, sampleRate int) (*playerPush, error) {
+	client, err := newIcecastClient(cctx, sampleRate, "goradio")
+	if err != nil {
+		return nil, err
+	}
+
+	// subscribe before reading the tuning not to miss a change
+	events := p.Subscribe()
+	client.SetTitle(tuningTitle(p.Tuning()))
+	go func() {
+		for ev := range events.Events() {
+			if ev, ok := ev.(player.TunedEvent); ok {
+				client.SetTitle(tuningTitle(ev.Tuning))
+			}
+		}
+	}()
+
+	b := stream.NewBroadcaster()
+	sub := b.Subscribe(icecastBufferFrames)
+
+	clientErr := make(chan error, 1)
+	go func() {
+		clientErr <- client.Run(ctx, sub.Frames())
+		events.Close()
+		// frames written later are discarded
+		b.Close()
+	}()
+	return &playerPush{
+		events: client.Events(),
+		err:    clientErr,
+		b:      b,
+	}, nil
+}
+
+// write sends frame to the client, it never blocks.
+func (pp *playerPush) write(frame []int16) {
+	pp.b.Write(frame)
+}
+
+// newIcecastClient returns the client of the mount specified by --icecast,
+// streaming 16-bit mono audio at sampleRate.
+func newIcecastClient(ctx *cli.Context, sampleRate int, name string) (*icecast.Client, error) {
+	client, err := icecast.NewClient(ctx.String("icecast"), wav.Format{
+		SampleRate:    sampleRate,
+		Channels:      1,
+		BitsPerSample: 16,
+	}, icecast.WithName(name), icecast.WithPublic(ctx.Bool("icecast-public")))
+	if err != nil {
+		return nil, ArgumentError(err.Error())
+	}
+	return client, nil
+}
+
+// tuningTitle returns the stream title of t, the station name or the
+// frequency.
+func tuningTitle(t player.Tuning) string {
+	if t.Station != "" {
+		return t.Station
+	}
+	if t.Frequency != 0 {
+		return t.Frequency.String()
+	}
+	return "goradio"
+}
+
+func printIcecastEvents(events <-chan icecast.Event) {
+	for ev := range events {
+		if msg := icecastMessage(ev); msg != "" {
+			fmt.Fprintln(os.Stderr, msg)
+		}
+	}
+}
+
+// icecastMessage returns the message shown for ev, or "" if it is not
+// shown.
+func icecastMessage(ev icecast.Event) string {
+	switch ev := ev.(type) {
+	case icecast.ConnectedEvent:
+		return fmt.Sprintf("connected to icecast (%s, %s)", ev.Method, ev.ContentType)
+	case icecast.DisconnectedEvent:
+		return fmt.Sprintf("disconnected from icecast: %v, reconnecting in %v", ev.Err, ev.Retry)
+	case icecast.MetadataErrorEvent:
+		return ev.Err.Error()
+	}
+	return ""
+}
@@ -0,0 +1,379 @@
+package icecast
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kechako/goradio/wav"
+)
+
+var testFormat = wav.Format{
+	SampleRate:    8000,
+	Channels:      1,
+	BitsPerSample: 16,
+}
+
+// stubServer is an Icecast server on raw connections, as the body of a
+// source has no length. Handshakes are passed to source, metadata updates
+// are accepted and sent to metadata.
+type stubServer struct {
+	l        net.Listener
+	source   func(conn net.Conn, r *bufio.Reader, req *http.Request)
+	metadata chan *http.Request
+	wg       sync.WaitGroup
+}
+
+func newStubServer(t *testing.T, source func(conn net.Conn, r *bufio.Reader, req *http.Request)) *stubServer {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &stubServer{
+		l:        l,
+		source:   source,
+		metadata: make(chan *http.Request, 16),
+	}
+	s.wg.Add(1)
+	go s.serve()
+	t.Cleanup(func() {
+		l.Close()
+		s.wg.Wait()
+	})
+	return s
+}
+
+func (s *stubServer) url() string {
+	return "http://source:hackme@" + s.l.Addr().String() + "/radio.wav"
+}
+
+func (s *stubServer) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.l.Accept()
+		if err != nil {
+			return
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer conn.Close()
+
+			r := bufio.NewReader(conn)
+			req, err := http.ReadRequest(r)
+			if err != nil {
+				return
+			}
+			if req.URL.Path == "/admin/metadata" {
+				io.WriteString(conn, "HTTP/1.1 200 OK\r\nContent-Length: 0\r\nConnection: close\r\n\r\n")
+				s.metadata <- req
+				return
+			}
+			s.source(conn, r, req)
+		}()
+	}
+}
+
+// handshake is a source request received by the stub server.
+type handshake struct {
+	method      string
+	contentType string
+	at          time.Time
+}
+
+func recordHandshake(handshakes chan<- handshake, req *http.Request) {
+	handshakes <- handshake{
+		method:      req.Method,
+		contentType: req.Header.Get("Content-Type"),
+		at:          time.Now(),
+	}
+}
+
+// runClient runs c with frames of silence until the test ends.
+func runClient(t *testing.T, c *Client) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	frames := make(chan []int16)
+	done := make(chan error, 1)
+	go func() {
+		done <- c.Run(ctx, frames)
+	}()
+	go func() {
+		frame := make([]int16, 80)
+		for {
+			select {
+			case frames <- frame:
+			case <-ctx.Done():
+				return
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("Run() = %v", err)
+		}
+	})
+}
+
+// nextEvent returns the next event of c of type E.
+func nextEvent[E Event](t *testing.T, c *Client) E {
+	t.Helper()
+
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case ev, ok := <-c.Events():
+			if !ok {
+				t.Fatal("events are closed")
+			}
+			if ev, ok := ev.(E); ok {
+				return ev
+			}
+		case <-timeout:
+			var ev E
+			t.Fatalf("timed out waiting for %T", ev)
+		}
+	}
+}
+
+func TestNewClientMount(t *testing.T) {
+	tests := []struct {
+		url     string
+		wantErr error
+	}{
+		{"http://localhost:8000/radio", nil},
+		{"http://localhost:8000/radio.wav", nil},
+		{"http://localhost:8000/radio.WAV", nil},
+		{"http://localhost:8000/radio.wave", nil},
+		{"http://localhost:8000/fm.ogg", nil},
+		{"http://localhost:8000/fm.OGG", nil},
+		{"http://localhost:8000/fm.oga", nil},
+		{"http://localhost:8000/radio.mp3", ErrUnsupportedMount},
+		{"http://localhost:8000/radio.opus", ErrUnsupportedMount},
+		{"http://localhost:8000/radio.flac", ErrUnsupportedMount},
+	}
+	for _, tt := range tests {
+		_, err := NewClient(tt.url, testFormat)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("NewClient(%q) error = %v, want %v", tt.url, err, tt.wantErr)
+		}
+	}
+}
+
+func TestNewClientInvalidURL(t *testing.T) {
+	for _, rawURL := range []string{"", "localhost:8000/radio", "ftp://localhost/radio", "http://localhost:8000", "http://localhost:8000/"} {
+		if _, err := NewClient(rawURL, testFormat); err == nil {
+			t.Errorf("NewClient(%q) error = nil", rawURL)
+		}
+	}
+}
+
+func TestClientFallback(t *testing.T) {
+	handshakes := make(chan handshake, 16)
+	bodies := make(chan string, 1)
+	s := newStubServer(t, func(conn net.Conn, r *bufio.Reader, req *http.Request) {
+		recordHandshake(handshakes, req)
+		switch {
+		case req.Method == http.MethodPut:
+			io.WriteString(conn, "HTTP/1.1 405 Method Not Allowed\r\n\r\n")
+		case req.Header.Get("Content-Type") != "audio/x-wav":
+			io.WriteString(conn, "HTTP/1.0 415 Unsupported Media Type\r\n\r\n")
+		default:
+			io.WriteString(conn, "HTTP/1.0 200 OK\r\n\r\n")
+			header := make([]byte, 4)
+			if _, err := io.ReadFull(r, header); err == nil {
+				bodies <- string(header)
+			}
+			io.Copy(io.Discard, r)
+		}
+	})
+
+	c, err := NewClient(s.url(), testFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	runClient(t, c)
+
+	ev := nextEvent[ConnectedEvent](t, c)
+	if ev.Method != "SOURCE" || ev.ContentType != "audio/x-wav" {
+		t.Errorf("ConnectedEvent = %+v, want SOURCE with audio/x-wav", ev)
+	}
+
+	want := []handshake{
+		{method: http.MethodPut, contentType: "audio/wav"},
+		{method: "SOURCE", contentType: "audio/wav"},
+		{method: "SOURCE", contentType: "audio/x-wav"},
+	}
+	for i, w := range want {
+		h := <-handshakes
+		if h.method != w.method || h.contentType != w.contentType {
+			t.Errorf("handshake %d = %s %s, want %s %s", i, h.method, h.contentType, w.method, w.contentType)
+		}
+	}
+
+	select {
+	case body := <-bodies:
+		if body != "RIFF" {
+			t.Errorf("body starts with %q, want RIFF", body)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the body")
+	}
+}
+
+func TestClientOgg(t *testing.T) {
+	handshakes := make(chan handshake, 16)
+	bodies := make(chan []byte, 1)
+	s := newStubServer(t, func(conn net.Conn, r *bufio.Reader, req *http.Request) {
+		recordHandshake(handshakes, req)
+		io.WriteString(conn, "HTTP/1.1 100 Continue\r\n\r\n")
+		// the first page and the header of the second one
+		page := make([]byte, 28+51+27)
+		if _, err := io.ReadFull(r, page); err == nil {
+			bodies <- page
+		}
+		io.Copy(io.Discard, r)
+	})
+
+	c, err := NewClient(strings.TrimSuffix(s.url(), ".wav")+".ogg", testFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	runClient(t, c)
+
+	ev := nextEvent[ConnectedEvent](t, c)
+	if ev.Method != http.MethodPut || ev.ContentType != "audio/ogg" {
+		t.Errorf("ConnectedEvent = %+v, want PUT with audio/ogg", ev)
+	}
+	if h := <-handshakes; h.contentType != "audio/ogg" {
+		t.Errorf("handshake content type = %s, want audio/ogg", h.contentType)
+	}
+
+	select {
+	case page := <-bodies:
+		if string(page[:4]) != "OggS" || string(page[28:33]) != "\x7fFLAC" || string(page[79:83]) != "OggS" {
+			t.Errorf("body starts with %q, want Ogg FLAC", page)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the body")
+	}
+}
+
+func TestNewClientOggFormat(t *testing.T) {
+	format := testFormat
+	format.MuLaw = true
+	format.BitsPerSample = 8
+	if _, err := NewClient("http://localhost:8000/fm.ogg", format); err == nil {
+		t.Error("NewClient() of mu-law to an Ogg mount = nil error")
+	}
+}
+
+func TestClientUnauthorized(t *testing.T) {
+	s := newStubServer(t, func(conn net.Conn, r *bufio.Reader, req *http.Request) {
+		io.WriteString(conn, "HTTP/1.1 401 Unauthorized\r\n\r\n")
+	})
+
+	c, err := NewClient(s.url(), testFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = c.Run(context.Background(), make(chan []int16))
+	if !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("Run() = %v, want %v", err, ErrUnauthorized)
+	}
+}
+
+func TestClientReconnect(t *testing.T) {
+	handshakes := make(chan handshake, 16)
+	s := newStubServer(t, func(conn net.Conn, r *bufio.Reader, req *http.Request) {
+		recordHandshake(handshakes, req)
+		// accept and drop the connection at once
+		io.WriteString(conn, "HTTP/1.1 100 Continue\r\n\r\n")
+	})
+
+	const (
+		minBackoff = 20 * time.Millisecond
+		maxBackoff = 80 * time.Millisecond
+	)
+	c, err := NewClient(s.url(), testFormat, WithReconnectBackoff(minBackoff, maxBackoff))
+	if err != nil {
+		t.Fatal(err)
+	}
+	runClient(t, c)
+
+	prev := <-handshakes
+	for _, want := range []time.Duration{minBackoff, 2 * minBackoff, maxBackoff, maxBackoff} {
+		ev := nextEvent[DisconnectedEvent](t, c)
+		if ev.Retry != want {
+			t.Errorf("DisconnectedEvent.Retry = %v, want %v", ev.Retry, want)
+		}
+
+		h := <-handshakes
+		if h.method != http.MethodPut || h.contentType != "audio/wav" {
+			t.Errorf("reconnected with %s %s, want the negotiated PUT audio/wav", h.method, h.contentType)
+		}
+		if d := h.at.Sub(prev.at); d < want {
+			t.Errorf("reconnected after %v, want at least %v", d, want)
+		}
+		prev = h
+	}
+}
+
+func TestClientMetadata(t *testing.T) {
+	s := newStubServer(t, func(conn net.Conn, r *bufio.Reader, req *http.Request) {
+		io.WriteString(conn, "HTTP/1.1 100 Continue\r\n\r\n")
+		io.Copy(io.Discard, r)
+	})
+
+	c, err := NewClient(s.url(), testFormat, WithName("goradio"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the title set before connecting is sent once connected
+	c.SetTitle("FM Tokyo")
+	runClient(t, c)
+
+	nextMetadata := func(song string) {
+		t.Helper()
+
+		var req *http.Request
+		select {
+		case req = <-s.metadata:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for the metadata %q", song)
+		}
+
+		if req.Method != http.MethodGet {
+			t.Errorf("metadata method = %s, want GET", req.Method)
+		}
+		user, password, ok := req.BasicAuth()
+		if !ok || user != "source" || password != "hackme" {
+			t.Errorf("metadata auth = %q, %q, %v, want source, hackme", user, password, ok)
+		}
+		want := url.Values{
+			"mount": {"/radio.wav"},
+			"mode":  {"updinfo"},
+			"song":  {song},
+		}
+		if got := req.URL.Query(); got.Encode() != want.Encode() {
+			t.Errorf("metadata query = %v, want %v", got, want)
+		}
+	}
+
+	nextMetadata("FM Tokyo")
+	c.SetTitle("J-WAVE")
+	nextMetadata("J-WAVE")
+}
@@ -0,0 +1,260 @@
+package jitter
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"time"
+)
+
+const (
+	defaultTargetLatency = 100 * time.Millisecond
+	defaultMaxRatio      = 0.002
+
+	// smoothing factor of the resampling ratio applied on each Read
+	ratioSmoothing = 0.05
+)
+
+var ErrClosed = errors.New("jitter buffer closed")
+
+// Stats is the statistics of a Buffer.
+type Stats struct {
+	// Prefills is the number of times the buffer waited to be filled up to
+	// the target latency, including the first time.
+	Prefills int
+	// Underruns is the number of times the buffer ran out of samples.
+	Underruns int
+	// Overruns is the number of times samples were dropped because the
+	// buffer was full.
+	Overruns int
+}
+
+// Buffer is a ring buffer between a producer and a consumer running on
+// different clocks. It resamples the samples slightly to hold the fill
+// level at the target latency.
+type Buffer struct {
+	mu   sync.Mutex
+	ring []int16
+	head int
+	n    int
+
+	target   int
+	maxRatio float64
+	ratio    float64
+	frac     float64
+
+	prefilling bool
+	closed     bool
+	stats      Stats
+}
+
+func New(sampleRate int, opts ...Option) *Buffer {
+	options := bufferOptions{
+		targetLatency: defaultTargetLatency,
+		maxRatio:      defaultMaxRatio,
+	}
+	for _, opt := range opts {
+		opt.apply(&options)
+	}
+
+	target := int(float64(sampleRate) * options.targetLatency.Seconds())
+	if target < 2 {
+		target = 2
+	}
+	capacity := int(float64(sampleRate) * options.maxLatency.Seconds())
+	if capacity < 2*target {
+		capacity = 3 * target
+	}
+
+	return &Buffer{
+		ring:       make([]int16, capacity),
+		target:     target,
+		maxRatio:   options.maxRatio,
+		ratio:      1,
+		prefilling: true,
+		stats: Stats{
+			Prefills: 1,
+		},
+	}
+}
+
+// Write appends frame to the buffer. It never blocks, the oldest samples
+// are dropped back to the target latency if the buffer is full.
+func (b *Buffer) Write(frame []int16) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return ErrClosed
+	}
+
+	if len(frame) > len(b.ring) {
+		frame = frame[len(frame)-len(b.ring):]
+	}
+	if b.n+len(frame) > len(b.ring) {
+		drop := b.n + len(frame) - b.target
+		if drop > b.n {
+			drop = b.n
+		}
+		b.discard(drop)
+		b.stats.Overruns++
+	}
+
+	tail := (b.head + b.n) % len(b.ring)
+	copied := copy(b.ring[tail:], frame)
+	copy(b.ring, frame[copied:])
+	b.n += len(frame)
+
+	return nil
+}
+
+// Read fills frame with resampled samples. It never blocks, frame is filled
+// with silence while prefilling. After Close, it returns io.EOF once all
+// samples are read.
+func (b *Buffer) Read(frame []int16) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed && b.n < 2 {
+		return io.EOF
+	}
+
+	if b.prefilling && !b.closed {
+		if b.n < b.target {
+			silence(frame)
+			return nil
+		}
+		b.prefilling = false
+	}
+
+	b.adjust()
+
+	for i := range frame {
+		if b.n < 2 {
+			silence(frame[i:])
+			if !b.closed {
+				b.stats.Underruns++
+				b.stats.Prefills++
+				b.prefilling = true
+				b.frac = 0
+			}
+			return nil
+		}
+
+		// linear interpolation
+		s0, s1 := float64(b.at(0)), float64(b.at(1))
+		frame[i] = int16(s0 + (s1-s0)*b.frac)
+
+		b.frac += b.ratio
+		k := int(b.frac)
+		b.frac -= float64(k)
+		b.discard(k)
+	}
+
+	return nil
+}
+
+// Close closes the buffer for writing. Read returns the remaining samples
+// without prefilling.
+func (b *Buffer) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	return nil
+}
+
+func (b *Buffer) Stats() Stats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.stats
+}
+
+// Len returns the number of buffered samples.
+func (b *Buffer) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.n
+}
+
+// Ratio returns the current resampling ratio. A ratio greater than 1 means
+// the buffer is consumed faster than the producer to reduce the latency.
+func (b *Buffer) Ratio() float64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.ratio
+}
+
+// adjust updates the resampling ratio in proportion to the deviation of the
+// fill level from the target.
+func (b *Buffer) adjust() {
+	deviation := float64(b.n-b.target) / float64(b.target)
+	ratio := 1 + deviation*b.maxRatio
+	if ratio > 1+b.maxRatio {
+		ratio = 1 + b.maxRatio
+	} else if ratio < 1-b.maxRatio {
+		ratio = 1 - b.maxRatio
+	}
+	b.ratio += (ratio - b.ratio) * ratioSmoothing
+}
+
+func (b *Buffer) at(i int) int16 {
+	return b.ring[(b.head+i)%len(b.ring)]
+}
+
+func (b *Buffer) discard(n int) {
+	b.head = (b.head + n) % len(b.ring)
+	b.n -= n
+}
+
+func silence(frame []int16) {
+	for i := range frame {
+		frame[i] = 0
+	}
+}
+
+type bufferOptions struct {
+	targetLatency time.Duration
+	maxLatency    time.Duration
+	maxRatio      float64
+}
+
+type Option interface {
+	apply(opts *bufferOptions)
+}
+
+type optionFunc func(opts *bufferOptions)
+
+func (f optionFunc) apply(opts *bufferOptions) {
+	f(opts)
+}
+
+// WithTargetLatency sets the fill level the buffer tries to hold.
+func WithTargetLatency(latency time.Duration) Option {
+	return optionFunc(func(opts *bufferOptions) {
+		if latency > 0 {
+			opts.targetLatency = latency
+		}
+	})
+}
+
+// WithMaxLatency sets the capacity of the buffer. It must be at least twice
+// the target latency, otherwise three times the target latency is used.
+func WithMaxLatency(latency time.Duration) Option {
+	return optionFunc(func(opts *bufferOptions) {
+		opts.maxLatency = latency
+	})
+}
+
+// WithMaxRatio sets the maximum deviation of the resampling ratio from 1,
+// e.g. 0.002 for 2000ppm.
+func WithMaxRatio(ratio float64) Option {
+	return optionFunc(func(opts *bufferOptions) {
+		if ratio >= 0 && ratio < 0.1 {
+			opts.maxRatio = ratio
+		}
+	})
+}
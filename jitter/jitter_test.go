@@ -0,0 +1,196 @@
+package jitter
+
+import (
+	"errors"
+	"io"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// ramp returns n samples counting up from start.
+func ramp(start, n int) []int16 {
+	frame := make([]int16, n)
+	for i := range frame {
+		frame[i] = int16(start + i)
+	}
+	return frame
+}
+
+// newExact returns a buffer of 100 samples of target latency that does not
+// resample.
+func newExact() *Buffer {
+	return New(1000, WithTargetLatency(100*time.Millisecond), WithMaxRatio(0))
+}
+
+func TestBufferPrefill(t *testing.T) {
+	tests := []struct {
+		name  string
+		write int
+		want  []int16
+	}{
+		{"empty", 0, make([]int16, 10)},
+		{"below target", 99, make([]int16, 10)},
+		{"target", 100, ramp(1, 10)},
+		{"above target", 150, ramp(1, 10)},
+	}
+	for _, tt := range tests {
+		b := newExact()
+		if err := b.Write(ramp(1, tt.write)); err != nil {
+			t.Fatal(err)
+		}
+
+		got := make([]int16, 10)
+		if err := b.Read(got); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: Read() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestBufferUnderrun(t *testing.T) {
+	b := newExact()
+	b.Write(ramp(1, 100))
+
+	got := make([]int16, 120)
+	if err := b.Read(got); err != nil {
+		t.Fatal(err)
+	}
+	// the last sample is kept to interpolate
+	want := append(ramp(1, 99), make([]int16, 21)...)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Read() = %v, want %v", got, want)
+	}
+
+	wantStats := Stats{Prefills: 2, Underruns: 1}
+	if stats := b.Stats(); stats != wantStats {
+		t.Errorf("Stats() = %+v, want %+v", stats, wantStats)
+	}
+
+	// prefilling again
+	b.Write(ramp(200, 50))
+	got = make([]int16, 10)
+	b.Read(got)
+	if !reflect.DeepEqual(got, make([]int16, 10)) {
+		t.Errorf("Read() while prefilling = %v, want silence", got)
+	}
+}
+
+func TestBufferOverrun(t *testing.T) {
+	tests := []struct {
+		name    string
+		writes  []int
+		wantLen int
+		wantOvr int
+	}{
+		{"capacity", []int{300}, 300, 0},
+		{"one over", []int{300, 1}, 100, 1},
+		// dropping is limited to the samples buffered
+		{"twice", []int{250, 100, 250}, 250, 2},
+		// only the newest samples of a frame larger than the buffer
+		{"large frame", []int{1000}, 300, 0},
+	}
+	for _, tt := range tests {
+		b := newExact()
+		n := 0
+		for _, w := range tt.writes {
+			if err := b.Write(ramp(n, w)); err != nil {
+				t.Fatal(err)
+			}
+			n += w
+		}
+
+		if got := b.Len(); got != tt.wantLen {
+			t.Errorf("%s: Len() = %d, want %d", tt.name, got, tt.wantLen)
+		}
+		if got := b.Stats().Overruns; got != tt.wantOvr {
+			t.Errorf("%s: Overruns = %d, want %d", tt.name, got, tt.wantOvr)
+		}
+
+		// the newest samples are kept
+		got := make([]int16, 10)
+		b.Read(got)
+		if want := ramp(n-tt.wantLen, 10); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: Read() = %v, want %v", tt.name, got, want)
+		}
+	}
+}
+
+func TestBufferClose(t *testing.T) {
+	b := newExact()
+	b.Write(ramp(1, 50))
+	b.Close()
+
+	if err := b.Write(ramp(1, 10)); !errors.Is(err, ErrClosed) {
+		t.Errorf("Write() after Close() = %v, want %v", err, ErrClosed)
+	}
+
+	// the rest is read without prefilling
+	got := make([]int16, 60)
+	if err := b.Read(got); err != nil {
+		t.Fatal(err)
+	}
+	want := append(ramp(1, 49), make([]int16, 11)...)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Read() = %v, want %v", got, want)
+	}
+	if err := b.Read(got); !errors.Is(err, io.EOF) {
+		t.Errorf("Read() of drained buffer = %v, want %v", err, io.EOF)
+	}
+	if stats := b.Stats(); stats.Underruns != 0 {
+		t.Errorf("Underruns = %d after Close(), want 0", stats.Underruns)
+	}
+}
+
+func TestBufferDrift(t *testing.T) {
+	const (
+		sampleRate = 48000
+		frameSize  = 480
+		maxRatio   = 0.002
+		// five time constants of the fill level
+		steps = 30000
+	)
+	tests := []struct {
+		name string
+		// clock drift of the producer
+		drift float64
+	}{
+		{"none", 0},
+		{"fast producer", 0.001},
+		{"slow producer", -0.001},
+	}
+	for _, tt := range tests {
+		b := New(sampleRate, WithTargetLatency(100*time.Millisecond), WithMaxRatio(maxRatio))
+		target := sampleRate / 10
+
+		var produced float64
+		written, level := 0, 0
+		frame := make([]int16, frameSize)
+		for i := 0; i < steps; i++ {
+			produced += frameSize * (1 + tt.drift)
+			n := int(produced) - written
+			b.Write(make([]int16, n))
+			written += n
+
+			// the level the ratio is adjusted to
+			level = b.Len()
+			b.Read(frame)
+		}
+
+		// the ratio cancels the drift
+		if got := b.Ratio(); math.Abs(got-(1+tt.drift)) > 0.0001 {
+			t.Errorf("%s: Ratio() = %.5f, want %.5f", tt.name, got, 1+tt.drift)
+		}
+		// the fill level settles off the target in proportion to the drift
+		want := float64(target) * (1 + tt.drift/maxRatio)
+		if got := float64(level); math.Abs(got-want) > 0.1*float64(target) {
+			t.Errorf("%s: Len() = %.0f before Read(), want %.0f", tt.name, got, want)
+		}
+		if stats := b.Stats(); stats.Underruns != 0 || stats.Overruns != 0 {
+			t.Errorf("%s: Stats() = %+v, want no underruns nor overruns", tt.name, stats)
+		}
+	}
+}
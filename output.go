@@ -0,0 +1,199 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kechako/goradio/resample"
+	"github.com/kechako/goradio/source"
+	"github.com/kechako/goradio/wav"
+	cli "github.com/urfave/cli/v2"
+)
+
+type outputFormat int
+
+const (
+	formatS16LE outputFormat = iota + 1
+	formatF32LE
+	formatWAV
+)
+
+func parseOutputFormat(ctx *cli.Context) (outputFormat, error) {
+	name := ctx.String("format")
+	if name == "" {
+		if strings.EqualFold(filepath.Ext(ctx.String("output")), ".wav") {
+			return formatWAV, nil
+		}
+		return formatS16LE, nil
+	}
+
+	switch strings.ToLower(name) {
+	case "s16le":
+		return formatS16LE, nil
+	case "f32le":
+		return formatF32LE, nil
+	case "wav":
+		return formatWAV, nil
+	default:
+		return 0, ArgumentError("invalid output format")
+	}
+}
+
+// pipeRadio writes the audio to the path specified by --output instead of
+// playing it. The filters and the volume are applied as when playing.
+func pipeRadio(ctx *cli.Context) (err error) {
+	format, err := parseOutputFormat(ctx)
+	if err != nil {
+		return err
+	}
+	sampleRate := ctx.Int("sample-rate")
+	if sampleRate < 0 {
+		return ArgumentError("invalid sample rate")
+	}
+	quality, err := resampleQuality(ctx)
+	if err != nil {
+		return err
+	}
+
+	src, err := openSource(ctx, 0, true)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	go printEvents(src.Events())
+
+	if sampleRate > 0 {
+		src, err = source.Resample(src, sampleRate, resample.WithQuality(quality))
+		if err != nil {
+			return err
+		}
+	}
+	process, _, err := outputProcess(ctx, src.SampleRate())
+	if err != nil {
+		return err
+	}
+
+	out, err := createOutput(ctx.String("output"))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := out.Close(); cerr != nil && err == nil {
+			err = fmt.Errorf("failed to close output: %w", cerr)
+		}
+	}()
+
+	w, err := newPCMWriter(out, format, src.SampleRate())
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := w.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}()
+
+	frameSize := src.SampleRate() * 10 / 1000
+
+	return runPipeline(ctx.Context, src, src, frameSize, func(frame []int16) error {
+		process(frame)
+		return w.Write(frame)
+	})
+}
+
+// createOutput opens path for writing, "-" means stdout. Only regular files
+// are seekable, so that the WAV header is not patched on pipes.
+func createOutput(path string) (io.WriteCloser, error) {
+	if path == "-" {
+		return nopCloser{os.Stdout}, nil
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create output file: %w", err)
+	}
+	if fi, err := file.Stat(); err == nil && !fi.Mode().IsRegular() {
+		return nopSeeker{file}, nil
+	}
+	return file, nil
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error { return nil }
+
+type nopSeeker struct {
+	io.WriteCloser
+}
+
+type pcmWriter struct {
+	w      io.Writer
+	format outputFormat
+	wav    *wav.Writer
+	buf    []byte
+}
+
+func newPCMWriter(w io.Writer, format outputFormat, sampleRate int) (*pcmWriter, error) {
+	pw := &pcmWriter{
+		w:      w,
+		format: format,
+	}
+	if format == formatWAV {
+		ww, err := wav.NewWriter(w, wav.Format{
+			SampleRate:    sampleRate,
+			Channels:      1,
+			BitsPerSample: 16,
+		})
+		if err != nil {
+			return nil, err
+		}
+		pw.wav = ww
+	}
+	return pw, nil
+}
+
+func (w *pcmWriter) Write(frame []int16) error {
+	switch w.format {
+	case formatWAV:
+		return w.wav.WriteInt16(frame)
+	case formatF32LE:
+		buf := w.grow(4 * len(frame))
+		for i, s := range frame {
+			binary.LittleEndian.PutUint32(buf[4*i:], math.Float32bits(float32(s)/32768))
+		}
+	default:
+		buf := w.grow(2 * len(frame))
+		for i, s := range frame {
+			binary.LittleEndian.PutUint16(buf[2*i:], uint16(s))
+		}
+	}
+
+	if _, err := w.w.Write(w.buf); err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+	return nil
+}
+
+func (w *pcmWriter) grow(size int) []byte {
+	if cap(w.buf) < size {
+		w.buf = make([]byte, size)
+	}
+	w.buf = w.buf[:size]
+	return w.buf
+}
+
+// Close patches the WAV header if the output is seekable.
+func (w *pcmWriter) Close() error {
+	if w.wav != nil {
+		return w.wav.Close()
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/kechako/goradio/rtlfm"
+)
+
+const pipelineDepth = 8
+
+// errPipelineDone is returned by a frameSink to stop the pipeline normally.
+var errPipelineDone = errors.New("pipeline done")
+
+type frameSink func(frame []int16) error
+
+// runPipeline reads frames from r in a goroutine and passes them to sink
+// through a bounded channel. When ctx is canceled, c is closed to unblock
+// the reader. It returns the first error of any stage.
+func runPipeline(ctx context.Context, r rtlfm.FrameReader[int16], c io.Closer, frameSize int, sink frameSink) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		errOnce  sync.Once
+		firstErr error
+	)
+	fail := func(err error) {
+		errOnce.Do(func() {
+			firstErr = err
+		})
+		cancel()
+	}
+
+	frames := make(chan []int16, pipelineDepth)
+	free := make(chan []int16, pipelineDepth+2)
+
+	var wg sync.WaitGroup
+	wg.Add(3)
+
+	// closer
+	go func() {
+		defer wg.Done()
+		<-ctx.Done()
+		c.Close()
+	}()
+
+	// producer
+	go func() {
+		defer wg.Done()
+		defer close(frames)
+
+		for {
+			var frame []int16
+			select {
+			case frame = <-free:
+			default:
+				frame = make([]int16, frameSize)
+			}
+
+			if err := r.Read(frame); err != nil {
+				if ctx.Err() == nil && !errors.Is(err, io.EOF) {
+					fail(err)
+				}
+				return
+			}
+
+			select {
+			case frames <- frame:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	// consumer
+	go func() {
+		defer wg.Done()
+		// stop the closer when all frames are consumed
+		defer cancel()
+
+		for frame := range frames {
+			if ctx.Err() != nil {
+				return
+			}
+
+			if err := sink(frame); err != nil {
+				if !errors.Is(err, errPipelineDone) {
+					fail(err)
+				}
+				return
+			}
+
+			select {
+			case free <- frame:
+			default:
+			}
+		}
+	}()
+
+	wg.Wait()
+
+	return firstErr
+}
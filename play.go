@@ -1,53 +1,121 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"io"
+	"os"
+	"time"
 
 	"github.com/kechako/goradio/audio"
+	"github.com/kechako/goradio/jitter"
+	"github.com/kechako/goradio/resample"
 	"github.com/kechako/goradio/rtlfm"
+	"github.com/kechako/goradio/source"
+	"github.com/kechako/goradio/volume"
 	cli "github.com/urfave/cli/v2"
 )
 
+// fadeOutTimeout is added to the fade time to wait for the fade out.
+const fadeOutTimeout = 500 * time.Millisecond
+
+func outputFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:     "device",
+			Aliases:  []string{"d"},
+			Usage:    "audio device name to play radio",
+			Required: false,
+		},
+		&cli.IntFlag{
+			Name:        "sample-rate",
+			Aliases:     []string{"r"},
+			Usage:       "audio sample rate",
+			DefaultText: "default sample rate of audio device",
+			Required:    false,
+		},
+		&cli.IntFlag{
+			Name:        "buffer-samples",
+			Aliases:     []string{"b"},
+			Usage:       "audio buffer samples",
+			DefaultText: "samples for 10ms",
+			Required:    false,
+		},
+		&cli.StringFlag{
+			Name:     "resample-quality",
+			Usage:    "quality of sample rate conversion to the audio device (low, medium, high)",
+			Value:    "medium",
+			Required: false,
+		},
+		&cli.DurationFlag{
+			Name:     "latency",
+			Usage:    "target latency of the jitter buffer for live sources",
+			Value:    100 * time.Millisecond,
+			Required: false,
+		},
+		&cli.StringFlag{
+			Name:     "volume",
+			Usage:    "output volume in dB (e.g. -6dB)",
+			Value:    "0dB",
+			Required: false,
+		},
+		&cli.DurationFlag{
+			Name:     "fade",
+			Usage:    "time to fade in and out when starting, stopping or switching stations (0 disables fading)",
+			Value:    200 * time.Millisecond,
+			Required: false,
+		},
+		filterFlag(),
+	}
+}
+
 func playRadioCommand(ctx *cli.Context) error {
-	sfreq := ctx.String("freq")
-	freq, err := rtlfm.ParseFrequency(sfreq)
-	if err != nil {
-		return ArgumentError("invalid frequency")
+	if ctx.String("icecast") != "" {
+		return pushRadio(ctx)
+	}
+	if ctx.String("output") != "" {
+		return pipeRadio(ctx)
 	}
-	deviceName := ctx.String("device")
-	sampleRate := ctx.Int("sample-rate")
 
-	var device *audio.Device
-	if deviceName == "" {
-		var err error
-		device, err = audio.GetDefaultOutputDevice()
-		if err != nil {
-			return err
-		}
-	} else {
-		var err error
-		device, err = audio.GetDevice(deviceName)
-		if err != nil {
-			return err
-		}
+	device, err := outputDevice(ctx)
+	if err != nil {
+		return err
 	}
+	sampleRate := ctx.Int("sample-rate")
 	if sampleRate == 0 {
 		sampleRate = device.DefaultSampleRate()
 	}
 
-	const channels = 1
-	bufferSamples := ctx.Int("buffer-samples")
-	if bufferSamples == 0 {
-		bufferSamples = sampleRate * 10 / 1000
+	quality, err := resampleQuality(ctx)
+	if err != nil {
+		return err
+	}
+	process, vol, err := outputProcess(ctx, sampleRate)
+	if err != nil {
+		return err
 	}
 
-	stream, err := audio.Open[int16](
-		audio.WithOutputDevice(device),
-		audio.WithOutputChannels(channels),
-		audio.WithSampleRate(sampleRate),
-		audio.WithBufferSamples(bufferSamples),
-	)
+	// playback and the source keep running until the audio fades out
+	fadeCtx, cancel := fadeOutContext(ctx.Context, vol, ctx.Duration("fade"))
+	defer cancel()
+	ctx.Context = fadeCtx
+
+	// the source runs at its native rate and is converted to the device rate
+	src, err := openSource(ctx, 0, true)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	go printEvents(src.Events())
+
+	src, err = source.Resample(src, sampleRate, resample.WithQuality(quality))
+	if err != nil {
+		return err
+	}
+
+	stream, bufferSamples, err := openOutputStream(ctx, device, sampleRate)
 	if err != nil {
 		return err
 	}
@@ -58,52 +126,181 @@ func playRadioCommand(ctx *cli.Context) error {
 	}
 	defer stream.Stop()
 
-	opts := []rtlfm.Option{
-		rtlfm.WithSampleRate(sampleRate),
+	var buf *jitter.Buffer
+	if liveSource(ctx) {
+		buf = newJitterBuffer(ctx, sampleRate)
+		defer printJitterStats(buf)
 	}
-	if ctx.Bool("edge") {
-		opts = append(opts, rtlfm.EnableLowerEdgeTuning())
+
+	return playFrames(ctx.Context, src, src, stream, bufferSamples, buf, process)
+}
+
+func printEvents(events <-chan rtlfm.Event) {
+	for ev := range events {
+		printEvent(ev)
 	}
-	if ctx.Bool("dc") {
-		opts = append(opts, rtlfm.EnableDCBlockingFilter())
+}
+
+func printEvent(ev rtlfm.Event) {
+	switch ev := ev.(type) {
+	case rtlfm.DeviceOpenedEvent:
+		fmt.Fprintf(os.Stderr, "using dongle %d: %s\n", ev.Index, ev.Name)
+	case rtlfm.TunerEvent:
+		fmt.Fprintf(os.Stderr, "tuner: %s\n", ev.Tuner)
+	case rtlfm.TunedEvent:
+		fmt.Fprintf(os.Stderr, "tuned to %s\n", ev.Frequency)
+	case rtlfm.SampleRateEvent:
+		fmt.Fprintf(os.Stderr, "sampling at %d S/s\n", ev.SampleRate)
+	case rtlfm.OutputRateEvent:
+		fmt.Fprintf(os.Stderr, "output at %d Hz\n", ev.OutputRate)
+	case rtlfm.RestartEvent:
+		fmt.Fprintf(os.Stderr, "rtl_fm stopped (%v), restarting (%d)\n", ev.Err, ev.Restarts)
 	}
-	if ctx.Bool("deemp") {
-		opts = append(opts, rtlfm.EnableDeEmphasisFilter())
+}
+
+func outputDevice(ctx *cli.Context) (*audio.Device, error) {
+	deviceName := ctx.String("device")
+	if deviceName == "" {
+		return audio.GetDefaultOutputDevice()
 	}
-	if ctx.Bool("direct") {
-		opts = append(opts, rtlfm.EnableDirectSampling())
+	return audio.GetDevice(deviceName)
+}
+
+func openOutputStream(ctx *cli.Context, device *audio.Device, sampleRate int) (stream *audio.Stream[int16], bufferSamples int, err error) {
+	const channels = 1
+	bufferSamples = ctx.Int("buffer-samples")
+	if bufferSamples == 0 {
+		bufferSamples = sampleRate * 10 / 1000
 	}
-	if ctx.Bool("offset") {
-		opts = append(opts, rtlfm.EnableOffsetTuning())
+
+	stream, err = audio.Open[int16](
+		audio.WithOutputDevice(device),
+		audio.WithOutputChannels(channels),
+		audio.WithSampleRate(sampleRate),
+		audio.WithBufferSamples(bufferSamples),
+	)
+	if err != nil {
+		return nil, 0, err
 	}
 
-	p, err := rtlfm.Play(ctx.Context, freq, opts...)
+	return stream, bufferSamples, nil
+}
+
+func volumeDB(ctx *cli.Context) (float64, error) {
+	db, err := volume.ParseDB(ctx.String("volume"))
 	if err != nil {
-		return fmt.Errorf("failed to play radio: %w", err)
+		return 0, ArgumentError("invalid volume")
 	}
-	defer p.Close()
+	return db, nil
+}
 
-	r := rtlfm.NewFrameReader(p)
+// outputVolume returns the volume control specified by --volume, fading in
+// by --fade.
+func outputVolume(ctx *cli.Context, sampleRate int) (*volume.Control, error) {
+	db, err := volumeDB(ctx)
+	if err != nil {
+		return nil, err
+	}
+	vol := volume.New(sampleRate, db)
+	vol.FadeIn(ctx.Duration("fade"))
+	return vol, nil
+}
 
-	frame := make([]int16, bufferSamples)
-loop:
-	for {
+// fadeOutContext returns a context canceled when ctx is done and vol has
+// faded out over d, so that the audio keeps playing while fading out.
+func fadeOutContext(ctx context.Context, vol *volume.Control, d time.Duration) (context.Context, context.CancelFunc) {
+	fadeCtx, cancel := context.WithCancel(context.Background())
+	go func() {
 		select {
 		case <-ctx.Done():
-			break loop
-		default:
+		case <-fadeCtx.Done():
+			return
+		}
+		defer cancel()
+		if d <= 0 {
+			return
 		}
 
-		if err := r.Read(frame); err != nil {
-			return err
+		// frames may stop being processed, e.g. when the source fails
+		timer := time.NewTimer(d + fadeOutTimeout)
+		defer timer.Stop()
+		select {
+		case <-vol.FadeOut(d):
+		case <-fadeCtx.Done():
+		case <-timer.C:
 		}
+	}()
+	return fadeCtx, cancel
+}
+
+func resampleQuality(ctx *cli.Context) (resample.Quality, error) {
+	quality, err := resample.ParseQuality(ctx.String("resample-quality"))
+	if err != nil {
+		return 0, ArgumentError("invalid resample quality")
+	}
+	return quality, nil
+}
+
+func newJitterBuffer(ctx *cli.Context, sampleRate int) *jitter.Buffer {
+	return jitter.New(sampleRate, jitter.WithTargetLatency(ctx.Duration("latency")))
+}
 
-		err = stream.Write(frame)
-		if errors.Is(err, audio.ErrOutputOverflowed) {
-			// ignore
-		} else if err != nil {
-			return err
+func printJitterStats(buf *jitter.Buffer) {
+	stats := buf.Stats()
+	fmt.Fprintf(os.Stderr, "jitter buffer: %d prefills, %d underruns, %d overruns\n", stats.Prefills, stats.Underruns, stats.Overruns)
+}
+
+// playFrames plays frames read from r, processed by process. If buf is
+// not nil, frames are written to the stream through buf to absorb the clock
+// drift between a live source and the audio device.
+func playFrames(ctx context.Context, r rtlfm.FrameReader[int16], c io.Closer, stream *audio.Stream[int16], bufferSamples int, buf *jitter.Buffer, process func(frame []int16)) error {
+	if buf == nil {
+		return runPipeline(ctx, r, c, bufferSamples, func(frame []int16) error {
+			process(frame)
+			return writeStream(stream, frame)
+		})
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		// stop the pipeline if the stream fails
+		defer cancel()
+
+		frame := make([]int16, bufferSamples)
+		for ctx.Err() == nil {
+			if err := buf.Read(frame); err != nil {
+				if errors.Is(err, io.EOF) {
+					err = nil
+				}
+				done <- err
+				return
+			}
+			process(frame)
+			if err := writeStream(stream, frame); err != nil {
+				done <- err
+				return
+			}
 		}
+		done <- nil
+	}()
+
+	err := runPipeline(ctx, r, c, bufferSamples, buf.Write)
+	// drain the buffer
+	buf.Close()
+	if werr := <-done; err == nil {
+		err = werr
+	}
+	return err
+}
+
+func writeStream(stream *audio.Stream[int16], frame []int16) error {
+	err := stream.Write(frame)
+	if errors.Is(err, audio.ErrOutputOverflowed) {
+		// ignore
+		return nil
 	}
-	return nil
+	return err
 }
@@ -0,0 +1,110 @@
+package player
+
+import (
+	"github.com/kechako/goradio/rtlfm"
+)
+
+type Event interface {
+	event()
+}
+
+type StartedEvent struct {
+	Tuning Tuning
+}
+
+// StoppedEvent is sent when the player stops. Err is the reason if the
+// source ended by itself.
+type StoppedEvent struct {
+	Err error
+}
+
+type TunedEvent struct {
+	Tuning Tuning
+}
+
+type MutedEvent struct {
+	Muted bool
+}
+
+// VolumeEvent is sent when the volume is set. Volume is in dB.
+type VolumeEvent struct {
+	Volume float64
+}
+
+// SquelchEvent is sent when the audio of the source starts or stops.
+type SquelchEvent struct {
+	Open bool
+}
+
+// UnderrunEvent is sent when the jitter buffer runs out of audio.
+type UnderrunEvent struct {
+	Underruns int
+}
+
+// SourceEvent wraps an event of the source, such as rtlfm.RestartEvent.
+type SourceEvent struct {
+	Event rtlfm.Event
+}
+
+func (StartedEvent) event()  {}
+func (StoppedEvent) event()  {}
+func (TunedEvent) event()    {}
+func (MutedEvent) event()    {}
+func (VolumeEvent) event()   {}
+func (SquelchEvent) event()  {}
+func (UnderrunEvent) event() {}
+func (SourceEvent) event()   {}
+
+// Subscription receives events of a player.
+type Subscription struct {
+	p      *Player
+	events chan Event
+}
+
+// Subscribe returns a new subscription. Events are dropped if the channel
+// is not drained.
+func (p *Player) Subscribe() *Subscription {
+	sub := &Subscription{
+		p:      p,
+		events: make(chan Event, 64),
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		close(sub.events)
+		return sub
+	}
+	p.subs[sub] = struct{}{}
+	return sub
+}
+
+// Events returns the channel of events. It is closed when the subscription
+// or the player is closed.
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+func (s *Subscription) Close() error {
+	s.p.mu.Lock()
+	defer s.p.mu.Unlock()
+
+	if _, ok := s.p.subs[s]; ok {
+		close(s.events)
+		delete(s.p.subs, s)
+	}
+	return nil
+}
+
+func (p *Player) sendEvent(ev Event) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for sub := range p.subs {
+		select {
+		case sub.events <- ev:
+		default:
+		}
+	}
+}
@@ -0,0 +1,679 @@
+package player
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/kechako/goradio/audio"
+	"github.com/kechako/goradio/filter"
+	"github.com/kechako/goradio/jitter"
+	"github.com/kechako/goradio/resample"
+	"github.com/kechako/goradio/rtlfm"
+	"github.com/kechako/goradio/source"
+	"github.com/kechako/goradio/station"
+	"github.com/kechako/goradio/volume"
+)
+
+const (
+	defaultLatency = 100 * time.Millisecond
+	defaultFade    = 200 * time.Millisecond
+	// fading out gives up after the fade time plus this, if the stream
+	// stalls
+	fadeTimeout = 500 * time.Millisecond
+
+	// squelch is considered closed after silence for this duration
+	squelchHang = 200 * time.Millisecond
+)
+
+var (
+	ErrClosed = errors.New("player is closed")
+	// ErrNotTuned is returned by Start if no frequency is tuned.
+	ErrNotTuned = errors.New("frequency is not tuned")
+)
+
+// Tuning is the radio settings used to open a source.
+type Tuning struct {
+	Frequency  rtlfm.Frequency
+	Modulation rtlfm.Modulation
+	// Gain is the tuner gain in dB, nil means auto gain.
+	Gain    *float64
+	Station string
+	// Preset is the station preset tuned to, nil if tuned to a frequency.
+	// Its settings override the flags of the command. Modulation and Gain
+	// take precedence over it.
+	Preset *station.Station
+	// Filters are applied to the audio, nil means the default filters of
+	// the player.
+	Filters []filter.Spec
+}
+
+// RTLFMOptions returns the rtl_fm options of the tuning.
+func (t Tuning) RTLFMOptions() []rtlfm.Option {
+	var opts []rtlfm.Option
+	if t.Preset != nil {
+		opts = t.Preset.Options()
+	}
+	if t.Modulation != 0 {
+		opts = append(opts, rtlfm.WithModulation(t.Modulation))
+	}
+	if t.Gain != nil {
+		opts = append(opts, rtlfm.WithGain(*t.Gain))
+	} else {
+		opts = append(opts, rtlfm.WithAutoGain())
+	}
+	return opts
+}
+
+// WithStation returns the tuning tuned to the station preset st. The
+// settings of st override those of t.
+func (t Tuning) WithStation(st *station.Station) Tuning {
+	t.Frequency = st.Frequency
+	t.Station = st.Name
+	t.Preset = st
+	t.Filters = st.Filters
+	if st.Mode != 0 {
+		t.Modulation = st.Mode
+	}
+	if st.Gain != nil {
+		gain := *st.Gain
+		t.Gain = &gain
+	}
+	return t
+}
+
+// OpenFunc opens a source tuned to t. The source may use any sample rate,
+// it is converted to the rate of the stream.
+type OpenFunc func(t Tuning) (source.Source, error)
+
+type State string
+
+const (
+	Stopped State = "stopped"
+	Playing State = "playing"
+)
+
+type Status struct {
+	State  State
+	Tuning Tuning
+	Muted  bool
+	// Volume is the output volume in dB.
+	Volume float64
+	// SquelchOpen reports whether the source has sound.
+	SquelchOpen bool
+	// Restarts is the number of rtl_fm restarts since started.
+	Restarts int
+	// Level is the peak level of the latest frame from 0 to 1, before
+	// the volume is applied.
+	Level float64
+	// Buffered is the audio in the jitter buffer.
+	Buffered time.Duration
+	Stats    jitter.Stats
+	// Err is the error that stopped the player, if any.
+	Err error
+}
+
+// Player plays a radio source on an audio stream. Unlike a one-shot
+// pipeline, the source can be retuned, stopped and started again while the
+// stream stays open.
+type Player struct {
+	stream     *audio.Stream[int16]
+	sampleRate int
+	frameSize  int
+	open       OpenFunc
+	latency    time.Duration
+	quality    resample.Quality
+	vol        *volume.Control
+	fade       time.Duration
+	filters    []filter.Spec
+
+	// serializes Start, Stop and Tune
+	opMu sync.Mutex
+
+	mu       sync.Mutex
+	tuning   Tuning
+	session  *session
+	restarts int
+	err      error
+	closed   bool
+	subs     map[*Subscription]struct{}
+	tap      func(frame []int16)
+}
+
+// New returns a player writing to stream, which is opened with sampleRate
+// and frameSize samples per buffer. The player starts and stops stream.
+func New(stream *audio.Stream[int16], sampleRate, frameSize int, open OpenFunc, opts ...Option) *Player {
+	options := playerOptions{
+		latency: defaultLatency,
+		quality: resample.Medium,
+		fade:    defaultFade,
+	}
+	for _, opt := range opts {
+		opt.apply(&options)
+	}
+
+	return &Player{
+		stream:     stream,
+		sampleRate: sampleRate,
+		frameSize:  frameSize,
+		open:       open,
+		latency:    options.latency,
+		quality:    options.quality,
+		vol:        volume.New(sampleRate, options.volume),
+		fade:       options.fade,
+		filters:    options.filters,
+		subs:       make(map[*Subscription]struct{}),
+	}
+}
+
+// Start starts playing the current tuning.
+func (p *Player) Start() error {
+	p.opMu.Lock()
+	defer p.opMu.Unlock()
+
+	p.mu.Lock()
+	closed, playing, t := p.closed, p.session != nil, p.tuning
+	p.mu.Unlock()
+	if closed {
+		return ErrClosed
+	}
+	if t.Frequency == 0 {
+		return ErrNotTuned
+	}
+	if playing {
+		return nil
+	}
+
+	if err := p.stream.Start(); err != nil {
+		return fmt.Errorf("failed to start stream: %w", err)
+	}
+	p.vol.FadeIn(p.fade)
+	if err := p.startSession(t); err != nil {
+		p.stream.Stop()
+		return err
+	}
+	p.sendEvent(StartedEvent{Tuning: t})
+	return nil
+}
+
+// Stop stops playing and releases the source.
+func (p *Player) Stop() error {
+	p.opMu.Lock()
+	defer p.opMu.Unlock()
+
+	return p.stop()
+}
+
+func (p *Player) stop() error {
+	p.mu.Lock()
+	s := p.session
+	p.session = nil
+	p.mu.Unlock()
+	if s == nil {
+		return nil
+	}
+
+	p.fadeOut(s)
+	s.stop()
+	err := p.stream.Stop()
+	p.sendEvent(StoppedEvent{})
+	if err != nil {
+		return fmt.Errorf("failed to stop stream: %w", err)
+	}
+	return nil
+}
+
+// Tune changes the tuning. If the player is playing, the source is
+// reopened with t. The frequency of t may be zero to set the other
+// settings before tuning to a frequency.
+func (p *Player) Tune(t Tuning) error {
+	p.opMu.Lock()
+	defer p.opMu.Unlock()
+
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return ErrClosed
+	}
+	if t.Frequency == 0 && p.session != nil {
+		p.mu.Unlock()
+		return ErrNotTuned
+	}
+	old := p.session
+	p.session = nil
+	p.mu.Unlock()
+
+	if old != nil {
+		// the stream keeps running while switching
+		p.fadeOut(old)
+		old.stop()
+		p.vol.FadeIn(p.fade)
+		if err := p.startSession(t); err != nil {
+			p.stream.Stop()
+			p.sendEvent(StoppedEvent{Err: err})
+			return err
+		}
+	}
+
+	p.mu.Lock()
+	p.tuning = t
+	p.mu.Unlock()
+
+	p.sendEvent(TunedEvent{Tuning: t})
+	return nil
+}
+
+// Tuning returns the current tuning.
+func (p *Player) Tuning() Tuning {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.tuning
+}
+
+func (p *Player) SetMuted(muted bool) {
+	p.mu.Lock()
+	changed := p.vol.Muted() != muted
+	p.vol.SetMuted(muted)
+	p.mu.Unlock()
+
+	if changed {
+		p.sendEvent(MutedEvent{Muted: muted})
+	}
+}
+
+func (p *Player) Muted() bool {
+	return p.vol.Muted()
+}
+
+// SetVolume sets the output volume in dB. It is clamped to the range of
+// the volume package.
+func (p *Player) SetVolume(db float64) {
+	p.mu.Lock()
+	p.vol.SetVolume(db)
+	db = p.vol.Volume()
+	p.mu.Unlock()
+
+	p.sendEvent(VolumeEvent{Volume: db})
+}
+
+// Volume returns the output volume in dB.
+func (p *Player) Volume() float64 {
+	return p.vol.Volume()
+}
+
+// SetTap sets a function called with every frame played, before the volume,
+// e.g. to record the audio. It is called on the playback goroutine, so it
+// must not block nor retain frame. nil removes the tap.
+func (p *Player) SetTap(tap func(frame []int16)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.tap = tap
+}
+
+func (p *Player) Status() Status {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	st := Status{
+		State:    Stopped,
+		Tuning:   p.tuning,
+		Muted:    p.vol.Muted(),
+		Volume:   p.vol.Volume(),
+		Restarts: p.restarts,
+		Err:      p.err,
+	}
+	if s := p.session; s != nil {
+		st.State = Playing
+		st.SquelchOpen = s.squelchOpen()
+		st.Level = math.Float64frombits(atomic.LoadUint64(&s.level))
+		st.Buffered = time.Duration(s.buf.Len()) * time.Second / time.Duration(p.sampleRate)
+		st.Stats = s.buf.Stats()
+	}
+	return st
+}
+
+// Close stops playing and closes all subscriptions. The stream is not
+// closed.
+func (p *Player) Close() error {
+	p.opMu.Lock()
+	defer p.opMu.Unlock()
+
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil
+	}
+	p.closed = true
+	p.mu.Unlock()
+
+	err := p.stop()
+
+	p.mu.Lock()
+	for sub := range p.subs {
+		close(sub.events)
+		delete(p.subs, sub)
+	}
+	p.mu.Unlock()
+
+	return err
+}
+
+// startSession opens the source of t and starts playing it. It is called
+// with opMu held.
+func (p *Player) startSession(t Tuning) error {
+	specs := t.Filters
+	if specs == nil {
+		specs = p.filters
+	}
+	chain, err := filter.Build[int16](p.sampleRate, specs...)
+	if err != nil {
+		p.setErr(err)
+		return err
+	}
+
+	src, err := p.open(t)
+	if err != nil {
+		p.setErr(err)
+		return err
+	}
+	events := src.Events()
+	rs, err := source.Resample(src, p.sampleRate, resample.WithQuality(p.quality))
+	if err != nil {
+		src.Close()
+		p.setErr(err)
+		return err
+	}
+	src = rs
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	s := &session{
+		cancel: cancel,
+		done:   make(chan struct{}),
+		buf:    jitter.New(p.sampleRate, jitter.WithTargetLatency(p.latency)),
+		chain:  chain,
+	}
+	atomic.StoreInt64(&s.lastSound, time.Now().UnixNano())
+
+	p.mu.Lock()
+	p.session = s
+	p.err = nil
+	p.restarts = 0
+	p.mu.Unlock()
+
+	go p.forwardEvents(events)
+	go func() {
+		err := p.run(ctx, s, src)
+		close(s.done)
+
+		p.opMu.Lock()
+		defer p.opMu.Unlock()
+
+		p.mu.Lock()
+		current := p.session == s
+		if current {
+			// the source ended by itself
+			p.session = nil
+			p.err = err
+		}
+		p.mu.Unlock()
+
+		if current {
+			p.stream.Stop()
+			p.sendEvent(StoppedEvent{Err: err})
+		}
+	}()
+
+	return nil
+}
+
+// run reads src into the jitter buffer and writes the buffer to the
+// stream until ctx is canceled or src ends.
+func (p *Player) run(ctx context.Context, s *session, src source.Source) error {
+	var wg sync.WaitGroup
+	wg.Add(2)
+
+	readErr := make(chan error, 1)
+	go func() {
+		defer wg.Done()
+		defer s.buf.Close()
+		readErr <- p.read(ctx, s, src)
+	}()
+
+	// closer
+	go func() {
+		defer wg.Done()
+		<-ctx.Done()
+		src.Close()
+	}()
+
+	err := p.write(ctx, s)
+
+	s.cancel()
+	wg.Wait()
+
+	if rerr := <-readErr; rerr != nil {
+		return rerr
+	}
+	return err
+}
+
+func (p *Player) read(ctx context.Context, s *session, src source.Source) error {
+	frame := make([]int16, p.frameSize)
+	// throttle sources faster than real time, e.g. files
+	limit := 2 * int(p.latency*time.Duration(p.sampleRate)/time.Second)
+	frameDuration := time.Duration(p.frameSize) * time.Second / time.Duration(p.sampleRate)
+
+	for {
+		if err := src.Read(frame); err != nil {
+			if ctx.Err() != nil || errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		if !silent(frame) {
+			atomic.StoreInt64(&s.lastSound, time.Now().UnixNano())
+		}
+		if err := s.buf.Write(frame); err != nil {
+			return nil
+		}
+
+		for s.buf.Len() > limit {
+			select {
+			case <-time.After(frameDuration):
+			case <-ctx.Done():
+				return nil
+			}
+		}
+	}
+}
+
+func (p *Player) write(ctx context.Context, s *session) error {
+	frame := make([]int16, p.frameSize)
+	var stats jitter.Stats
+	squelch := true
+
+	for ctx.Err() == nil {
+		if err := s.buf.Read(frame); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		s.chain.Process(frame)
+		atomic.StoreUint64(&s.level, math.Float64bits(peak(frame)))
+
+		p.mu.Lock()
+		tap := p.tap
+		p.mu.Unlock()
+		if tap != nil {
+			tap(frame)
+		}
+		p.vol.Process(frame)
+
+		err := p.stream.Write(frame)
+		if err != nil && !errors.Is(err, audio.ErrOutputOverflowed) {
+			return err
+		}
+
+		if open := s.squelchOpen(); open != squelch {
+			squelch = open
+			p.sendEvent(SquelchEvent{Open: open})
+		}
+		// the buffer underruns while squelch mutes rtl_fm
+		st := s.buf.Stats()
+		if st.Underruns > stats.Underruns && squelch {
+			p.sendEvent(UnderrunEvent{Underruns: st.Underruns})
+		}
+		stats = st
+	}
+
+	return nil
+}
+
+// fadeOut fades out the audio of s and waits for it, unless s ends.
+func (p *Player) fadeOut(s *session) {
+	if p.fade <= 0 {
+		return
+	}
+	done := p.vol.FadeOut(p.fade)
+
+	timer := time.NewTimer(p.fade + fadeTimeout)
+	defer timer.Stop()
+	select {
+	case <-done:
+	case <-s.done:
+	case <-timer.C:
+	}
+}
+
+func (p *Player) forwardEvents(events <-chan rtlfm.Event) {
+	for ev := range events {
+		if ev, ok := ev.(rtlfm.RestartEvent); ok {
+			p.mu.Lock()
+			p.restarts = ev.Restarts
+			p.mu.Unlock()
+		}
+		p.sendEvent(SourceEvent{Event: ev})
+	}
+}
+
+func (p *Player) setErr(err error) {
+	p.mu.Lock()
+	p.err = err
+	p.mu.Unlock()
+}
+
+type session struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+	buf    *jitter.Buffer
+	chain  *filter.Chain[int16]
+
+	// unix time in nanoseconds of the last frame with sound
+	lastSound int64
+	// bits of the peak level of the latest frame
+	level uint64
+}
+
+func (s *session) stop() {
+	s.cancel()
+	<-s.done
+}
+
+func (s *session) squelchOpen() bool {
+	return time.Since(time.Unix(0, atomic.LoadInt64(&s.lastSound))) < squelchHang
+}
+
+func peak(frame []int16) float64 {
+	var max int32
+	for _, s := range frame {
+		v := int32(s)
+		if v < 0 {
+			v = -v
+		}
+		if v > max {
+			max = v
+		}
+	}
+	if max > 32767 {
+		max = 32767
+	}
+	return float64(max) / 32767
+}
+
+func silent(frame []int16) bool {
+	for _, s := range frame {
+		if s != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+type playerOptions struct {
+	latency time.Duration
+	quality resample.Quality
+	volume  float64
+	fade    time.Duration
+	filters []filter.Spec
+}
+
+type Option interface {
+	apply(opts *playerOptions)
+}
+
+type optionFunc func(opts *playerOptions)
+
+func (f optionFunc) apply(opts *playerOptions) {
+	f(opts)
+}
+
+// WithLatency sets the target latency of the jitter buffer.
+func WithLatency(latency time.Duration) Option {
+	return optionFunc(func(opts *playerOptions) {
+		if latency > 0 {
+			opts.latency = latency
+		}
+	})
+}
+
+// WithResampleQuality sets the quality of the conversion to the sample rate
+// of the stream.
+func WithResampleQuality(quality resample.Quality) Option {
+	return optionFunc(func(opts *playerOptions) {
+		opts.quality = quality
+	})
+}
+
+// WithVolume sets the initial output volume in dB.
+func WithVolume(db float64) Option {
+	return optionFunc(func(opts *playerOptions) {
+		opts.volume = db
+	})
+}
+
+// WithFade sets the time to fade in and out when starting, stopping and
+// retuning. Zero disables fading.
+func WithFade(fade time.Duration) Option {
+	return optionFunc(func(opts *playerOptions) {
+		if fade >= 0 {
+			opts.fade = fade
+		}
+	})
+}
+
+// WithFilters sets the default filters applied to the audio. Tunings may
+// override them.
+func WithFilters(specs ...filter.Spec) Option {
+	return optionFunc(func(opts *playerOptions) {
+		opts.filters = specs
+	})
+}
@@ -0,0 +1,368 @@
+package player
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kechako/goradio/audio"
+	"github.com/kechako/goradio/rtlfm"
+	"github.com/kechako/goradio/source"
+	"github.com/kechako/goradio/station"
+)
+
+const (
+	testSampleRate = 8000
+	testFrameSize  = 80
+)
+
+// testSource records whether it is closed.
+type testSource struct {
+	source.Source
+	closed int32
+}
+
+func (s *testSource) Close() error {
+	atomic.StoreInt32(&s.closed, 1)
+	return s.Source.Close()
+}
+
+func (s *testSource) isClosed() bool {
+	return atomic.LoadInt32(&s.closed) != 0
+}
+
+// opener opens sources by newSource and records the tunings opened.
+type opener struct {
+	newSource func(t Tuning) (source.Source, error)
+
+	mu      sync.Mutex
+	tunings []Tuning
+	sources []*testSource
+}
+
+func (o *opener) open(t Tuning) (source.Source, error) {
+	src, err := o.newSource(t)
+	if err != nil {
+		return nil, err
+	}
+	ts := &testSource{Source: src}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.tunings = append(o.tunings, t)
+	o.sources = append(o.sources, ts)
+	return ts, nil
+}
+
+func (o *opener) opened() ([]Tuning, []*testSource) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return append([]Tuning(nil), o.tunings...), append([]*testSource(nil), o.sources...)
+}
+
+func toneOpener() *opener {
+	return &opener{
+		newSource: func(t Tuning) (source.Source, error) {
+			return source.NewTone(1000, 0.5, testSampleRate), nil
+		},
+	}
+}
+
+// newPlayer returns a player on a stream of the null audio backend.
+func newPlayer(t *testing.T, open OpenFunc) *Player {
+	t.Helper()
+
+	if err := audio.SetBackend(audio.Null()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		audio.Terminate()
+		audio.SetBackend(audio.PortAudio())
+	})
+
+	stream, err := audio.Open[int16](
+		audio.WithDefaultOutputDevice(),
+		audio.WithOutputChannels(1),
+		audio.WithSampleRate(testSampleRate),
+		audio.WithBufferSamples(testFrameSize),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := New(stream, testSampleRate, testFrameSize, open, WithFade(0), WithLatency(20*time.Millisecond))
+	t.Cleanup(func() {
+		p.Close()
+		stream.Close()
+	})
+	return p
+}
+
+// waitEvent waits for an event of type E and returns it.
+func waitEvent[E Event](t *testing.T, sub *Subscription) E {
+	t.Helper()
+
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case ev, ok := <-sub.Events():
+			if !ok {
+				var zero E
+				t.Fatalf("events closed waiting for %T", zero)
+			}
+			if ev, ok := ev.(E); ok {
+				return ev
+			}
+		case <-timeout:
+			var zero E
+			t.Fatalf("timed out waiting for %T", zero)
+		}
+	}
+}
+
+func TestPlayerStartStop(t *testing.T) {
+	o := toneOpener()
+	p := newPlayer(t, o.open)
+	sub := p.Subscribe()
+
+	if err := p.Start(); !errors.Is(err, ErrNotTuned) {
+		t.Errorf("Start() before tuned = %v, want %v", err, ErrNotTuned)
+	}
+
+	// tuning while stopped does not open a source
+	tuning := Tuning{Frequency: 80 * rtlfm.MegaHertz, Modulation: rtlfm.WBFM}
+	if err := p.Tune(tuning); err != nil {
+		t.Fatal(err)
+	}
+	if ev := waitEvent[TunedEvent](t, sub); !reflect.DeepEqual(ev.Tuning, tuning) {
+		t.Errorf("TunedEvent = %+v, want %+v", ev.Tuning, tuning)
+	}
+	if tunings, _ := o.opened(); len(tunings) != 0 {
+		t.Errorf("opened %d sources while stopped, want 0", len(tunings))
+	}
+
+	// frames of sound, the jitter buffer plays silence until it is filled
+	frames := make(chan []int16, 1)
+	p.SetTap(func(frame []int16) {
+		if silent(frame) {
+			return
+		}
+		select {
+		case frames <- append([]int16(nil), frame...):
+		default:
+		}
+	})
+
+	if err := p.Start(); err != nil {
+		t.Fatal(err)
+	}
+	waitEvent[StartedEvent](t, sub)
+	if st := p.Status(); st.State != Playing || st.Tuning.Frequency != tuning.Frequency {
+		t.Errorf("Status() = %s at %s, want %s at %s", st.State, st.Tuning.Frequency, Playing, tuning.Frequency)
+	}
+
+	// the tap receives the frames played
+	select {
+	case frame := <-frames:
+		if len(frame) != testFrameSize {
+			t.Errorf("tap received %d samples, want %d", len(frame), testFrameSize)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the tap")
+	}
+
+	if err := p.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if ev := waitEvent[StoppedEvent](t, sub); ev.Err != nil {
+		t.Errorf("StoppedEvent.Err = %v, want nil", ev.Err)
+	}
+	if st := p.Status(); st.State != Stopped {
+		t.Errorf("Status().State = %s after Stop(), want %s", st.State, Stopped)
+	}
+	if _, sources := o.opened(); len(sources) != 1 || !sources[0].isClosed() {
+		t.Error("source is not closed by Stop()")
+	}
+}
+
+func TestPlayerTune(t *testing.T) {
+	o := toneOpener()
+	p := newPlayer(t, o.open)
+	sub := p.Subscribe()
+
+	if err := p.Tune(Tuning{Frequency: 80 * rtlfm.MegaHertz}); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the source is reopened while playing
+	if err := p.Tune(Tuning{Frequency: 81300 * rtlfm.KiloHertz}); err != nil {
+		t.Fatal(err)
+	}
+	waitEvent[TunedEvent](t, sub)
+	tunings, sources := o.opened()
+	if len(tunings) != 2 || tunings[1].Frequency != 81300*rtlfm.KiloHertz {
+		t.Fatalf("opened %v, want 80.0M and 81.3M", tunings)
+	}
+	if !sources[0].isClosed() || sources[1].isClosed() {
+		t.Error("the source of the previous tuning is not closed")
+	}
+	if st := p.Status(); st.State != Playing {
+		t.Errorf("Status().State = %s after Tune(), want %s", st.State, Playing)
+	}
+
+	if err := p.Tune(Tuning{}); !errors.Is(err, ErrNotTuned) {
+		t.Errorf("Tune() without frequency while playing = %v, want %v", err, ErrNotTuned)
+	}
+}
+
+func TestPlayerSourceEnds(t *testing.T) {
+	errRead := errors.New("read error")
+	tests := []struct {
+		name string
+		data []byte
+		err  error
+	}{
+		{"eof", make([]byte, 2*testFrameSize), nil},
+		{"error", nil, errRead},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := &opener{
+				newSource: func(t Tuning) (source.Source, error) {
+					if tt.err != nil {
+						return &errSource{Source: source.NewTone(1000, 0.5, testSampleRate), err: tt.err}, nil
+					}
+					return source.NewPCM(bytes.NewReader(tt.data), testSampleRate), nil
+				},
+			}
+			p := newPlayer(t, o.open)
+			sub := p.Subscribe()
+
+			if err := p.Tune(Tuning{Frequency: 80 * rtlfm.MegaHertz}); err != nil {
+				t.Fatal(err)
+			}
+			if err := p.Start(); err != nil {
+				t.Fatal(err)
+			}
+			if ev := waitEvent[StoppedEvent](t, sub); !errors.Is(ev.Err, tt.err) {
+				t.Errorf("StoppedEvent.Err = %v, want %v", ev.Err, tt.err)
+			}
+			if st := p.Status(); st.State != Stopped || !errors.Is(st.Err, tt.err) {
+				t.Errorf("Status() = %s, %v, want %s, %v", st.State, st.Err, Stopped, tt.err)
+			}
+		})
+	}
+}
+
+// errSource fails on the first read.
+type errSource struct {
+	source.Source
+	err error
+}
+
+func (s *errSource) Read(frame []int16) error {
+	return s.err
+}
+
+func TestPlayerOpenError(t *testing.T) {
+	errOpen := errors.New("no dongle")
+	p := newPlayer(t, func(t Tuning) (source.Source, error) {
+		return nil, errOpen
+	})
+
+	if err := p.Tune(Tuning{Frequency: 80 * rtlfm.MegaHertz}); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Start(); !errors.Is(err, errOpen) {
+		t.Errorf("Start() = %v, want %v", err, errOpen)
+	}
+	if st := p.Status(); st.State != Stopped || !errors.Is(st.Err, errOpen) {
+		t.Errorf("Status() = %s, %v, want %s, %v", st.State, st.Err, Stopped, errOpen)
+	}
+}
+
+func TestPlayerVolume(t *testing.T) {
+	p := newPlayer(t, toneOpener().open)
+	sub := p.Subscribe()
+
+	p.SetMuted(true)
+	if ev := waitEvent[MutedEvent](t, sub); !ev.Muted || !p.Muted() {
+		t.Errorf("MutedEvent.Muted = %v, Muted() = %v, want true", ev.Muted, p.Muted())
+	}
+	// unchanged
+	p.SetMuted(true)
+	p.SetVolume(-6)
+	if ev := waitEvent[VolumeEvent](t, sub); ev.Volume != -6 || p.Volume() != -6 {
+		t.Errorf("VolumeEvent.Volume = %g, Volume() = %g, want -6", ev.Volume, p.Volume())
+	}
+	if st := p.Status(); !st.Muted || st.Volume != -6 {
+		t.Errorf("Status() muted %v at %gdB, want muted at -6dB", st.Muted, st.Volume)
+	}
+}
+
+func TestPlayerClose(t *testing.T) {
+	o := toneOpener()
+	p := newPlayer(t, o.open)
+	sub := p.Subscribe()
+
+	if err := p.Tune(Tuning{Frequency: 80 * rtlfm.MegaHertz}); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Start(); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	for range sub.Events() {
+	}
+	if _, sources := o.opened(); !sources[0].isClosed() {
+		t.Error("source is not closed by Close()")
+	}
+	if err := p.Start(); !errors.Is(err, ErrClosed) {
+		t.Errorf("Start() after Close() = %v, want %v", err, ErrClosed)
+	}
+	if err := p.Tune(Tuning{Frequency: 80 * rtlfm.MegaHertz}); !errors.Is(err, ErrClosed) {
+		t.Errorf("Tune() after Close() = %v, want %v", err, ErrClosed)
+	}
+	if _, ok := <-p.Subscribe().Events(); ok {
+		t.Error("subscription after Close() is not closed")
+	}
+}
+
+func TestTuningWithStation(t *testing.T) {
+	gain := 28.0
+	st := &station.Station{
+		Name:      "NHK-FM",
+		Frequency: 82500 * rtlfm.KiloHertz,
+		Mode:      rtlfm.FM,
+		Gain:      &gain,
+	}
+	base := Tuning{Frequency: 80 * rtlfm.MegaHertz, Modulation: rtlfm.WBFM}
+
+	got := base.WithStation(st)
+	if got.Frequency != st.Frequency || got.Station != st.Name || got.Preset != st || got.Modulation != rtlfm.FM {
+		t.Errorf("WithStation() = %+v, want the settings of %s", got, st.Name)
+	}
+	if got.Gain == nil || *got.Gain != gain {
+		t.Errorf("WithStation() gain = %v, want %g", got.Gain, gain)
+	}
+	// the gain of the preset is copied
+	gain = 0
+	if *got.Gain != 28 {
+		t.Errorf("WithStation() gain = %g after the station changed, want 28", *got.Gain)
+	}
+
+	// the station without mode keeps the modulation of the tuning
+	got = base.WithStation(&station.Station{Name: "J-WAVE", Frequency: 81300 * rtlfm.KiloHertz})
+	if got.Modulation != rtlfm.WBFM || got.Gain != nil {
+		t.Errorf("WithStation() = %+v, want wbfm and auto gain", got)
+	}
+}
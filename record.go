@@ -1,11 +1,81 @@
 package main
 
 import (
-	"errors"
+	"fmt"
+	"os"
 
+	"github.com/kechako/goradio/wav"
 	cli "github.com/urfave/cli/v2"
 )
 
-func recordRadioCommand(ctx *cli.Context) error {
-	return errors.New("not implemented")
+func recordRadioCommand(ctx *cli.Context) (err error) {
+	output := ctx.String("output")
+	if output == "" {
+		return ArgumentError("output file is not specified")
+	}
+	duration := ctx.Duration("duration")
+	if duration < 0 {
+		return ArgumentError("invalid duration")
+	}
+	sampleRate := ctx.Int("sample-rate")
+	if sampleRate < 0 {
+		return ArgumentError("invalid sample rate")
+	}
+
+	const channels = 1
+
+	src, err := openSource(ctx, sampleRate, false)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	go printEvents(src.Events())
+
+	sampleRate = src.SampleRate()
+
+	file, err := os.Create(output)
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %w", err)
+	}
+	defer func() {
+		if cerr := file.Close(); cerr != nil && err == nil {
+			err = fmt.Errorf("failed to close output file: %w", cerr)
+		}
+	}()
+
+	w, err := wav.NewWriter(file, wav.Format{
+		SampleRate:    sampleRate,
+		Channels:      channels,
+		BitsPerSample: 16,
+	})
+	if err != nil {
+		return err
+	}
+	// patch the WAV header even if recording is interrupted
+	defer func() {
+		if cerr := w.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}()
+
+	// 0 means recording until interrupted
+	remaining := int64(duration.Seconds() * float64(sampleRate) * channels)
+
+	frameSize := sampleRate * channels * 10 / 1000
+
+	return runPipeline(ctx.Context, src, src, frameSize, func(frame []int16) error {
+		if duration > 0 && int64(len(frame)) > remaining {
+			frame = frame[:remaining]
+		}
+		if err := w.WriteInt16(frame); err != nil {
+			return err
+		}
+		remaining -= int64(len(frame))
+
+		if duration > 0 && remaining <= 0 {
+			return errPipelineDone
+		}
+		return nil
+	})
 }
@@ -0,0 +1,353 @@
+package resample
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/kechako/goradio/audio"
+)
+
+// maxExactPhases is the maximum number of filter phases for a reduced rate
+// ratio. Ratios with more phases interpolate between the filter phases.
+const maxExactPhases = 1024
+
+var ErrInvalidRate = errors.New("invalid sample rate")
+
+type Quality int
+
+const (
+	Low Quality = iota + 1
+	Medium
+	High
+)
+
+type qualityParams struct {
+	// filter taps for each output sample when upsampling
+	taps int
+	// passband edge relative to the Nyquist frequency
+	rolloff float64
+	// Kaiser window beta
+	beta float64
+	// filter phases when the rate ratio is not exact
+	phases int
+}
+
+var qualities = map[Quality]qualityParams{
+	Low:    {taps: 8, rolloff: 0.80, beta: 5, phases: 64},
+	Medium: {taps: 32, rolloff: 0.90, beta: 8, phases: 256},
+	High:   {taps: 64, rolloff: 0.95, beta: 10, phases: 512},
+}
+
+func ParseQuality(s string) (Quality, error) {
+	switch strings.ToLower(s) {
+	case "low":
+		return Low, nil
+	case "medium":
+		return Medium, nil
+	case "high":
+		return High, nil
+	default:
+		return 0, fmt.Errorf("invalid resample quality: %s", s)
+	}
+}
+
+func (q Quality) String() string {
+	switch q {
+	case Low:
+		return "low"
+	case Medium:
+		return "medium"
+	case High:
+		return "high"
+	default:
+		return fmt.Sprintf("Quality(%d)", int(q))
+	}
+}
+
+// Resampler converts the sample rate of a mono stream with a polyphase
+// windowed-sinc filter.
+type Resampler[T audio.SampleType] struct {
+	inRate  int
+	outRate int
+
+	// output step is m/l input samples
+	l, m int
+	t    int
+
+	taps   int
+	phases int
+	exact  bool
+	// (phases + 1) rows of taps
+	filter []float64
+
+	buf []float64
+	pos int
+	res []float64
+}
+
+func New[T audio.SampleType](inRate, outRate int, opts ...Option) (*Resampler[T], error) {
+	if inRate <= 0 || outRate <= 0 {
+		return nil, ErrInvalidRate
+	}
+
+	options := resamplerOptions{
+		quality: Medium,
+	}
+	for _, opt := range opts {
+		opt.apply(&options)
+	}
+	params, ok := qualities[options.quality]
+	if !ok {
+		return nil, fmt.Errorf("invalid resample quality: %v", options.quality)
+	}
+
+	g := gcd(inRate, outRate)
+	l, m := outRate/g, inRate/g
+
+	// cutoff in cycles per input sample
+	cutoff := 0.5 * params.rolloff
+	taps := params.taps
+	if m > l {
+		cutoff *= float64(l) / float64(m)
+		taps = int(math.Ceil(float64(taps) * float64(m) / float64(l)))
+	}
+	if taps%2 != 0 {
+		taps++
+	}
+
+	phases, exact := l, true
+	if phases > maxExactPhases {
+		phases, exact = params.phases, false
+	}
+
+	r := &Resampler[T]{
+		inRate:  inRate,
+		outRate: outRate,
+		l:       l,
+		m:       m,
+		taps:    taps,
+		phases:  phases,
+		exact:   exact,
+		filter:  design(taps, phases, cutoff, params.beta),
+	}
+	r.Reset()
+
+	return r, nil
+}
+
+func (r *Resampler[T]) InputRate() int  { return r.inRate }
+func (r *Resampler[T]) OutputRate() int { return r.outRate }
+
+// Delay returns the delay of the filter in input samples.
+func (r *Resampler[T]) Delay() int { return r.taps / 2 }
+
+// Reset clears the filter history.
+func (r *Resampler[T]) Reset() {
+	r.buf = make([]float64, r.taps-1, 2*r.taps)
+	r.pos = r.taps - 1
+	r.t = 0
+}
+
+// Process resamples in and appends the result to out.
+func (r *Resampler[T]) Process(in []T, out []T) []T {
+	r.load(in)
+
+	r.res = r.res[:0]
+	for r.pos < len(r.buf) {
+		r.res = append(r.res, r.convolve())
+
+		r.t += r.m
+		r.pos += r.t / r.l
+		r.t %= r.l
+	}
+
+	// keep the history for the next input
+	drop := len(r.buf) - (r.taps - 1)
+	r.buf = r.buf[:copy(r.buf, r.buf[drop:])]
+	r.pos -= drop
+
+	return r.store(out)
+}
+
+func (r *Resampler[T]) convolve() float64 {
+	x := r.buf[r.pos-r.taps+1 : r.pos+1]
+
+	if r.exact {
+		row := r.filter[r.t*r.taps : (r.t+1)*r.taps]
+		var acc float64
+		for k, h := range row {
+			acc += h * x[len(x)-1-k]
+		}
+		return acc
+	}
+
+	phase := float64(r.t) * float64(r.phases) / float64(r.l)
+	p := int(phase)
+	a := phase - float64(p)
+	row0 := r.filter[p*r.taps : (p+1)*r.taps]
+	row1 := r.filter[(p+1)*r.taps : (p+2)*r.taps]
+	var acc0, acc1 float64
+	for k := range row0 {
+		v := x[len(x)-1-k]
+		acc0 += row0[k] * v
+		acc1 += row1[k] * v
+	}
+	return acc0 + (acc1-acc0)*a
+}
+
+func (r *Resampler[T]) load(in []T) {
+	switch in := any(in).(type) {
+	case []float32:
+		for _, v := range in {
+			r.buf = append(r.buf, float64(v))
+		}
+	case []int32:
+		for _, v := range in {
+			r.buf = append(r.buf, float64(v))
+		}
+	case []audio.Int24:
+		for _, v := range in {
+			r.buf = append(r.buf, float64(v.Int32()>>8))
+		}
+	case []int16:
+		for _, v := range in {
+			r.buf = append(r.buf, float64(v))
+		}
+	case []int8:
+		for _, v := range in {
+			r.buf = append(r.buf, float64(v))
+		}
+	case []uint8:
+		for _, v := range in {
+			r.buf = append(r.buf, float64(v)-128)
+		}
+	}
+}
+
+func (r *Resampler[T]) store(out []T) []T {
+	switch o := any(out).(type) {
+	case []float32:
+		for _, v := range r.res {
+			o = append(o, float32(v))
+		}
+		return any(o).([]T)
+	case []int32:
+		for _, v := range r.res {
+			o = append(o, int32(clamp(v, math.MinInt32, math.MaxInt32)))
+		}
+		return any(o).([]T)
+	case []audio.Int24:
+		for _, v := range r.res {
+			var i24 audio.Int24
+			i24.PutInt32(int32(clamp(v, -1<<23, 1<<23-1)) << 8)
+			o = append(o, i24)
+		}
+		return any(o).([]T)
+	case []int16:
+		for _, v := range r.res {
+			o = append(o, int16(clamp(v, math.MinInt16, math.MaxInt16)))
+		}
+		return any(o).([]T)
+	case []int8:
+		for _, v := range r.res {
+			o = append(o, int8(clamp(v, math.MinInt8, math.MaxInt8)))
+		}
+		return any(o).([]T)
+	case []uint8:
+		for _, v := range r.res {
+			o = append(o, uint8(clamp(v, math.MinInt8, math.MaxInt8)+128))
+		}
+		return any(o).([]T)
+	}
+	return out
+}
+
+// design returns phases+1 rows of a Kaiser windowed-sinc filter. The row p
+// interpolates an input stream at the fractional position p/phases.
+func design(taps, phases int, cutoff, beta float64) []float64 {
+	filter := make([]float64, (phases+1)*taps)
+	center := float64(taps) / 2
+	i0beta := bessel0(beta)
+
+	for p := 0; p <= phases; p++ {
+		row := filter[p*taps : (p+1)*taps]
+		frac := float64(p) / float64(phases)
+
+		var sum float64
+		for k := range row {
+			// distance of the input sample k taps before the latest one from
+			// the output position, delayed by half the taps
+			x := float64(k) + frac - center
+			v := 2 * cutoff
+			if x != 0 {
+				v = math.Sin(2*math.Pi*cutoff*x) / (math.Pi * x)
+			}
+			w := x / center
+			if w < -1 || w > 1 {
+				v = 0
+			} else {
+				v *= bessel0(beta*math.Sqrt(1-w*w)) / i0beta
+			}
+			row[k] = v
+			sum += v
+		}
+		for k := range row {
+			row[k] /= sum
+		}
+	}
+
+	return filter
+}
+
+// bessel0 is the zeroth order modified Bessel function of the first kind.
+func bessel0(x float64) float64 {
+	sum, term := 1.0, 1.0
+	for k := 1; k < 50; k++ {
+		term *= (x / 2) / float64(k)
+		sum += term * term
+		if term*term < sum*1e-12 {
+			break
+		}
+	}
+	return sum
+}
+
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
+
+func clamp(v, min, max float64) float64 {
+	v = math.Round(v)
+	if v < min {
+		return min
+	}
+	if v > max {
+		return max
+	}
+	return v
+}
+
+type resamplerOptions struct {
+	quality Quality
+}
+
+type Option interface {
+	apply(opts *resamplerOptions)
+}
+
+type optionFunc func(opts *resamplerOptions)
+
+func (f optionFunc) apply(opts *resamplerOptions) {
+	f(opts)
+}
+
+func WithQuality(quality Quality) Option {
+	return optionFunc(func(opts *resamplerOptions) {
+		opts.quality = quality
+	})
+}
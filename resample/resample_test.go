@@ -0,0 +1,146 @@
+package resample
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/kechako/goradio/audio"
+)
+
+// sine returns n samples of a tone at freq with amplitude.
+func sine(n, rate int, freq, amplitude float64) []int16 {
+	s := make([]int16, n)
+	for i := range s {
+		s[i] = int16(amplitude * math.Sin(2*math.Pi*freq*float64(i)/float64(rate)))
+	}
+	return s
+}
+
+// toneAmplitude returns the amplitude of the tone at freq in s.
+func toneAmplitude(s []int16, rate int, freq float64) float64 {
+	var re, im float64
+	for i, v := range s {
+		w := 2 * math.Pi * freq * float64(i) / float64(rate)
+		re += float64(v) * math.Cos(w)
+		im += float64(v) * math.Sin(w)
+	}
+	return 2 * math.Hypot(re, im) / float64(len(s))
+}
+
+func TestResampler(t *testing.T) {
+	tests := []struct {
+		inRate, outRate int
+	}{
+		{48000, 44100},
+		{32000, 48000},
+		{44100, 48000},
+		{48000, 48000},
+		// not reduced to exact phases
+		{170000, 48000},
+	}
+	for _, tt := range tests {
+		for _, q := range []Quality{Low, Medium, High} {
+			name := fmt.Sprintf("%d-%d/%v", tt.inRate, tt.outRate, q)
+			t.Run(name, func(t *testing.T) {
+				r, err := New[int16](tt.inRate, tt.outRate, WithQuality(q))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				in := sine(tt.inRate, tt.inRate, 1000, 10000)
+				var out []int16
+				// odd chunks exercise the history between calls
+				for len(in) > 0 {
+					n := 997
+					if n > len(in) {
+						n = len(in)
+					}
+					out = r.Process(in[:n], out)
+					in = in[n:]
+				}
+
+				if d := len(out) - tt.outRate; d < -1 || d > 1 {
+					t.Errorf("output samples = %d, want %d", len(out), tt.outRate)
+				}
+				// skip the delay of the filter
+				skip := r.Delay()*tt.outRate/tt.inRate + 1
+				if a := toneAmplitude(out[skip:], tt.outRate, 1000); math.Abs(a-10000) > 200 {
+					t.Errorf("tone amplitude = %.0f, want 10000", a)
+				}
+			})
+		}
+	}
+}
+
+func TestResamplerSampleTypes(t *testing.T) {
+	in := []audio.Int24{}
+	for _, v := range sine(4800, 48000, 1000, 10000) {
+		var i24 audio.Int24
+		i24.PutInt32(int32(v) << 16)
+		in = append(in, i24)
+	}
+
+	r, err := New[audio.Int24](48000, 24000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := r.Process(in, nil)
+
+	s := make([]int16, len(out))
+	for i, v := range out {
+		s[i] = int16(v.Int32() >> 16)
+	}
+	skip := r.Delay()/2 + 1
+	if a := toneAmplitude(s[skip:], 24000, 1000); math.Abs(a-10000) > 200 {
+		t.Errorf("tone amplitude = %.0f, want 10000", a)
+	}
+}
+
+func TestNewInvalid(t *testing.T) {
+	if _, err := New[int16](0, 48000); err != ErrInvalidRate {
+		t.Errorf("New(0, 48000) error = %v, want %v", err, ErrInvalidRate)
+	}
+	if _, err := New[int16](48000, 44100, WithQuality(0)); err == nil {
+		t.Error("New with invalid quality succeeded")
+	}
+}
+
+func TestParseQuality(t *testing.T) {
+	for _, q := range []Quality{Low, Medium, High} {
+		got, err := ParseQuality(q.String())
+		if err != nil || got != q {
+			t.Errorf("ParseQuality(%q) = %v, %v", q.String(), got, err)
+		}
+	}
+	if _, err := ParseQuality("best"); err == nil {
+		t.Error("ParseQuality(\"best\") succeeded")
+	}
+}
+
+func BenchmarkResampler(b *testing.B) {
+	// 10ms frames
+	for _, rates := range []struct{ in, out int }{
+		{48000, 44100},
+		{32000, 48000},
+	} {
+		for _, q := range []Quality{Low, Medium, High} {
+			name := fmt.Sprintf("%d-%d/%v", rates.in, rates.out, q)
+			b.Run(name, func(b *testing.B) {
+				r, err := New[int16](rates.in, rates.out, WithQuality(q))
+				if err != nil {
+					b.Fatal(err)
+				}
+				in := sine(rates.in/100, rates.in, 1000, 10000)
+				out := make([]int16, 0, rates.out/100+1)
+
+				b.ReportAllocs()
+				b.SetBytes(int64(2 * len(in)))
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					out = r.Process(in, out[:0])
+				}
+			})
+		}
+	}
+}
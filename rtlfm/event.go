@@ -0,0 +1,125 @@
+package rtlfm
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type Event interface {
+	event()
+}
+
+type DeviceOpenedEvent struct {
+	Index int
+	Name  string
+}
+
+type TunerEvent struct {
+	Tuner string
+}
+
+type TunedEvent struct {
+	Frequency Frequency
+}
+
+type SampleRateEvent struct {
+	SampleRate int
+}
+
+type OutputRateEvent struct {
+	OutputRate int
+}
+
+type ErrorEvent struct {
+	Err error
+}
+
+func (DeviceOpenedEvent) event() {}
+func (TunerEvent) event()        {}
+func (TunedEvent) event()        {}
+func (SampleRateEvent) event()   {}
+func (OutputRateEvent) event()   {}
+func (ErrorEvent) event()        {}
+
+var (
+	ErrNoDevice   = errors.New("no supported devices found")
+	ErrDeviceBusy = errors.New("dongle busy (DVB driver loaded?)")
+	ErrDeviceOpen = errors.New("failed to open dongle")
+)
+
+var (
+	deviceOpenedPattern = regexp.MustCompile(`^Using device (\d+): (.*)$`)
+	tunerPattern        = regexp.MustCompile(`^Found (.+) tuner$`)
+	tunedPattern        = regexp.MustCompile(`^Tuned to (\d+) Hz\.$`)
+	sampleRatePattern   = regexp.MustCompile(`^Sampling at (\d+) S/s\.$`)
+	outputRatePattern   = regexp.MustCompile(`^Output at (\d+) Hz\.$`)
+)
+
+// parseEvent converts a line of rtl_fm stderr to an event. It returns nil
+// for lines that are not known.
+func parseEvent(line string) Event {
+	line = strings.TrimSpace(line)
+
+	if m := deviceOpenedPattern.FindStringSubmatch(line); m != nil {
+		index, _ := strconv.Atoi(m[1])
+		return DeviceOpenedEvent{Index: index, Name: m[2]}
+	}
+	if m := tunerPattern.FindStringSubmatch(line); m != nil {
+		return TunerEvent{Tuner: m[1]}
+	}
+	if m := tunedPattern.FindStringSubmatch(line); m != nil {
+		freq, _ := strconv.Atoi(m[1])
+		return TunedEvent{Frequency: Frequency(freq)}
+	}
+	if m := sampleRatePattern.FindStringSubmatch(line); m != nil {
+		rate, _ := strconv.Atoi(m[1])
+		return SampleRateEvent{SampleRate: rate}
+	}
+	if m := outputRatePattern.FindStringSubmatch(line); m != nil {
+		rate, _ := strconv.Atoi(m[1])
+		return OutputRateEvent{OutputRate: rate}
+	}
+
+	switch {
+	case strings.HasPrefix(line, "No supported devices found"):
+		return ErrorEvent{Err: ErrNoDevice}
+	case strings.HasPrefix(line, "usb_claim_interface error"),
+		strings.HasPrefix(line, "Kernel driver is active"):
+		return ErrorEvent{Err: ErrDeviceBusy}
+	case strings.HasPrefix(line, "Failed to open rtlsdr device"):
+		return ErrorEvent{Err: ErrDeviceOpen}
+	}
+
+	return nil
+}
+
+func (p *Process) readStderr(r io.Reader) {
+	defer close(p.stderrDone)
+	defer close(p.events)
+
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		ev := parseEvent(s.Text())
+		if ev == nil {
+			continue
+		}
+
+		if ev, ok := ev.(ErrorEvent); ok {
+			p.mu.Lock()
+			// keep the first error, it is usually the cause
+			if p.err == nil {
+				p.err = ev.Err
+			}
+			p.mu.Unlock()
+		}
+
+		select {
+		case p.events <- ev:
+		default:
+		}
+	}
+}
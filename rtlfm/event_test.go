@@ -0,0 +1,65 @@
+package rtlfm
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseEvent(t *testing.T) {
+	tests := []struct {
+		line string
+		want Event
+	}{
+		{"Using device 0: Generic RTL2832U OEM", DeviceOpenedEvent{Index: 0, Name: "Generic RTL2832U OEM"}},
+		{"Found Rafael Micro R820T tuner", TunerEvent{Tuner: "Rafael Micro R820T"}},
+		{"Tuned to 80328000 Hz.", TunedEvent{Frequency: 80328000}},
+		{"Sampling at 1200000 S/s.", SampleRateEvent{SampleRate: 1200000}},
+		{"Output at 48000 Hz.", OutputRateEvent{OutputRate: 48000}},
+		{"  Output at 24000 Hz.\r", OutputRateEvent{OutputRate: 24000}},
+		{"No supported devices found.", ErrorEvent{Err: ErrNoDevice}},
+		{"usb_claim_interface error -6", ErrorEvent{Err: ErrDeviceBusy}},
+		{"Kernel driver is active, or device is claimed by second instance of librtlsdr.", ErrorEvent{Err: ErrDeviceBusy}},
+		{"Failed to open rtlsdr device #0.", ErrorEvent{Err: ErrDeviceOpen}},
+		{"Found 1 device(s):", nil},
+		{"Oversampling input by: 3x.", nil},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		if got := parseEvent(tt.line); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseEvent(%q) = %#v, want %#v", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestReadStderr(t *testing.T) {
+	stderr := strings.Join([]string{
+		"Found 1 device(s):",
+		"Using device 0: Generic RTL2832U OEM",
+		"usb_claim_interface error -6",
+		"Failed to open rtlsdr device #0.",
+	}, "\n")
+
+	p := &Process{
+		events:     make(chan Event, 64),
+		stderrDone: make(chan struct{}),
+	}
+	p.readStderr(strings.NewReader(stderr))
+
+	var got []Event
+	for ev := range p.events {
+		got = append(got, ev)
+	}
+	want := []Event{
+		DeviceOpenedEvent{Index: 0, Name: "Generic RTL2832U OEM"},
+		ErrorEvent{Err: ErrDeviceBusy},
+		ErrorEvent{Err: ErrDeviceOpen},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("events = %#v, want %#v", got, want)
+	}
+	// the first error is the cause
+	if err := p.Err(); err != ErrDeviceBusy {
+		t.Errorf("Err() = %v, want %v", err, ErrDeviceBusy)
+	}
+}
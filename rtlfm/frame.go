@@ -4,37 +4,94 @@ import (
 	"encoding/binary"
 	"fmt"
 	"io"
+
+	"github.com/kechako/goradio/audio"
 )
 
-type FrameReader interface {
-	Read(frame []int16) error
+// FrameReader reads audio frames.
+type FrameReader[T audio.SampleType] interface {
+	Read(frame []T) error
 }
 
-type frameReader struct {
+type frameReader[T audio.SampleType] struct {
 	r   io.Reader
 	buf []byte
+	eof bool
 }
 
-func NewFrameReader(r io.Reader) FrameReader {
-	return &frameReader{
+// NewFrameReader returns a FrameReader that decodes signed 16-bit little
+// endian PCM read from r. Samples are scaled to the range of T, float32
+// samples are normalized to [-1, 1).
+func NewFrameReader[T audio.SampleType](r io.Reader) FrameReader[T] {
+	return &frameReader[T]{
 		r: r,
 	}
 }
 
-func (r *frameReader) Read(frame []int16) error {
+// Read fills frame with samples. If r ends in the middle of frame, the rest
+// of frame is filled with silence and the next Read returns io.EOF.
+func (r *frameReader[T]) Read(frame []T) error {
 	if len(frame) == 0 {
 		return nil
 	}
+	if r.eof {
+		return io.EOF
+	}
 
 	size := 2 * len(frame)
-	if r.buf == nil || len(r.buf) != size {
+	if cap(r.buf) < size {
 		r.buf = make([]byte, size)
 	}
+	buf := r.buf[:size]
 
-	err := binary.Read(r.r, binary.LittleEndian, frame)
-	if err != nil {
+	n, err := io.ReadFull(r.r, buf)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		if n < 2 {
+			return fmt.Errorf("failed to read audio frame: %w", io.EOF)
+		}
+		// pad the last frame with silence, an incomplete sample is dropped
+		r.eof = true
+		for i := n &^ 1; i < size; i++ {
+			buf[i] = 0
+		}
+	} else if err != nil {
 		return fmt.Errorf("failed to read audio frame: %w", err)
 	}
 
+	decode(buf, frame)
+
 	return nil
 }
+
+func decode[T audio.SampleType](buf []byte, frame []T) {
+	switch frame := any(frame).(type) {
+	case []float32:
+		for i := range frame {
+			frame[i] = float32(sample(buf, i)) / 32768
+		}
+	case []int32:
+		for i := range frame {
+			frame[i] = int32(sample(buf, i)) << 16
+		}
+	case []audio.Int24:
+		for i := range frame {
+			frame[i].PutInt32(int32(sample(buf, i)) << 16)
+		}
+	case []int16:
+		for i := range frame {
+			frame[i] = sample(buf, i)
+		}
+	case []int8:
+		for i := range frame {
+			frame[i] = int8(sample(buf, i) >> 8)
+		}
+	case []uint8:
+		for i := range frame {
+			frame[i] = uint8(sample(buf, i)>>8) + 128
+		}
+	}
+}
+
+func sample(buf []byte, i int) int16 {
+	return int16(binary.LittleEndian.Uint16(buf[2*i:]))
+}
@@ -0,0 +1,222 @@
+package rtlfm
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+	"testing/iotest"
+
+	"github.com/kechako/goradio/audio"
+)
+
+func pcm(samples ...int16) []byte {
+	b := make([]byte, 2*len(samples))
+	for i, s := range samples {
+		binary.LittleEndian.PutUint16(b[2*i:], uint16(s))
+	}
+	return b
+}
+
+func TestFrameReader(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   []byte
+		reader func(io.Reader) io.Reader
+		size   int
+		want   [][]int16
+	}{
+		{
+			name: "whole frames",
+			data: pcm(1, 2, 3, 4),
+			size: 2,
+			want: [][]int16{{1, 2}, {3, 4}},
+		},
+		{
+			name:   "short reads",
+			data:   pcm(1, -2, 3, -4, 5, -6),
+			reader: iotest.OneByteReader,
+			size:   3,
+			want:   [][]int16{{1, -2, 3}, {-4, 5, -6}},
+		},
+		{
+			name:   "half reads",
+			data:   pcm(100, 200, 300, 400),
+			reader: iotest.HalfReader,
+			size:   4,
+			want:   [][]int16{{100, 200, 300, 400}},
+		},
+		{
+			name: "partial trailing frame",
+			data: pcm(1, 2, 3, 4, 5),
+			size: 3,
+			want: [][]int16{{1, 2, 3}, {4, 5, 0}},
+		},
+		{
+			name: "incomplete trailing sample",
+			data: append(pcm(1, 2, 3), 0xff),
+			size: 2,
+			want: [][]int16{{1, 2}, {3, 0}},
+		},
+		{
+			name:   "partial trailing frame of short reads",
+			data:   pcm(7, 8, 9),
+			reader: iotest.OneByteReader,
+			size:   2,
+			want:   [][]int16{{7, 8}, {9, 0}},
+		},
+		{
+			name: "single byte",
+			data: []byte{0x01},
+			size: 2,
+			want: nil,
+		},
+		{
+			name: "empty",
+			size: 2,
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var r io.Reader = bytes.NewReader(tt.data)
+			if tt.reader != nil {
+				r = tt.reader(r)
+			}
+			fr := NewFrameReader[int16](r)
+
+			var got [][]int16
+			for {
+				frame := make([]int16, tt.size)
+				err := fr.Read(frame)
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, frame)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("frames = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFrameReaderError(t *testing.T) {
+	errRead := errors.New("read error")
+	fr := NewFrameReader[int16](iotest.ErrReader(errRead))
+	if err := fr.Read(make([]int16, 2)); !errors.Is(err, errRead) {
+		t.Errorf("Read() error = %v, want %v", err, errRead)
+	}
+}
+
+func TestFrameReaderSampleTypes(t *testing.T) {
+	data := pcm(-32768, -1, 0, 16384, 32767)
+
+	readFrame := func(t *testing.T, read func(r io.Reader) error) {
+		t.Helper()
+		if err := read(bytes.NewReader(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Run("float32", func(t *testing.T) {
+		frame := make([]float32, 5)
+		readFrame(t, func(r io.Reader) error { return NewFrameReader[float32](r).Read(frame) })
+		want := []float32{-1, -1.0 / 32768, 0, 0.5, 32767.0 / 32768}
+		if !reflect.DeepEqual(frame, want) {
+			t.Errorf("frame = %v, want %v", frame, want)
+		}
+	})
+	t.Run("int32", func(t *testing.T) {
+		frame := make([]int32, 5)
+		readFrame(t, func(r io.Reader) error { return NewFrameReader[int32](r).Read(frame) })
+		want := []int32{-1 << 31, -1 << 16, 0, 1 << 30, 32767 << 16}
+		if !reflect.DeepEqual(frame, want) {
+			t.Errorf("frame = %v, want %v", frame, want)
+		}
+	})
+	t.Run("int24", func(t *testing.T) {
+		frame := make([]audio.Int24, 5)
+		readFrame(t, func(r io.Reader) error { return NewFrameReader[audio.Int24](r).Read(frame) })
+		want := []int32{-1 << 31, -1 << 16, 0, 1 << 30, 32767 << 16}
+		for i, v := range frame {
+			if v.Int32() != want[i] {
+				t.Errorf("frame[%d] = %#x, want %#x", i, v.Int32(), want[i])
+			}
+		}
+	})
+	t.Run("int8", func(t *testing.T) {
+		frame := make([]int8, 5)
+		readFrame(t, func(r io.Reader) error { return NewFrameReader[int8](r).Read(frame) })
+		want := []int8{-128, -1, 0, 64, 127}
+		if !reflect.DeepEqual(frame, want) {
+			t.Errorf("frame = %v, want %v", frame, want)
+		}
+	})
+	t.Run("uint8", func(t *testing.T) {
+		frame := make([]uint8, 5)
+		readFrame(t, func(r io.Reader) error { return NewFrameReader[uint8](r).Read(frame) })
+		want := []uint8{0, 127, 128, 192, 255}
+		if !reflect.DeepEqual(frame, want) {
+			t.Errorf("frame = %v, want %v", frame, want)
+		}
+	})
+}
+
+// loopReader reads data repeatedly.
+type loopReader struct {
+	data []byte
+	pos  int
+}
+
+func (r *loopReader) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		c := copy(p[n:], r.data[r.pos:])
+		n += c
+		r.pos = (r.pos + c) % len(r.data)
+	}
+	return n, nil
+}
+
+func benchmarkFrameReader[T audio.SampleType](b *testing.B) {
+	// 10ms frames at 48kHz
+	const size = 480
+	r := NewFrameReader[T](&loopReader{data: pcm(make([]int16, 4*size)...)})
+	frame := make([]T, size)
+
+	b.ReportAllocs()
+	b.SetBytes(2 * size)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := r.Read(frame); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkFrameReader(b *testing.B) {
+	b.Run("int16", benchmarkFrameReader[int16])
+	b.Run("float32", benchmarkFrameReader[float32])
+	// binary.Read was used before the reader decoded samples itself, for
+	// comparison
+	b.Run("int16-binary.Read", func(b *testing.B) {
+		const size = 480
+		r := &loopReader{data: pcm(make([]int16, 4*size)...)}
+		frame := make([]int16, size)
+
+		b.ReportAllocs()
+		b.SetBytes(2 * size)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if err := binary.Read(r, binary.LittleEndian, frame); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
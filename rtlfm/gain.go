@@ -0,0 +1,51 @@
+package rtlfm
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// gain steps of R820T/R820T2 tuner in tenths of a dB
+var r820tGains = []int{
+	0, 9, 14, 27, 37, 77, 87, 125, 144, 157,
+	166, 197, 207, 229, 254, 280, 297, 328, 338, 364,
+	372, 386, 402, 421, 434, 439, 445, 480, 496,
+}
+
+func Gains() []float64 {
+	gains := make([]float64, len(r820tGains))
+	for i, g := range r820tGains {
+		gains[i] = float64(g) / 10
+	}
+	return gains
+}
+
+func ValidGain(gain float64) bool {
+	g := int(math.Round(gain * 10))
+	if math.Abs(float64(g)-gain*10) > 0.01 {
+		return false
+	}
+	for _, step := range r820tGains {
+		if step == g {
+			return true
+		}
+	}
+	return false
+}
+
+var errParseGain = errors.New("failed to parse gain")
+
+// ParseGain parses a gain in dB. It returns auto == true for "auto".
+func ParseGain(s string) (gain float64, auto bool, err error) {
+	s = strings.TrimSuffix(strings.TrimSpace(s), "dB")
+	if strings.EqualFold(s, "auto") {
+		return 0, true, nil
+	}
+	gain, err = strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, false, errParseGain
+	}
+	return gain, false, nil
+}
@@ -8,6 +8,9 @@ import (
 	"os"
 	"os/exec"
 	"strconv"
+	"strings"
+	"sync"
+	"time"
 )
 
 const defaultCommand = "rtl_fm"
@@ -58,17 +61,175 @@ func (f Frequency) String() string {
 	if f < MegaHertz {
 		i := f / KiloHertz
 		d := f % KiloHertz
-		return fmt.Sprintf("%d.%dK", i, d)
+		return fmt.Sprintf("%d.%sK", i, fraction(int(d), 3))
 	}
 
 	i := f / MegaHertz
 	d := f % MegaHertz
-	return fmt.Sprintf("%d.%dM", i, d)
+	return fmt.Sprintf("%d.%sM", i, fraction(int(d), 6))
+}
+
+func (f Frequency) MarshalText() ([]byte, error) {
+	return []byte(f.String()), nil
+}
+
+func (f *Frequency) UnmarshalText(b []byte) error {
+	freq, err := ParseFrequency(string(b))
+	if err != nil {
+		return err
+	}
+	*f = freq
+	return nil
+}
+
+func fraction(d, digits int) string {
+	s := strings.TrimRight(fmt.Sprintf("%0*d", digits, d), "0")
+	if s == "" {
+		return "0"
+	}
+	return s
+}
+
+const maxFrequencies = 1000
+
+var errTooManyFrequencies = errors.New("too many frequencies")
+
+// ParseFrequencies parses a comma separated list of frequencies and
+// frequency ranges in the form of start:stop:step (e.g. 144.0M:146.0M:25K).
+func ParseFrequencies(s string) ([]Frequency, error) {
+	var freqs []Frequency
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if !strings.Contains(item, ":") {
+			freq, err := ParseFrequency(item)
+			if err != nil {
+				return nil, err
+			}
+			freqs = append(freqs, freq)
+			continue
+		}
+
+		parts := strings.Split(item, ":")
+		if len(parts) != 3 {
+			return nil, errParseFrequency
+		}
+		var values [3]Frequency
+		for i, part := range parts {
+			freq, err := ParseFrequency(part)
+			if err != nil {
+				return nil, err
+			}
+			values[i] = freq
+		}
+		start, stop, step := values[0], values[1], values[2]
+		if step <= 0 || start > stop {
+			return nil, errParseFrequency
+		}
+		for freq := start; freq <= stop; freq += step {
+			freqs = append(freqs, freq)
+			if len(freqs) > maxFrequencies {
+				return nil, errTooManyFrequencies
+			}
+		}
+	}
+	if len(freqs) > maxFrequencies {
+		return nil, errTooManyFrequencies
+	}
+
+	return freqs, nil
+}
+
+type Modulation int
+
+const (
+	WBFM Modulation = iota + 1
+	FM
+	AM
+	USB
+	LSB
+	Raw
+)
+
+var modulationNames = map[Modulation]string{
+	WBFM: "wbfm",
+	FM:   "fm",
+	AM:   "am",
+	USB:  "usb",
+	LSB:  "lsb",
+	Raw:  "raw",
+}
+
+var errParseModulation = errors.New("failed to parse modulation")
+
+func ParseModulation(s string) (Modulation, error) {
+	s = strings.ToLower(s)
+	if s == "nfm" {
+		return FM, nil
+	}
+	for m, name := range modulationNames {
+		if name == s {
+			return m, nil
+		}
+	}
+	return 0, errParseModulation
+}
+
+func (m Modulation) String() string {
+	if name, ok := modulationNames[m]; ok {
+		return name
+	}
+	return "Modulation(" + strconv.Itoa(int(m)) + ")"
+}
+
+func (m Modulation) MarshalText() ([]byte, error) {
+	if _, ok := modulationNames[m]; !ok {
+		return nil, fmt.Errorf("invalid modulation %d", int(m))
+	}
+	return []byte(m.String()), nil
+}
+
+func (m *Modulation) UnmarshalText(b []byte) error {
+	modulation, err := ParseModulation(string(b))
+	if err != nil {
+		return err
+	}
+	*m = modulation
+	return nil
+}
+
+func (m Modulation) DefaultSampleRate() int {
+	switch m {
+	case WBFM:
+		return 400000
+	case USB, LSB:
+		return 12000
+	default:
+		return 24000
+	}
+}
+
+func (m Modulation) DefaultOutputRate() int {
+	switch m {
+	case WBFM:
+		return 48000
+	case USB, LSB:
+		return 12000
+	default:
+		return 24000
+	}
 }
 
 type Process struct {
-	cmd *exec.Cmd
-	rc  io.ReadCloser
+	cmd        *exec.Cmd
+	rc         io.ReadCloser
+	events     chan Event
+	stderrDone chan struct{}
+
+	closeOnce sync.Once
+	closeErr  error
+
+	mu  sync.Mutex
+	err error
 }
 
 func Play(ctx context.Context, freq Frequency, opts ...Option) (*Process, error) {
@@ -76,6 +237,9 @@ func Play(ctx context.Context, freq Frequency, opts ...Option) (*Process, error)
 	for _, opt := range opts {
 		opt.apply(&options)
 	}
+	if err := options.validate(); err != nil {
+		return nil, err
+	}
 
 	path, err := commandPath(&options)
 	if err != nil {
@@ -86,40 +250,96 @@ func Play(ctx context.Context, freq Frequency, opts ...Option) (*Process, error)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get stdout pipe: %w", err)
 	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		rc.Close()
+		return nil, fmt.Errorf("failed to get stderr pipe: %w", err)
+	}
 	if err := cmd.Start(); err != nil {
 		rc.Close()
+		stderr.Close()
 		return nil, fmt.Errorf("failed to start command: %w", err)
 	}
 
-	return &Process{
-		cmd: cmd,
-		rc:  rc,
-	}, nil
+	p := &Process{
+		cmd:        cmd,
+		rc:         rc,
+		events:     make(chan Event, 64),
+		stderrDone: make(chan struct{}),
+	}
+	go p.readStderr(stderr)
+
+	return p, nil
+}
+
+// Events returns a channel that receives status events parsed from stderr
+// of rtl_fm. Events are dropped if the channel is not drained.
+func (p *Process) Events() <-chan Event {
+	return p.events
+}
+
+// Err returns the fatal error reported by rtl_fm, if any.
+func (p *Process) Err() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.err
 }
 
 func (p *Process) Close() error {
-	p.rc.Close()
+	p.closeOnce.Do(func() {
+		p.rc.Close()
 
-	err := p.cmd.Process.Signal(os.Interrupt)
-	if err != nil {
-		p.cmd.Process.Kill()
-	}
-	return p.cmd.Wait()
+		err := p.cmd.Process.Signal(os.Interrupt)
+		if err != nil {
+			p.cmd.Process.Kill()
+		}
+		<-p.stderrDone
+		p.closeErr = p.cmd.Wait()
+	})
+	return p.closeErr
 }
 
 func (p *Process) Read(b []byte) (n int, err error) {
-	return p.rc.Read(b)
+	n, err = p.rc.Read(b)
+	if err == io.EOF {
+		// rtl_fm exited, report the reason if it told us
+		<-p.stderrDone
+		if perr := p.Err(); perr != nil {
+			return n, perr
+		}
+	}
+	return n, err
 }
 
 func makeArguments(freq Frequency, options *playOptions) []string {
+	modulation := options.modulation
+	if modulation == 0 {
+		modulation = WBFM
+	}
+
 	args := []string{
-		"-M", "wbfm",
+		"-M", modulation.String(),
 		"-f", freq.String(),
-		"-s", "400k",
+		"-s", strconv.Itoa(modulation.DefaultSampleRate()),
 	}
 
-	if options.sampleRate > 0 {
-		args = append(args, "-r", strconv.Itoa(options.sampleRate))
+	sampleRate := options.sampleRate
+	if sampleRate <= 0 {
+		sampleRate = modulation.DefaultOutputRate()
+	}
+	args = append(args, "-r", strconv.Itoa(sampleRate))
+
+	if options.device != "" {
+		args = append(args, "-d", options.device)
+	}
+	if options.manualGain {
+		args = append(args, "-g", strconv.FormatFloat(options.gain, 'f', -1, 64))
+	}
+	if options.ppm != 0 {
+		args = append(args, "-p", strconv.Itoa(options.ppm))
+	}
+	if options.squelch > 0 {
+		args = append(args, "-l", strconv.Itoa(options.squelch))
 	}
 
 	if options.enableLowerEdgeTuning {
@@ -155,7 +375,17 @@ func commandPath(options *playOptions) (string, error) {
 
 type playOptions struct {
 	commandPath            string
+	modulation             Modulation
 	sampleRate             int
+	device                 string
+	manualGain             bool
+	gain                   float64
+	ppm                    int
+	squelch                int
+	maxRestarts            int
+	maxRestartsSet         bool
+	minBackoff             time.Duration
+	maxBackoff             time.Duration
 	enableLowerEdgeTuning  bool
 	enableDCBlockingFilter bool
 	enableDeEmphasisFilter bool
@@ -163,6 +393,45 @@ type playOptions struct {
 	enableOffsetTuning     bool
 }
 
+var ErrInvalidOption = errors.New("invalid option")
+
+const maxPPM = 1000
+
+func (opts *playOptions) validate() error {
+	if opts.modulation != 0 {
+		if _, ok := modulationNames[opts.modulation]; !ok {
+			return fmt.Errorf("%w: unknown modulation %d", ErrInvalidOption, int(opts.modulation))
+		}
+	}
+	if opts.sampleRate < 0 {
+		return fmt.Errorf("%w: sample rate must be positive", ErrInvalidOption)
+	}
+	if opts.manualGain && !ValidGain(opts.gain) {
+		return fmt.Errorf("%w: gain %gdB is not supported by tuner", ErrInvalidOption, opts.gain)
+	}
+	if opts.ppm < -maxPPM || opts.ppm > maxPPM {
+		return fmt.Errorf("%w: ppm correction must be between %d and %d", ErrInvalidOption, -maxPPM, maxPPM)
+	}
+	if opts.squelch < 0 {
+		return fmt.Errorf("%w: squelch level must be >= 0", ErrInvalidOption)
+	}
+	if opts.minBackoff < 0 || opts.maxBackoff < 0 {
+		return fmt.Errorf("%w: restart backoff must be >= 0", ErrInvalidOption)
+	}
+	if strings.HasPrefix(opts.device, "-") {
+		return fmt.Errorf("%w: device index must be >= 0", ErrInvalidOption)
+	}
+	return nil
+}
+
+func ValidateOptions(opts ...Option) error {
+	var options playOptions
+	for _, opt := range opts {
+		opt.apply(&options)
+	}
+	return options.validate()
+}
+
 type Option interface {
 	apply(opts *playOptions)
 }
@@ -179,12 +448,72 @@ func WithCommandPath(path string) Option {
 	})
 }
 
+func WithModulation(modulation Modulation) Option {
+	return optionFunc(func(opts *playOptions) {
+		opts.modulation = modulation
+	})
+}
+
 func WithSampleRate(sampleRate int) Option {
 	return optionFunc(func(opts *playOptions) {
 		opts.sampleRate = sampleRate
 	})
 }
 
+func WithDeviceIndex(index int) Option {
+	return optionFunc(func(opts *playOptions) {
+		opts.device = strconv.Itoa(index)
+	})
+}
+
+func WithDeviceSerial(serial string) Option {
+	return optionFunc(func(opts *playOptions) {
+		opts.device = serial
+	})
+}
+
+func WithGain(gain float64) Option {
+	return optionFunc(func(opts *playOptions) {
+		opts.manualGain = true
+		opts.gain = gain
+	})
+}
+
+func WithAutoGain() Option {
+	return optionFunc(func(opts *playOptions) {
+		opts.manualGain = false
+		opts.gain = 0
+	})
+}
+
+func WithPPMCorrection(ppm int) Option {
+	return optionFunc(func(opts *playOptions) {
+		opts.ppm = ppm
+	})
+}
+
+func WithSquelch(level int) Option {
+	return optionFunc(func(opts *playOptions) {
+		opts.squelch = level
+	})
+}
+
+// WithMaxRestarts sets how many consecutive failures Supervise tolerates.
+// A negative value means restarting forever.
+func WithMaxRestarts(n int) Option {
+	return optionFunc(func(opts *playOptions) {
+		opts.maxRestarts = n
+		opts.maxRestartsSet = true
+	})
+}
+
+func WithRestartBackoff(min, max time.Duration) Option {
+	return optionFunc(func(opts *playOptions) {
+		opts.minBackoff = min
+		opts.maxBackoff = max
+	})
+}
+
 func EnableLowerEdgeTuning() Option {
 	return optionFunc(func(opts *playOptions) {
 		opts.enableLowerEdgeTuning = true
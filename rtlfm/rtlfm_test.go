@@ -0,0 +1,214 @@
+package rtlfm
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMakeArgumentsModulation(t *testing.T) {
+	tests := []struct {
+		name       string
+		modulation Modulation
+		sampleRate int
+		want       []string
+	}{
+		{"default", 0, 0, []string{"-M", "wbfm", "-f", "80.0M", "-s", "400000", "-r", "48000"}},
+		{"wbfm", WBFM, 0, []string{"-M", "wbfm", "-f", "80.0M", "-s", "400000", "-r", "48000"}},
+		{"fm", FM, 0, []string{"-M", "fm", "-f", "80.0M", "-s", "24000", "-r", "24000"}},
+		{"am", AM, 0, []string{"-M", "am", "-f", "80.0M", "-s", "24000", "-r", "24000"}},
+		{"usb", USB, 0, []string{"-M", "usb", "-f", "80.0M", "-s", "12000", "-r", "12000"}},
+		{"lsb", LSB, 0, []string{"-M", "lsb", "-f", "80.0M", "-s", "12000", "-r", "12000"}},
+		{"raw", Raw, 0, []string{"-M", "raw", "-f", "80.0M", "-s", "24000", "-r", "24000"}},
+		{"output rate", FM, 16000, []string{"-M", "fm", "-f", "80.0M", "-s", "24000", "-r", "16000"}},
+	}
+	for _, tt := range tests {
+		options := playOptions{modulation: tt.modulation, sampleRate: tt.sampleRate}
+		if got := makeArguments(80*MegaHertz, &options); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: makeArguments() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParseModulation(t *testing.T) {
+	tests := []struct {
+		s    string
+		want Modulation
+		err  bool
+	}{
+		{"wbfm", WBFM, false},
+		{"FM", FM, false},
+		{"nfm", FM, false},
+		{"am", AM, false},
+		{"Usb", USB, false},
+		{"lsb", LSB, false},
+		{"raw", Raw, false},
+		{"", 0, true},
+		{"dsb", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseModulation(tt.s)
+		if (err != nil) != tt.err || got != tt.want {
+			t.Errorf("ParseModulation(%q) = %v, %v, want %v, error %v", tt.s, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestModulationText(t *testing.T) {
+	for m, name := range modulationNames {
+		b, err := m.MarshalText()
+		if err != nil || string(b) != name {
+			t.Errorf("%v.MarshalText() = %q, %v, want %q", m, b, err, name)
+		}
+		var got Modulation
+		if err := got.UnmarshalText(b); err != nil || got != m {
+			t.Errorf("UnmarshalText(%q) = %v, %v, want %v", b, got, err, m)
+		}
+	}
+
+	if _, err := Modulation(0).MarshalText(); err == nil {
+		t.Error("Modulation(0).MarshalText() error = nil, want an error")
+	}
+	if got := Modulation(0).String(); got != "Modulation(0)" {
+		t.Errorf("Modulation(0).String() = %q, want %q", got, "Modulation(0)")
+	}
+}
+
+func TestMakeArgumentsOptions(t *testing.T) {
+	base := []string{"-M", "wbfm", "-f", "80.0M", "-s", "400000", "-r", "48000"}
+	tests := []struct {
+		name string
+		opts []Option
+		want []string
+	}{
+		{"auto gain", []Option{WithGain(49.6), WithAutoGain()}, nil},
+		{"gain", []Option{WithGain(49.6)}, []string{"-g", "49.6"}},
+		{"zero gain", []Option{WithGain(0)}, []string{"-g", "0"}},
+		{"ppm", []Option{WithPPMCorrection(-52)}, []string{"-p", "-52"}},
+		{"squelch", []Option{WithSquelch(30)}, []string{"-l", "30"}},
+		{"device index", []Option{WithDeviceIndex(1)}, []string{"-d", "1"}},
+		{"device serial", []Option{WithDeviceSerial("00000001")}, []string{"-d", "00000001"}},
+		{
+			name: "all",
+			opts: []Option{
+				WithDeviceIndex(0),
+				WithGain(28),
+				WithPPMCorrection(10),
+				WithSquelch(5),
+				EnableLowerEdgeTuning(),
+				EnableDCBlockingFilter(),
+				EnableDeEmphasisFilter(),
+				EnableDirectSampling(),
+				EnableOffsetTuning(),
+			},
+			want: []string{
+				"-d", "0", "-g", "28", "-p", "10", "-l", "5",
+				"-E", "edge", "-E", "dc", "-E", "deemp", "-E", "direct", "-E", "offset",
+			},
+		},
+	}
+	for _, tt := range tests {
+		var options playOptions
+		for _, opt := range tt.opts {
+			opt.apply(&options)
+		}
+		want := append(append([]string{}, base...), tt.want...)
+		if got := makeArguments(80*MegaHertz, &options); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: makeArguments() = %v, want %v", tt.name, got, want)
+		}
+	}
+}
+
+func TestValidateOptions(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+		ok   bool
+	}{
+		{"none", nil, true},
+		{"valid", []Option{WithModulation(FM), WithGain(49.6), WithPPMCorrection(-1000), WithSquelch(0), WithDeviceIndex(0)}, true},
+		{"unknown modulation", []Option{WithModulation(Modulation(100))}, false},
+		{"negative sample rate", []Option{WithSampleRate(-1)}, false},
+		{"unsupported gain", []Option{WithGain(50)}, false},
+		{"ppm too large", []Option{WithPPMCorrection(1001)}, false},
+		{"ppm too small", []Option{WithPPMCorrection(-1001)}, false},
+		{"negative squelch", []Option{WithSquelch(-1)}, false},
+		{"negative backoff", []Option{WithRestartBackoff(-time.Second, 0)}, false},
+		{"negative device index", []Option{WithDeviceIndex(-1)}, false},
+	}
+	for _, tt := range tests {
+		err := ValidateOptions(tt.opts...)
+		if tt.ok && err != nil {
+			t.Errorf("%s: ValidateOptions() = %v, want nil", tt.name, err)
+		}
+		if !tt.ok && !errors.Is(err, ErrInvalidOption) {
+			t.Errorf("%s: ValidateOptions() = %v, want %v", tt.name, err, ErrInvalidOption)
+		}
+	}
+}
+
+func TestValidGain(t *testing.T) {
+	for _, gain := range Gains() {
+		if !ValidGain(gain) {
+			t.Errorf("ValidGain(%g) = false, want true", gain)
+		}
+	}
+	for _, gain := range []float64{-1, 0.5, 49.65, 50} {
+		if ValidGain(gain) {
+			t.Errorf("ValidGain(%g) = true, want false", gain)
+		}
+	}
+}
+
+func TestParseGain(t *testing.T) {
+	tests := []struct {
+		s    string
+		gain float64
+		auto bool
+		err  bool
+	}{
+		{"auto", 0, true, false},
+		{" AUTO ", 0, true, false},
+		{"49.6", 49.6, false, false},
+		{"28dB", 28, false, false},
+		{"", 0, false, true},
+		{"high", 0, false, true},
+	}
+	for _, tt := range tests {
+		gain, auto, err := ParseGain(tt.s)
+		if (err != nil) != tt.err || gain != tt.gain || auto != tt.auto {
+			t.Errorf("ParseGain(%q) = %g, %v, %v, want %g, %v, error %v", tt.s, gain, auto, err, tt.gain, tt.auto, tt.err)
+		}
+	}
+}
+
+func TestParseFrequencies(t *testing.T) {
+	tests := []struct {
+		s    string
+		want []Frequency
+		err  bool
+	}{
+		{"80.0M", []Frequency{80 * MegaHertz}, false},
+		{"145.5M, 145.6M", []Frequency{145500 * KiloHertz, 145600 * KiloHertz}, false},
+		{"144M:144.1M:25K", []Frequency{144000 * KiloHertz, 144025 * KiloHertz, 144050 * KiloHertz, 144075 * KiloHertz, 144100 * KiloHertz}, false},
+		{"144M:144.01M:25K", []Frequency{144 * MegaHertz}, false},
+		{"118M,120M:120.05M:25K", []Frequency{118 * MegaHertz, 120000 * KiloHertz, 120025 * KiloHertz, 120050 * KiloHertz}, false},
+		{"", nil, true},
+		{"80.0M,", nil, true},
+		{"144M:146M", nil, true},
+		{"144M:146M:25K:1", nil, true},
+		{"146M:144M:25K", nil, true},
+		{"144M:146M:0", nil, true},
+		{"144M:x:25K", nil, true},
+		// more than 1000 frequencies
+		{"1M:2M:1K", nil, true},
+		{"1M:1.5M:1K,2M:2.6M:1K", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseFrequencies(tt.s)
+		if (err != nil) != tt.err || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseFrequencies(%q) = %v, %v, want %v, error %v", tt.s, got, err, tt.want, tt.err)
+		}
+	}
+}
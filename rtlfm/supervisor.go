@@ -0,0 +1,320 @@
+package rtlfm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+const (
+	defaultMaxRestarts = 5
+	defaultMinBackoff  = 500 * time.Millisecond
+	defaultMaxBackoff  = 30 * time.Second
+
+	// failures are reset once rtl_fm keeps running for this duration
+	stableDuration = time.Minute
+
+	silenceInterval = 10 * time.Millisecond
+
+	readSize  = 4096
+	dataDepth = 16
+)
+
+type RestartEvent struct {
+	Restarts int
+	Err      error
+}
+
+func (RestartEvent) event() {}
+
+// Supervisor runs rtl_fm and restarts it with backoff when it exits
+// unexpectedly. Read returns silence while rtl_fm is not running. It gives
+// up without restarting when rtl_fm cannot be started or finds no device.
+type Supervisor struct {
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	events chan Event
+	data   chan []byte
+	// buffers consumed by Read, reused for the output of rtl_fm
+	free chan []byte
+	buf  []byte
+	// the buffer of rtl_fm being consumed by Read
+	cur []byte
+
+	silence []byte
+	timer   *time.Timer
+
+	mu       sync.Mutex
+	running  bool
+	restarts int
+	err      error
+}
+
+func Supervise(ctx context.Context, freq Frequency, opts ...Option) (*Supervisor, error) {
+	var options playOptions
+	for _, opt := range opts {
+		opt.apply(&options)
+	}
+	if err := options.validate(); err != nil {
+		return nil, err
+	}
+
+	maxRestarts := defaultMaxRestarts
+	if options.maxRestartsSet {
+		maxRestarts = options.maxRestarts
+	}
+	minBackoff := options.minBackoff
+	if minBackoff <= 0 {
+		minBackoff = defaultMinBackoff
+	}
+	maxBackoff := options.maxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = defaultMaxBackoff
+	}
+	if maxBackoff < minBackoff {
+		maxBackoff = minBackoff
+	}
+
+	outputRate := options.sampleRate
+	if outputRate <= 0 {
+		modulation := options.modulation
+		if modulation == 0 {
+			modulation = WBFM
+		}
+		outputRate = modulation.DefaultOutputRate()
+	}
+	silenceSamples := int(float64(outputRate) * silenceInterval.Seconds())
+
+	ctx, cancel := context.WithCancel(ctx)
+	s := &Supervisor{
+		cancel:  cancel,
+		events:  make(chan Event, 64),
+		data:    make(chan []byte, dataDepth),
+		free:    make(chan []byte, dataDepth+2),
+		silence: make([]byte, 2*silenceSamples),
+		timer:   time.NewTimer(silenceInterval),
+	}
+	s.timer.Stop()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer close(s.data)
+		defer close(s.events)
+
+		err := s.run(ctx, freq, opts, maxRestarts, minBackoff, maxBackoff)
+		if err != nil && !errors.Is(err, context.Canceled) {
+			s.mu.Lock()
+			s.err = err
+			s.mu.Unlock()
+		}
+	}()
+
+	return s, nil
+}
+
+// Events returns a channel that receives status events of rtl_fm and
+// RestartEvent. Events are dropped if the channel is not drained.
+func (s *Supervisor) Events() <-chan Event {
+	return s.events
+}
+
+func (s *Supervisor) Restarts() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.restarts
+}
+
+func (s *Supervisor) Running() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.running
+}
+
+func (s *Supervisor) Read(b []byte) (int, error) {
+	if len(s.buf) == 0 && s.cur != nil {
+		select {
+		case s.free <- s.cur[:0]:
+		default:
+		}
+		s.cur = nil
+	}
+
+	for len(s.buf) == 0 {
+		select {
+		case data, ok := <-s.data:
+			if !ok {
+				return 0, s.readErr()
+			}
+			s.buf = data
+			s.cur = data
+			continue
+		default:
+		}
+
+		s.timer.Reset(silenceInterval)
+		select {
+		case data, ok := <-s.data:
+			if !s.timer.Stop() {
+				<-s.timer.C
+			}
+			if !ok {
+				return 0, s.readErr()
+			}
+			s.buf = data
+			s.cur = data
+		case <-s.timer.C:
+			if !s.Running() {
+				s.buf = s.silence
+			}
+		}
+	}
+
+	n := copy(b, s.buf)
+	s.buf = s.buf[n:]
+	return n, nil
+}
+
+func (s *Supervisor) readErr() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	return io.EOF
+}
+
+func (s *Supervisor) Close() error {
+	s.cancel()
+	// drain audio so that the supervising goroutine is not blocked
+	go func() {
+		for range s.data {
+		}
+	}()
+	s.wg.Wait()
+	return nil
+}
+
+func (s *Supervisor) run(ctx context.Context, freq Frequency, opts []Option, maxRestarts int, minBackoff, maxBackoff time.Duration) error {
+	backoff := minBackoff
+	failures := 0
+	for {
+		p, err := Play(ctx, freq, opts...)
+		if err != nil {
+			// restarting does not help if rtl_fm cannot be started
+			return err
+		}
+		started := time.Now()
+		err = s.runProcess(ctx, p)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if errors.Is(err, ErrNoDevice) {
+			return err
+		}
+
+		if time.Since(started) >= stableDuration {
+			failures = 0
+			backoff = minBackoff
+		}
+		failures++
+		if maxRestarts >= 0 && failures > maxRestarts {
+			return fmt.Errorf("rtl_fm failed %d times: %w", failures, err)
+		}
+
+		s.mu.Lock()
+		s.restarts++
+		restarts := s.restarts
+		s.mu.Unlock()
+		s.sendEvent(RestartEvent{Restarts: restarts, Err: err})
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+func (s *Supervisor) runProcess(ctx context.Context, p *Process) error {
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for ev := range p.Events() {
+			s.sendEvent(ev)
+		}
+	}()
+	defer wg.Wait()
+	defer p.Close()
+
+	s.setRunning(true)
+	defer s.setRunning(false)
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			// unblock Read
+			p.Close()
+		case <-done:
+		}
+	}()
+
+	// the first byte of a sample split across reads, it is dropped when
+	// rtl_fm exits so that samples of the next process stay aligned
+	var odd []byte
+	for {
+		var buf []byte
+		select {
+		case buf = <-s.free:
+		default:
+			buf = make([]byte, 0, readSize)
+		}
+		buf = append(buf[:0], odd...)
+
+		n, err := p.Read(buf[len(buf):readSize])
+		buf = buf[:len(buf)+n]
+		odd = append(odd[:0], buf[len(buf)&^1:]...)
+		buf = buf[:len(buf)&^1]
+		if len(buf) > 0 {
+			select {
+			case s.data <- buf:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		} else {
+			select {
+			case s.free <- buf:
+			default:
+			}
+		}
+		if err != nil {
+			if err == io.EOF {
+				return errors.New("rtl_fm exited unexpectedly")
+			}
+			return err
+		}
+	}
+}
+
+func (s *Supervisor) setRunning(running bool) {
+	s.mu.Lock()
+	s.running = running
+	s.mu.Unlock()
+}
+
+func (s *Supervisor) sendEvent(ev Event) {
+	select {
+	case s.events <- ev:
+	default:
+	}
+}
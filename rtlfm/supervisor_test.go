@@ -0,0 +1,159 @@
+package rtlfm
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeCommand writes a shell script that acts as rtl_fm and returns its
+// path.
+func fakeCommand(t *testing.T, script string) string {
+	t.Helper()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("shell scripts are not supported")
+	}
+	path := filepath.Join(t.TempDir(), "rtl_fm")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script+"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func supervise(t *testing.T, script string, opts ...Option) *Supervisor {
+	t.Helper()
+
+	opts = append([]Option{WithCommandPath(fakeCommand(t, script))}, opts...)
+	s, err := Supervise(context.Background(), 80*MegaHertz, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func TestSupervisorRestart(t *testing.T) {
+	// a sample and a half, the odd byte must not shift the samples of the
+	// next process
+	s := supervise(t, `printf abc; exit 1`,
+		WithMaxRestarts(2),
+		WithRestartBackoff(time.Millisecond, time.Millisecond),
+	)
+
+	data, err := io.ReadAll(s)
+	if err == nil || !strings.Contains(err.Error(), "rtl_fm failed 3 times") {
+		t.Errorf("Read() error = %v, want rtl_fm failed 3 times", err)
+	}
+	if len(data)%2 != 0 {
+		t.Errorf("read %d bytes, want whole samples", len(data))
+	}
+	// silence is read between processes
+	if got := string(bytes.ReplaceAll(data, []byte{0}, nil)); got != "ababab" {
+		t.Errorf("Read() = %q without silence, want %q", got, "ababab")
+	}
+	if got := s.Restarts(); got != 2 {
+		t.Errorf("Restarts() = %d, want 2", got)
+	}
+
+	var restarts []int
+	for ev := range s.Events() {
+		if ev, ok := ev.(RestartEvent); ok {
+			restarts = append(restarts, ev.Restarts)
+		}
+	}
+	if len(restarts) != 2 || restarts[0] != 1 || restarts[1] != 2 {
+		t.Errorf("restart events %v, want [1 2]", restarts)
+	}
+}
+
+func TestSupervisorBackoff(t *testing.T) {
+	const (
+		min = 20 * time.Millisecond
+		max = 30 * time.Millisecond
+	)
+	s := supervise(t, `exit 1`,
+		WithMaxRestarts(3),
+		WithRestartBackoff(min, max),
+	)
+
+	start := time.Now()
+	if _, err := io.ReadAll(s); err == nil {
+		t.Error("Read() error = nil, want an error")
+	}
+	// doubled from min, and capped at max
+	if got, want := time.Since(start), min+max+max; got < want {
+		t.Errorf("restarted 3 times in %v, want >= %v", got, want)
+	}
+}
+
+func TestSupervisorSilence(t *testing.T) {
+	s := supervise(t, `exit 1`,
+		WithMaxRestarts(-1),
+		WithRestartBackoff(time.Minute, time.Minute),
+	)
+
+	// rtl_fm is waiting to be restarted
+	b := make([]byte, 4096)
+	for i := 0; i < 3; i++ {
+		n, err := s.Read(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n == 0 || n%2 != 0 {
+			t.Fatalf("Read() = %d bytes, want whole samples", n)
+		}
+		for _, v := range b[:n] {
+			if v != 0 {
+				t.Fatalf("Read() = % x, want silence", b[:n])
+			}
+		}
+	}
+	if s.Running() {
+		t.Error("Running() = true, want false")
+	}
+}
+
+func TestSupervisorFatal(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+		want error
+	}{
+		{
+			name: "no device",
+			path: fakeCommand(t, `echo "No supported devices found." >&2; exit 1`),
+			want: ErrNoDevice,
+		},
+		{
+			name: "not found",
+			path: filepath.Join(t.TempDir(), "rtl_fm"),
+			want: os.ErrNotExist,
+		},
+	}
+	for _, tt := range tests {
+		s, err := Supervise(context.Background(), 80*MegaHertz,
+			WithCommandPath(tt.path),
+			WithMaxRestarts(5),
+			WithRestartBackoff(time.Millisecond, time.Millisecond),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := io.ReadAll(s); !errors.Is(err, tt.want) {
+			t.Errorf("%s: Read() error = %v, want %v", tt.name, err, tt.want)
+		}
+		if got := s.Restarts(); got != 0 {
+			t.Errorf("%s: Restarts() = %d, want 0", tt.name, got)
+		}
+		s.Close()
+	}
+}
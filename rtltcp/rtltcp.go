@@ -0,0 +1,247 @@
+package rtltcp
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/kechako/goradio/rtlfm"
+)
+
+const (
+	DefaultAddress = "127.0.0.1:1234"
+
+	headerSize    = 12
+	headerTimeout = 5 * time.Second
+	commandSize   = 5
+	headerMagic   = "RTL0"
+)
+
+type TunerType uint32
+
+const (
+	TunerUnknown TunerType = iota
+	TunerE4000
+	TunerFC0012
+	TunerFC0013
+	TunerFC2580
+	TunerR820T
+	TunerR828D
+)
+
+var tunerNames = map[TunerType]string{
+	TunerUnknown: "unknown",
+	TunerE4000:   "E4000",
+	TunerFC0012:  "FC0012",
+	TunerFC0013:  "FC0013",
+	TunerFC2580:  "FC2580",
+	TunerR820T:   "R820T",
+	TunerR828D:   "R828D",
+}
+
+func (t TunerType) String() string {
+	if name, ok := tunerNames[t]; ok {
+		return name
+	}
+	return "TunerType(" + strconv.Itoa(int(t)) + ")"
+}
+
+type DongleInfo struct {
+	Tuner     TunerType
+	GainCount int
+}
+
+type command byte
+
+const (
+	cmdSetFrequency      command = 0x01
+	cmdSetSampleRate     command = 0x02
+	cmdSetGainMode       command = 0x03
+	cmdSetGain           command = 0x04
+	cmdSetFreqCorrection command = 0x05
+	cmdSetIFGain         command = 0x06
+	cmdSetTestMode       command = 0x07
+	cmdSetAGCMode        command = 0x08
+	cmdSetDirectSampling command = 0x09
+	cmdSetOffsetTuning   command = 0x0a
+	cmdSetRTLXtal        command = 0x0b
+	cmdSetTunerXtal      command = 0x0c
+	cmdSetGainByIndex    command = 0x0d
+	cmdSetBiasTee        command = 0x0e
+)
+
+var ErrInvalidHeader = errors.New("invalid rtl_tcp header")
+
+// Client is a client of rtl_tcp. Read returns unsigned 8-bit interleaved
+// IQ samples.
+type Client struct {
+	conn net.Conn
+	info DongleInfo
+
+	mu  sync.Mutex
+	cmd [commandSize]byte
+}
+
+func Dial(ctx context.Context, addr string) (*Client, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to rtl_tcp: %w", err)
+	}
+
+	c, err := NewClient(conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// NewClient reads the dongle information header from conn.
+func NewClient(conn net.Conn) (*Client, error) {
+	if err := conn.SetReadDeadline(time.Now().Add(headerTimeout)); err != nil {
+		return nil, fmt.Errorf("failed to set read deadline: %w", err)
+	}
+
+	var h [headerSize]byte
+	if _, err := io.ReadFull(conn, h[:]); err != nil {
+		return nil, fmt.Errorf("failed to read rtl_tcp header: %w", err)
+	}
+	if string(h[:4]) != headerMagic {
+		return nil, ErrInvalidHeader
+	}
+
+	if err := conn.SetReadDeadline(time.Time{}); err != nil {
+		return nil, fmt.Errorf("failed to set read deadline: %w", err)
+	}
+
+	return &Client{
+		conn: conn,
+		info: DongleInfo{
+			Tuner:     TunerType(binary.BigEndian.Uint32(h[4:])),
+			GainCount: int(binary.BigEndian.Uint32(h[8:])),
+		},
+	}, nil
+}
+
+func (c *Client) Info() DongleInfo {
+	return c.info
+}
+
+func (c *Client) Read(b []byte) (int, error) {
+	return c.conn.Read(b)
+}
+
+func (c *Client) Close() error {
+	if err := c.conn.Close(); err != nil {
+		return fmt.Errorf("failed to close rtl_tcp connection: %w", err)
+	}
+	return nil
+}
+
+func (c *Client) SetFrequency(freq rtlfm.Frequency) error {
+	if freq <= 0 || int64(freq) > math.MaxUint32 {
+		return errors.New("invalid frequency")
+	}
+	return c.send(cmdSetFrequency, uint32(freq))
+}
+
+func (c *Client) SetSampleRate(rate int) error {
+	if rate <= 0 {
+		return errors.New("invalid sample rate")
+	}
+	return c.send(cmdSetSampleRate, uint32(rate))
+}
+
+// SetGain sets the tuner gain in dB and switches to manual gain mode.
+func (c *Client) SetGain(gain float64) error {
+	if err := c.send(cmdSetGainMode, 1); err != nil {
+		return err
+	}
+	return c.send(cmdSetGain, uint32(int32(math.Round(gain*10))))
+}
+
+func (c *Client) SetAutoGain() error {
+	return c.send(cmdSetGainMode, 0)
+}
+
+func (c *Client) SetGainByIndex(index int) error {
+	if index < 0 || index >= c.info.GainCount {
+		return errors.New("invalid gain index")
+	}
+	if err := c.send(cmdSetGainMode, 1); err != nil {
+		return err
+	}
+	return c.send(cmdSetGainByIndex, uint32(index))
+}
+
+// SetIFGain sets the gain of the IF stage in dB. It is only supported by
+// E4000 tuner.
+func (c *Client) SetIFGain(stage int, gain float64) error {
+	g := uint32(uint16(int16(math.Round(gain * 10))))
+	return c.send(cmdSetIFGain, uint32(stage)<<16|g)
+}
+
+func (c *Client) SetPPMCorrection(ppm int) error {
+	return c.send(cmdSetFreqCorrection, uint32(int32(ppm)))
+}
+
+func (c *Client) SetAGC(enabled bool) error {
+	return c.send(cmdSetAGCMode, boolParam(enabled))
+}
+
+func (c *Client) SetTestMode(enabled bool) error {
+	return c.send(cmdSetTestMode, boolParam(enabled))
+}
+
+// SetDirectSampling sets direct sampling mode, 0: disabled, 1: I-ADC,
+// 2: Q-ADC.
+func (c *Client) SetDirectSampling(mode int) error {
+	if mode < 0 || mode > 2 {
+		return errors.New("invalid direct sampling mode")
+	}
+	return c.send(cmdSetDirectSampling, uint32(mode))
+}
+
+func (c *Client) SetOffsetTuning(enabled bool) error {
+	return c.send(cmdSetOffsetTuning, boolParam(enabled))
+}
+
+func (c *Client) SetRTLXtal(freq rtlfm.Frequency) error {
+	return c.send(cmdSetRTLXtal, uint32(freq))
+}
+
+func (c *Client) SetTunerXtal(freq rtlfm.Frequency) error {
+	return c.send(cmdSetTunerXtal, uint32(freq))
+}
+
+func (c *Client) SetBiasTee(enabled bool) error {
+	return c.send(cmdSetBiasTee, boolParam(enabled))
+}
+
+func (c *Client) send(cmd command, param uint32) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.cmd[0] = byte(cmd)
+	binary.BigEndian.PutUint32(c.cmd[1:], param)
+	if _, err := c.conn.Write(c.cmd[:]); err != nil {
+		return fmt.Errorf("failed to send rtl_tcp command: %w", err)
+	}
+	return nil
+}
+
+func boolParam(b bool) uint32 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,276 @@
+package rtltcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/kechako/goradio/rtlfm"
+)
+
+// fakeServer is an rtl_tcp server sending header and the IQ samples iq,
+// and recording the commands received.
+type fakeServer struct {
+	l        net.Listener
+	commands chan [commandSize]byte
+}
+
+func newFakeServer(t *testing.T, header, iq []byte) *fakeServer {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeServer{
+		l:        l,
+		commands: make(chan [commandSize]byte, 64),
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer close(s.commands)
+
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		if _, err := conn.Write(header); err != nil {
+			return
+		}
+		if _, err := conn.Write(iq); err != nil {
+			return
+		}
+		for {
+			var cmd [commandSize]byte
+			if _, err := io.ReadFull(conn, cmd[:]); err != nil {
+				return
+			}
+			s.commands <- cmd
+		}
+	}()
+	t.Cleanup(func() {
+		l.Close()
+		<-done
+	})
+	return s
+}
+
+// nextCommand returns the next command received by s.
+func (s *fakeServer) nextCommand(t *testing.T) (command, uint32) {
+	t.Helper()
+
+	select {
+	case cmd, ok := <-s.commands:
+		if !ok {
+			t.Fatal("connection is closed")
+		}
+		return command(cmd[0]), binary.BigEndian.Uint32(cmd[1:])
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for a command")
+	}
+	return 0, 0
+}
+
+func header(magic string, tuner TunerType, gainCount uint32) []byte {
+	b := make([]byte, headerSize)
+	copy(b, magic)
+	binary.BigEndian.PutUint32(b[4:], uint32(tuner))
+	binary.BigEndian.PutUint32(b[8:], gainCount)
+	return b
+}
+
+func dial(t *testing.T, s *fakeServer) *Client {
+	t.Helper()
+
+	c, err := Dial(context.Background(), s.l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		c.Close()
+	})
+	return c
+}
+
+func TestDialHeader(t *testing.T) {
+	iq := []byte{127, 128, 0, 255}
+	s := newFakeServer(t, header(headerMagic, TunerR820T, 29), iq)
+	c := dial(t, s)
+
+	want := DongleInfo{Tuner: TunerR820T, GainCount: 29}
+	if got := c.Info(); got != want {
+		t.Errorf("Info() = %+v, want %+v", got, want)
+	}
+
+	// samples follow the header
+	got := make([]byte, len(iq))
+	if _, err := io.ReadFull(c, got); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, iq) {
+		t.Errorf("Read() = %v, want %v", got, iq)
+	}
+}
+
+func TestDialInvalidHeader(t *testing.T) {
+	s := newFakeServer(t, header("RTL1", TunerR820T, 29), nil)
+	if _, err := Dial(context.Background(), s.l.Addr().String()); !errors.Is(err, ErrInvalidHeader) {
+		t.Errorf("Dial() error = %v, want %v", err, ErrInvalidHeader)
+	}
+}
+
+func TestNewClientShortHeader(t *testing.T) {
+	server, conn := net.Pipe()
+	defer conn.Close()
+	go func() {
+		server.Write([]byte(headerMagic))
+		server.Close()
+	}()
+
+	if _, err := NewClient(conn); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("NewClient() error = %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+}
+
+func TestTunerTypeString(t *testing.T) {
+	tests := []struct {
+		tuner TunerType
+		want  string
+	}{
+		{TunerUnknown, "unknown"},
+		{TunerE4000, "E4000"},
+		{TunerR820T, "R820T"},
+		{TunerR828D, "R828D"},
+		{TunerType(42), "TunerType(42)"},
+	}
+	for _, tt := range tests {
+		if got := tt.tuner.String(); got != tt.want {
+			t.Errorf("TunerType(%d).String() = %q, want %q", uint32(tt.tuner), got, tt.want)
+		}
+	}
+}
+
+func TestClientCommands(t *testing.T) {
+	type want struct {
+		cmd   command
+		param uint32
+	}
+	tests := []struct {
+		name string
+		send func(c *Client) error
+		want []want
+	}{
+		{
+			name: "frequency",
+			send: func(c *Client) error { return c.SetFrequency(80 * rtlfm.MegaHertz) },
+			want: []want{{cmdSetFrequency, 80000000}},
+		},
+		{
+			name: "sample rate",
+			send: func(c *Client) error { return c.SetSampleRate(2048000) },
+			want: []want{{cmdSetSampleRate, 2048000}},
+		},
+		{
+			name: "auto gain",
+			send: func(c *Client) error { return c.SetAutoGain() },
+			want: []want{{cmdSetGainMode, 0}},
+		},
+		{
+			name: "gain",
+			send: func(c *Client) error { return c.SetGain(49.6) },
+			want: []want{{cmdSetGainMode, 1}, {cmdSetGain, 496}},
+		},
+		{
+			name: "negative gain",
+			send: func(c *Client) error { return c.SetGain(-1) },
+			want: []want{{cmdSetGainMode, 1}, {cmdSetGain, 0xfffffff6}},
+		},
+		{
+			name: "gain by index",
+			send: func(c *Client) error { return c.SetGainByIndex(28) },
+			want: []want{{cmdSetGainMode, 1}, {cmdSetGainByIndex, 28}},
+		},
+		{
+			name: "ppm",
+			send: func(c *Client) error { return c.SetPPMCorrection(-3) },
+			want: []want{{cmdSetFreqCorrection, 0xfffffffd}},
+		},
+		{
+			name: "agc on",
+			send: func(c *Client) error { return c.SetAGC(true) },
+			want: []want{{cmdSetAGCMode, 1}},
+		},
+		{
+			name: "agc off",
+			send: func(c *Client) error { return c.SetAGC(false) },
+			want: []want{{cmdSetAGCMode, 0}},
+		},
+		{
+			name: "if gain",
+			send: func(c *Client) error { return c.SetIFGain(2, -3) },
+			want: []want{{cmdSetIFGain, 2<<16 | 0xffe2}},
+		},
+		{
+			name: "direct sampling",
+			send: func(c *Client) error { return c.SetDirectSampling(2) },
+			want: []want{{cmdSetDirectSampling, 2}},
+		},
+		{
+			name: "bias tee",
+			send: func(c *Client) error { return c.SetBiasTee(true) },
+			want: []want{{cmdSetBiasTee, 1}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newFakeServer(t, header(headerMagic, TunerR820T, 29), nil)
+			c := dial(t, s)
+
+			if err := tt.send(c); err != nil {
+				t.Fatal(err)
+			}
+			for _, w := range tt.want {
+				cmd, param := s.nextCommand(t)
+				if cmd != w.cmd || param != w.param {
+					t.Errorf("command = %#02x %#08x, want %#02x %#08x", byte(cmd), param, byte(w.cmd), w.param)
+				}
+			}
+		})
+	}
+}
+
+func TestClientInvalidCommands(t *testing.T) {
+	s := newFakeServer(t, header(headerMagic, TunerR820T, 29), nil)
+	c := dial(t, s)
+
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{"zero frequency", c.SetFrequency(0)},
+		{"too high frequency", c.SetFrequency(5000 * rtlfm.MegaHertz)},
+		{"zero sample rate", c.SetSampleRate(0)},
+		{"negative gain index", c.SetGainByIndex(-1)},
+		{"gain index out of range", c.SetGainByIndex(29)},
+		{"direct sampling mode", c.SetDirectSampling(3)},
+	}
+	for _, tt := range tests {
+		if tt.err == nil {
+			t.Errorf("%s: error = nil", tt.name)
+		}
+	}
+
+	// nothing is sent for invalid parameters
+	c.Close()
+	for cmd := range s.commands {
+		t.Errorf("command %#02x sent", cmd[0])
+	}
+}
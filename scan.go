@@ -0,0 +1,154 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/kechako/goradio/resample"
+	"github.com/kechako/goradio/rtlfm"
+	"github.com/kechako/goradio/rtltcp"
+	"github.com/kechako/goradio/scan"
+	"github.com/kechako/goradio/source"
+	cli "github.com/urfave/cli/v2"
+)
+
+func scanRadioCommand(ctx *cli.Context) error {
+	var freqs []rtlfm.Frequency
+	for _, s := range ctx.StringSlice("freq") {
+		f, err := rtlfm.ParseFrequencies(s)
+		if err != nil {
+			return ArgumentError("invalid frequency")
+		}
+		freqs = append(freqs, f...)
+	}
+	if len(freqs) == 0 {
+		return ArgumentError("frequency is not specified")
+	}
+	if ctx.Int("squelch") <= 0 {
+		return ArgumentError("squelch level is required to scan")
+	}
+	// the dongle is chosen by rtl_tcp, and the channel is demodulated at
+	// the center of the band
+	for _, name := range []string{"dongle", "edge"} {
+		if onCommandLine(ctx, name) {
+			return ArgumentError(fmt.Sprintf("--%s is not supported by scan", name))
+		}
+	}
+	dwell := ctx.Duration("dwell")
+	hang := ctx.Duration("hang")
+	if dwell < 0 || hang < 0 {
+		return ArgumentError("invalid scan duration")
+	}
+	t, err := tuningSettings(ctx)
+	if err != nil {
+		return err
+	}
+	t.Frequency = freqs[0]
+	addr, err := scanAddress(ctx)
+	if err != nil {
+		return err
+	}
+	quality, err := resampleQuality(ctx)
+	if err != nil {
+		return err
+	}
+
+	device, err := outputDevice(ctx)
+	if err != nil {
+		return err
+	}
+	sampleRate := ctx.Int("sample-rate")
+	if sampleRate == 0 {
+		sampleRate = device.DefaultSampleRate()
+	}
+
+	process, _, err := outputProcess(ctx, sampleRate)
+	if err != nil {
+		return err
+	}
+
+	d, err := newDemodulator(ctx, t, 0)
+	if err != nil {
+		return err
+	}
+	// rtl_tcp is retuned in place, the connection is kept while scanning
+	c, err := dialRTLTCP(ctx, addr, t, d.InputRate())
+	if err != nil {
+		return err
+	}
+
+	s, err := scan.Scan(ctx.Context, c, d, freqs,
+		scan.WithSquelch(float64(ctx.Int("squelch"))),
+		scan.WithDwell(dwell),
+		scan.WithHangTime(hang),
+	)
+	if err != nil {
+		c.Close()
+		if errors.Is(err, scan.ErrInvalidOption) {
+			return ArgumentError(err.Error())
+		}
+		return fmt.Errorf("failed to scan radio: %w", err)
+	}
+	defer s.Close()
+
+	stream, bufferSamples, err := openOutputStream(ctx, device, sampleRate)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	if err := stream.Start(); err != nil {
+		return err
+	}
+	defer stream.Stop()
+
+	go func() {
+		for ev := range s.Events() {
+			state := "closed"
+			if ev.Open {
+				state = "opened"
+			}
+			fmt.Printf("%s squelch %s: %s\n", ev.Time.Format("15:04:05"), state, ev.Frequency)
+		}
+	}()
+
+	fmt.Printf("scanning %d frequencies: %s\n", len(freqs), frequencyList(freqs))
+
+	src, err := source.Resample(source.NewPCM(s, d.OutputRate()), sampleRate, resample.WithQuality(quality))
+	if err != nil {
+		return err
+	}
+
+	buf := newJitterBuffer(ctx, sampleRate)
+	defer printJitterStats(buf)
+
+	return playFrames(ctx.Context, src, src, stream, bufferSamples, buf, process)
+}
+
+// scanAddress returns the address of the rtl_tcp server specified by
+// --source. Scanning retunes the dongle in place, which rtl_fm cannot do
+// while reporting the frequency tuned.
+func scanAddress(ctx *cli.Context) (string, error) {
+	kind, addr, _ := strings.Cut(ctx.String("source"), ":")
+	if kind != "rtltcp" {
+		return "", ArgumentError("scanning needs an rtl_tcp source (rtltcp[:HOST:PORT])")
+	}
+	if addr == "" {
+		addr = rtltcp.DefaultAddress
+	}
+	return addr, nil
+}
+
+func frequencyList(freqs []rtlfm.Frequency) string {
+	const max = 8
+	var names []string
+	for i, f := range freqs {
+		if i == max {
+			names = append(names, "...")
+			break
+		}
+		names = append(names, f.String())
+	}
+	return strings.Join(names, ", ")
+}
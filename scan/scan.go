@@ -0,0 +1,333 @@
+package scan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/kechako/goradio/demod"
+	"github.com/kechako/goradio/rtlfm"
+)
+
+const (
+	defaultDwell  = 2 * time.Second
+	defaultHang   = 2 * time.Second
+	defaultSettle = 250 * time.Millisecond
+
+	readSize = 16384
+	// chunks of audio queued for Read
+	dataDepth = 16
+)
+
+var ErrInvalidOption = errors.New("invalid scan option")
+
+// Tuner is a receiver of unsigned 8-bit interleaved IQ samples that is
+// retuned in place, e.g. *rtltcp.Client.
+type Tuner interface {
+	io.ReadCloser
+	SetFrequency(freq rtlfm.Frequency) error
+}
+
+type Event struct {
+	Frequency rtlfm.Frequency
+	// Open is true when squelch opened, false when it closed.
+	Open bool
+	Time time.Time
+}
+
+// Scanner retunes a tuner to each frequency in turn and stays on it while
+// squelch is open. Demodulated audio of active channels is available
+// through Read as signed 16-bit little endian PCM, muted while squelch is
+// closed.
+type Scanner struct {
+	tuner  Tuner
+	d      *demod.Demodulator
+	freqs  []rtlfm.Frequency
+	opts   scanOptions
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	events chan Event
+	data   chan []byte
+	// buffers consumed by Read, reused by write
+	free chan []byte
+	buf  []byte
+	// the buffer being consumed by Read
+	cur []byte
+
+	mu  sync.Mutex
+	err error
+}
+
+// Scan starts scanning freqs with t, demodulating by d. Durations are
+// measured in samples read from t, so they follow the clock of the tuner.
+func Scan(ctx context.Context, t Tuner, d *demod.Demodulator, freqs []rtlfm.Frequency, opts ...Option) (*Scanner, error) {
+	if len(freqs) == 0 {
+		return nil, fmt.Errorf("%w: no frequency to scan", ErrInvalidOption)
+	}
+
+	options := scanOptions{
+		dwell:  defaultDwell,
+		hang:   defaultHang,
+		settle: defaultSettle,
+	}
+	for _, opt := range opts {
+		opt.apply(&options)
+	}
+	if options.squelch <= 0 {
+		return nil, fmt.Errorf("%w: squelch level is required to scan", ErrInvalidOption)
+	}
+	if options.dwell < 0 || options.hang < 0 || options.settle < 0 {
+		return nil, fmt.Errorf("%w: scan durations must be >= 0", ErrInvalidOption)
+	}
+	if options.dwell == 0 {
+		options.dwell = defaultDwell
+	}
+	if options.hang == 0 {
+		options.hang = defaultHang
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	s := &Scanner{
+		tuner:  t,
+		d:      d,
+		freqs:  freqs,
+		opts:   options,
+		cancel: cancel,
+		events: make(chan Event, 64),
+		data:   make(chan []byte, dataDepth),
+		free:   make(chan []byte, dataDepth+2),
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer close(s.data)
+		defer close(s.events)
+
+		err := s.run(ctx)
+		if err != nil && !errors.Is(err, context.Canceled) {
+			s.mu.Lock()
+			s.err = err
+			s.mu.Unlock()
+		}
+	}()
+
+	return s, nil
+}
+
+// Events returns a channel that receives squelch events. Events are dropped
+// if the channel is not drained.
+func (s *Scanner) Events() <-chan Event {
+	return s.events
+}
+
+func (s *Scanner) Read(b []byte) (int, error) {
+	if len(s.buf) == 0 {
+		if s.cur != nil {
+			select {
+			case s.free <- s.cur[:0]:
+			default:
+			}
+			s.cur = nil
+		}
+
+		data, ok := <-s.data
+		if !ok {
+			s.mu.Lock()
+			err := s.err
+			s.mu.Unlock()
+			if err != nil {
+				return 0, err
+			}
+			return 0, io.EOF
+		}
+		s.buf = data
+		s.cur = data
+	}
+
+	n := copy(b, s.buf)
+	s.buf = s.buf[n:]
+	return n, nil
+}
+
+// Close stops scanning and closes the tuner.
+func (s *Scanner) Close() error {
+	s.cancel()
+	// unblock Read of the tuner
+	err := s.tuner.Close()
+	// drain audio so that the scanning goroutine is not blocked
+	go func() {
+		for range s.data {
+		}
+	}()
+	s.wg.Wait()
+	return err
+}
+
+func (s *Scanner) run(ctx context.Context) error {
+	samples := func(d time.Duration) int64 {
+		return int64(d.Seconds() * float64(s.d.InputRate()))
+	}
+	dwell, hang, settle := samples(s.opts.dwell), samples(s.opts.hang), samples(s.opts.settle)
+
+	i := 0
+	if err := s.tune(i); err != nil {
+		return err
+	}
+	// samples since tuned, and until the frequency is left
+	var elapsed int64
+	deadline := settle + dwell
+	open := false
+
+	iq := make([]byte, readSize)
+	var pcm []int16
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		n, err := s.tuner.Read(iq)
+		if n > 0 {
+			// samples are demodulated while settling to keep the state
+			// of filters
+			pcm = s.d.Demodulate(iq[:n], pcm[:0])
+			elapsed += int64(n / 2)
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("failed to read IQ samples while scanning %s: %w", s.freqs[i], err)
+		}
+		if n == 0 || elapsed <= settle {
+			continue
+		}
+
+		active := s.d.Level() >= s.opts.squelch
+		if active {
+			if !open {
+				open = true
+				s.sendEvent(s.freqs[i], true)
+			}
+			deadline = elapsed + hang
+		}
+		if open {
+			if !active {
+				// mute noise while waiting for the channel to come back
+				for j := range pcm {
+					pcm[j] = 0
+				}
+			}
+			if err := s.write(ctx, pcm); err != nil {
+				return err
+			}
+		}
+
+		if elapsed < deadline {
+			continue
+		}
+		if open {
+			open = false
+			s.sendEvent(s.freqs[i], false)
+		}
+		if len(s.freqs) == 1 {
+			deadline = elapsed + dwell
+			continue
+		}
+
+		i = (i + 1) % len(s.freqs)
+		if err := s.tune(i); err != nil {
+			return err
+		}
+		elapsed = 0
+		deadline = settle + dwell
+	}
+}
+
+func (s *Scanner) tune(i int) error {
+	if err := s.tuner.SetFrequency(s.freqs[i]); err != nil {
+		return fmt.Errorf("failed to tune to %s: %w", s.freqs[i], err)
+	}
+	return nil
+}
+
+func (s *Scanner) write(ctx context.Context, pcm []int16) error {
+	var b []byte
+	select {
+	case b = <-s.free:
+	default:
+	}
+	for _, v := range pcm {
+		b = append(b, byte(v), byte(uint16(v)>>8))
+	}
+
+	select {
+	case s.data <- b:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *Scanner) sendEvent(freq rtlfm.Frequency, open bool) {
+	select {
+	case s.events <- Event{
+		Frequency: freq,
+		Open:      open,
+		Time:      time.Now(),
+	}:
+	default:
+	}
+}
+
+type scanOptions struct {
+	squelch float64
+	dwell   time.Duration
+	hang    time.Duration
+	settle  time.Duration
+}
+
+type Option interface {
+	apply(opts *scanOptions)
+}
+
+type optionFunc func(opts *scanOptions)
+
+func (f optionFunc) apply(opts *scanOptions) {
+	f(opts)
+}
+
+// WithSquelch sets the level that opens squelch, compared with
+// demod.Demodulator.Level of the channel, not with the level of rtl_fm.
+func WithSquelch(level float64) Option {
+	return optionFunc(func(opts *scanOptions) {
+		opts.squelch = level
+	})
+}
+
+// WithDwell sets how long the scanner listens on a frequency waiting for
+// squelch to open.
+func WithDwell(d time.Duration) Option {
+	return optionFunc(func(opts *scanOptions) {
+		opts.dwell = d
+	})
+}
+
+// WithHangTime sets how long the scanner stays on a frequency after squelch
+// closed.
+func WithHangTime(d time.Duration) Option {
+	return optionFunc(func(opts *scanOptions) {
+		opts.hang = d
+	})
+}
+
+// WithSettleTime sets how long samples are discarded after retuning, while
+// samples of the previous frequency are still arriving from the tuner.
+func WithSettleTime(d time.Duration) Option {
+	return optionFunc(func(opts *scanOptions) {
+		opts.settle = d
+	})
+}
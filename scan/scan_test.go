@@ -0,0 +1,296 @@
+package scan
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"math/rand"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kechako/goradio/demod"
+	"github.com/kechako/goradio/rtlfm"
+)
+
+const testInputRate = 240000
+
+// fakeTuner generates an FM carrier on the frequencies active and noise on
+// the others, as fast as it is read.
+type fakeTuner struct {
+	// active reports whether freq is on the air after tuned for d.
+	active func(freq rtlfm.Frequency, d time.Duration) bool
+
+	mu      sync.Mutex
+	freq    rtlfm.Frequency
+	tunedAt int64
+	total   int64
+	phase   float64
+	tunes   []rtlfm.Frequency
+	closed  bool
+	rnd     *rand.Rand
+}
+
+func newFakeTuner(active func(freq rtlfm.Frequency, d time.Duration) bool) *fakeTuner {
+	return &fakeTuner{
+		active: active,
+		rnd:    rand.New(rand.NewSource(1)),
+	}
+}
+
+func (t *fakeTuner) SetFrequency(freq rtlfm.Frequency) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.freq = freq
+	t.tunedAt = t.total
+	t.tunes = append(t.tunes, freq)
+	return nil
+}
+
+func (t *fakeTuner) Read(b []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.closed {
+		return 0, io.ErrClosedPipe
+	}
+
+	n := len(b) &^ 1
+	for i := 0; i < n; i += 2 {
+		d := time.Duration(t.total-t.tunedAt) * time.Second / testInputRate
+		if t.active(t.freq, d) {
+			// 1kHz tone with 2.5kHz deviation
+			t.phase += 2 * math.Pi * 2500 * math.Cos(2*math.Pi*1000*float64(t.total)/testInputRate) / testInputRate
+			b[i] = byte(127.5 + 100*math.Cos(t.phase))
+			b[i+1] = byte(127.5 + 100*math.Sin(t.phase))
+		} else {
+			b[i] = byte(127 + t.rnd.Intn(2))
+			b[i+1] = byte(127 + t.rnd.Intn(2))
+		}
+		t.total++
+	}
+	return n, nil
+}
+
+func (t *fakeTuner) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.closed = true
+	return nil
+}
+
+// elapsed returns the duration of the samples read.
+func (t *fakeTuner) elapsed() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return time.Duration(t.total) * time.Second / testInputRate
+}
+
+func (t *fakeTuner) tunings() []rtlfm.Frequency {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return append([]rtlfm.Frequency(nil), t.tunes...)
+}
+
+func startScan(t *testing.T, tuner *fakeTuner, freqs []rtlfm.Frequency) *Scanner {
+	t.Helper()
+
+	d, err := demod.New(rtlfm.FM, demod.WithInputRate(testInputRate))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := Scan(context.Background(), tuner, d, freqs,
+		WithSquelch(100),
+		WithDwell(50*time.Millisecond),
+		WithHangTime(100*time.Millisecond),
+		WithSettleTime(10*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		s.Close()
+	})
+	return s
+}
+
+func nextEvent(t *testing.T, s *Scanner) Event {
+	t.Helper()
+
+	select {
+	case ev, ok := <-s.Events():
+		if !ok {
+			t.Fatal("events are closed")
+		}
+		return ev
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for an event")
+	}
+	return Event{}
+}
+
+const (
+	freqA = 145500 * rtlfm.KiloHertz
+	freqB = 145600 * rtlfm.KiloHertz
+	freqC = 145700 * rtlfm.KiloHertz
+)
+
+func TestScanStaysOnActiveChannel(t *testing.T) {
+	tuner := newFakeTuner(func(freq rtlfm.Frequency, d time.Duration) bool {
+		return freq == freqB
+	})
+	s := startScan(t, tuner, []rtlfm.Frequency{freqA, freqB, freqC})
+
+	if ev := nextEvent(t, s); ev.Frequency != freqB || !ev.Open {
+		t.Fatalf("event = %+v, want %s opened", ev, freqB)
+	}
+
+	// a second of audio of the active channel
+	pcm := make([]byte, 2*rtlfm.FM.DefaultOutputRate())
+	if _, err := io.ReadFull(s, pcm); err != nil {
+		t.Fatal(err)
+	}
+	var peak int16
+	for i := 0; i+1 < len(pcm); i += 2 {
+		if v := int16(uint16(pcm[i]) | uint16(pcm[i+1])<<8); v > peak {
+			peak = v
+		}
+	}
+	if peak < 8192 {
+		t.Errorf("peak of audio = %d, want the tone", peak)
+	}
+
+	want := []rtlfm.Frequency{freqA, freqB}
+	if got := tuner.tunings(); !reflect.DeepEqual(got, want) {
+		t.Errorf("tuned to %v, want %v", got, want)
+	}
+}
+
+func TestScanResumesAfterHang(t *testing.T) {
+	// B transmits for 200ms whenever it is tuned
+	tuner := newFakeTuner(func(freq rtlfm.Frequency, d time.Duration) bool {
+		return freq == freqB && d < 200*time.Millisecond
+	})
+	s := startScan(t, tuner, []rtlfm.Frequency{freqA, freqB, freqC})
+	go io.Copy(io.Discard, s)
+
+	want := []Event{
+		{Frequency: freqB, Open: true},
+		{Frequency: freqB, Open: false},
+		{Frequency: freqB, Open: true},
+	}
+	for i, w := range want {
+		ev := nextEvent(t, s)
+		if ev.Frequency != w.Frequency || ev.Open != w.Open {
+			t.Fatalf("event %d = %+v, want %+v", i, ev, w)
+		}
+	}
+
+	// the tuner is retuned, never reopened
+	tunes := tuner.tunings()
+	wantTunes := []rtlfm.Frequency{freqA, freqB, freqC, freqA, freqB}
+	if len(tunes) < len(wantTunes) || !reflect.DeepEqual(tunes[:len(wantTunes)], wantTunes) {
+		t.Errorf("tuned to %v, want %v", tunes, wantTunes)
+	}
+}
+
+func TestScanSingleFrequency(t *testing.T) {
+	tuner := newFakeTuner(func(freq rtlfm.Frequency, d time.Duration) bool {
+		return false
+	})
+	startScan(t, tuner, []rtlfm.Frequency{freqA})
+
+	deadline := time.Now().Add(5 * time.Second)
+	for tuner.elapsed() < time.Second {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for samples to be read")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	want := []rtlfm.Frequency{freqA}
+	if got := tuner.tunings(); !reflect.DeepEqual(got, want) {
+		t.Errorf("tuned to %v, want %v", got, want)
+	}
+}
+
+func TestScanInvalidOptions(t *testing.T) {
+	tests := []struct {
+		name  string
+		freqs []rtlfm.Frequency
+		opts  []Option
+	}{
+		{"no frequency", nil, []Option{WithSquelch(100)}},
+		{"no squelch", []rtlfm.Frequency{freqA}, nil},
+		{"negative dwell", []rtlfm.Frequency{freqA}, []Option{WithSquelch(100), WithDwell(-time.Second)}},
+		{"negative hang", []rtlfm.Frequency{freqA}, []Option{WithSquelch(100), WithHangTime(-time.Second)}},
+		{"negative settle", []rtlfm.Frequency{freqA}, []Option{WithSquelch(100), WithSettleTime(-time.Second)}},
+	}
+	for _, tt := range tests {
+		d, err := demod.New(rtlfm.FM, demod.WithInputRate(testInputRate))
+		if err != nil {
+			t.Fatal(err)
+		}
+		tuner := newFakeTuner(func(freq rtlfm.Frequency, d time.Duration) bool { return false })
+		_, err = Scan(context.Background(), tuner, d, tt.freqs, tt.opts...)
+		if !errors.Is(err, ErrInvalidOption) {
+			t.Errorf("%s: Scan() error = %v, want %v", tt.name, err, ErrInvalidOption)
+		}
+	}
+}
+
+func TestScanTunerError(t *testing.T) {
+	tuner := newFakeTuner(func(freq rtlfm.Frequency, d time.Duration) bool { return false })
+	tuner.Close()
+	s := startScan(t, tuner, []rtlfm.Frequency{freqA})
+
+	if _, err := io.ReadAll(s); !errors.Is(err, io.ErrClosedPipe) {
+		t.Errorf("Read() error = %v, want %v", err, io.ErrClosedPipe)
+	}
+}
+
+func TestScannerReusesBuffers(t *testing.T) {
+	s := &Scanner{
+		data: make(chan []byte, dataDepth),
+		free: make(chan []byte, dataDepth+2),
+	}
+	ctx := context.Background()
+
+	if err := s.write(ctx, []int16{1, -2}); err != nil {
+		t.Fatal(err)
+	}
+	b := make([]byte, 4)
+	if n, err := s.Read(b); n != 4 || err != nil {
+		t.Fatalf("Read() = %d, %v, want 4, nil", n, err)
+	}
+	if want := []byte{1, 0, 0xfe, 0xff}; !reflect.DeepEqual(b, want) {
+		t.Errorf("Read() = % x, want % x", b, want)
+	}
+
+	// the buffer consumed is returned by the next Read
+	if err := s.write(ctx, []int16{3}); err != nil {
+		t.Fatal(err)
+	}
+	first := s.cur
+	if n, err := s.Read(b); n != 2 || err != nil {
+		t.Fatalf("Read() = %d, %v, want 2, nil", n, err)
+	}
+	if err := s.write(ctx, []int16{4}); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := s.Read(b); n != 2 || err != nil {
+		t.Fatalf("Read() = %d, %v, want 2, nil", n, err)
+	}
+	if &s.cur[0] != &first[0] {
+		t.Error("buffer is not reused")
+	}
+	if b[0] != 4 {
+		t.Errorf("Read() = % x, want 04 00", b[:2])
+	}
+}
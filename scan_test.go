@@ -0,0 +1,56 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	cli "github.com/urfave/cli/v2"
+)
+
+func TestScanUnsupportedFlags(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(configPath, []byte(`{"defaults": {"edge": true}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"dongle", []string{"--dongle", "0"}, "--dongle is not supported by scan"},
+		{"edge", []string{"--edge"}, "--edge is not supported by scan"},
+		// flags set by the config are shared with other commands
+		{"config", nil, "scanning needs an rtl_tcp source (rtltcp[:HOST:PORT])"},
+	}
+	for _, tt := range tests {
+		app := &cli.App{
+			Name: "goradio",
+			Commands: []*cli.Command{
+				{
+					Name:   "scan",
+					Action: scanRadioCommand,
+					Flags: concatFlags([]cli.Flag{
+						&cli.StringSliceFlag{Name: "freq", Aliases: []string{"f"}},
+						&cli.StringFlag{Name: "source", Value: "rtltcp"},
+						&cli.DurationFlag{Name: "dwell"},
+						&cli.DurationFlag{Name: "hang"},
+					}, tuningFlags()),
+				},
+			},
+			Flags:  configFlags(),
+			Before: loadConfig,
+		}
+		setupCommands(app.Commands)
+
+		args := append([]string{"goradio", "--config", configPath, "scan", "-f", "145.5M", "-l", "50", "--source", "wav:x"}, tt.args...)
+		err := app.Run(args)
+		var argErr ArgumentError
+		if !errors.As(err, &argErr) || err.Error() != tt.want {
+			t.Errorf("%s: scan error = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
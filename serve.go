@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/kechako/goradio/resample"
+	"github.com/kechako/goradio/source"
+	"github.com/kechako/goradio/stream"
+	cli "github.com/urfave/cli/v2"
+)
+
+func serveRadioCommand(ctx *cli.Context) error {
+	sampleRate := ctx.Int("sample-rate")
+	if sampleRate < 0 {
+		return ArgumentError("invalid sample rate")
+	}
+	quality, err := resampleQuality(ctx)
+	if err != nil {
+		return err
+	}
+	name, err := streamName(ctx)
+	if err != nil {
+		return err
+	}
+
+	l, err := net.Listen("tcp", ctx.String("listen"))
+	if err != nil {
+		return fmt.Errorf("failed to listen: %w", err)
+	}
+	defer l.Close()
+
+	src, err := openSource(ctx, 0, true)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	go printEvents(src.Events())
+
+	if sampleRate > 0 {
+		src, err = source.Resample(src, sampleRate, resample.WithQuality(quality))
+		if err != nil {
+			return err
+		}
+	}
+
+	pctx, cancel := context.WithCancel(ctx.Context)
+	defer cancel()
+
+	b := stream.NewBroadcaster()
+	defer b.Close()
+
+	var icecastErr <-chan error
+	if ctx.String("icecast") != "" {
+		icecastErr, err = pushBroadcast(pctx, ctx, b, src.SampleRate(), name, cancel)
+		if err != nil {
+			return err
+		}
+	}
+
+	opts := []stream.Option{
+		stream.WithName(name),
+		stream.WithClientBuffer(ctx.Duration("client-buffer")),
+	}
+	mux := http.NewServeMux()
+	pcm := stream.NewHandler(b, src.SampleRate(), opts...)
+	mux.Handle("/", pcm)
+	mux.Handle("/stream.wav", pcm)
+	mux.Handle("/stream-ulaw.wav", stream.NewHandler(b, src.SampleRate(), append(opts, stream.WithCodec(stream.MuLaw))...))
+	mux.Handle("/stream-adpcm.wav", stream.NewHandler(b, src.SampleRate(), append(opts, stream.WithCodec(stream.IMAADPCM))...))
+
+	srv := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	srvErr := make(chan error, 1)
+	go func() {
+		srvErr <- srv.Serve(l)
+	}()
+
+	fmt.Fprintf(os.Stderr, "streaming %s on http://%s/\n", name, l.Addr())
+
+	go func() {
+		// stop the receiver if the server fails
+		if err := <-srvErr; err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fmt.Fprintf(os.Stderr, "server error: %v\n", err)
+		}
+		cancel()
+	}()
+
+	frameSize := src.SampleRate() * 10 / 1000
+	err = runPipeline(pctx, src, src, frameSize, b.Write)
+
+	// streams never end gracefully, close connections of clients
+	b.Close()
+	srv.Close()
+
+	if icecastErr != nil {
+		if cerr := <-icecastErr; cerr != nil {
+			return cerr
+		}
+	}
+	return err
+}
+
+// streamName returns the name of the station specified by --station, or the
+// frequency.
+func streamName(ctx *cli.Context) (string, error) {
+	if name := ctx.String("station"); name != "" {
+		st, err := findStation(ctx, name)
+		if err != nil {
+			return "", err
+		}
+		return st.Name, nil
+	}
+	if ctx.String("freq") != "" {
+		freq, err := frequency(ctx)
+		if err != nil {
+			return "", err
+		}
+		return freq.String(), nil
+	}
+	return "goradio", nil
+}
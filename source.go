@@ -0,0 +1,220 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kechako/goradio/demod"
+	"github.com/kechako/goradio/player"
+	"github.com/kechako/goradio/rtlfm"
+	"github.com/kechako/goradio/rtltcp"
+	"github.com/kechako/goradio/source"
+	cli "github.com/urfave/cli/v2"
+)
+
+const (
+	defaultSourceSampleRate = 48000
+	defaultToneFrequency    = 1000
+	deEmphasisTau           = 50 * time.Microsecond
+)
+
+func sourceFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:     "source",
+			Usage:    "audio source (rtlfm, rtltcp[:HOST:PORT], wav:PATH, stdin, tone[:FREQ])",
+			Value:    "rtlfm",
+			Required: false,
+		},
+	}
+}
+
+// openSource opens the audio source specified by --source. sampleRate is
+// the preferred sample rate, the source may use its own rate.
+func openSource(ctx *cli.Context, sampleRate int, supervise bool) (source.Source, error) {
+	var t player.Tuning
+	if radioSource(ctx) {
+		var err error
+		t, err = tuning(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return openTunedSource(ctx, t, sampleRate, supervise)
+}
+
+// openTunedSource opens the audio source specified by --source tuned to t.
+// Tuning is ignored by sources other than radios.
+func openTunedSource(ctx *cli.Context, t player.Tuning, sampleRate int, supervise bool) (source.Source, error) {
+	kind, arg, _ := strings.Cut(ctx.String("source"), ":")
+
+	switch kind {
+	case "rtlfm":
+		return openRTLFMSource(ctx, t, sampleRate, supervise)
+	case "rtltcp":
+		addr := arg
+		if addr == "" {
+			addr = rtltcp.DefaultAddress
+		}
+		return openRTLTCPSource(ctx, addr, t, sampleRate)
+	case "wav":
+		if arg == "" {
+			return nil, ArgumentError("wav file is not specified")
+		}
+		return source.OpenWAV(arg)
+	case "stdin":
+		if sampleRate == 0 {
+			sampleRate = defaultSourceSampleRate
+		}
+		return source.Stdin(sampleRate), nil
+	case "tone":
+		freq := float64(defaultToneFrequency)
+		if arg != "" {
+			f, err := strconv.ParseFloat(arg, 64)
+			if err != nil || f <= 0 {
+				return nil, ArgumentError("invalid tone frequency")
+			}
+			freq = f
+		}
+		if sampleRate == 0 {
+			sampleRate = defaultSourceSampleRate
+		}
+		return source.NewTone(freq, 0.5, sampleRate), nil
+	default:
+		return nil, ArgumentError("invalid source")
+	}
+}
+
+// liveSource reports whether the source specified by --source produces
+// samples on its own clock.
+func liveSource(ctx *cli.Context) bool {
+	return radioSource(ctx)
+}
+
+// radioSource reports whether the source specified by --source is tuned
+// by frequency.
+func radioSource(ctx *cli.Context) bool {
+	kind, _, _ := strings.Cut(ctx.String("source"), ":")
+	return kind == "rtlfm" || kind == "rtltcp"
+}
+
+func frequency(ctx *cli.Context) (rtlfm.Frequency, error) {
+	sfreq := ctx.String("freq")
+	if sfreq == "" {
+		return 0, ArgumentError("frequency is not specified")
+	}
+	freq, err := rtlfm.ParseFrequency(sfreq)
+	if err != nil {
+		return 0, ArgumentError("invalid frequency")
+	}
+	return freq, nil
+}
+
+func openRTLFMSource(ctx *cli.Context, t player.Tuning, sampleRate int, supervise bool) (source.Source, error) {
+	opts, err := rtlfmOptions(ctx, t)
+	if err != nil {
+		return nil, err
+	}
+	if sampleRate == 0 {
+		sampleRate = t.Modulation.DefaultOutputRate()
+	}
+	opts = append(opts, rtlfm.WithSampleRate(sampleRate))
+
+	var p source.Process
+	if supervise {
+		opts = append(opts, rtlfm.WithMaxRestarts(ctx.Int("max-restarts")))
+		p, err = rtlfm.Supervise(ctx.Context, t.Frequency, opts...)
+	} else {
+		p, err = rtlfm.Play(ctx.Context, t.Frequency, opts...)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to play radio: %w", err)
+	}
+
+	return source.FromProcess(p, sampleRate), nil
+}
+
+func openRTLTCPSource(ctx *cli.Context, addr string, t player.Tuning, sampleRate int) (source.Source, error) {
+	d, err := newDemodulator(ctx, t, sampleRate)
+	if err != nil {
+		return nil, err
+	}
+	c, err := dialRTLTCP(ctx, addr, t, d.InputRate())
+	if err != nil {
+		return nil, err
+	}
+
+	return source.NewIQ(c, d), nil
+}
+
+// newDemodulator returns the demodulator of t for rtl_tcp. sampleRate is
+// the preferred output rate, 0 means the default rate of the modulation.
+func newDemodulator(ctx *cli.Context, t player.Tuning, sampleRate int) (*demod.Demodulator, error) {
+	s := sessionSettings(ctx, t)
+	opts := []demod.Option{
+		demod.WithOutputRate(sampleRate),
+	}
+	if s.deemp {
+		opts = append(opts, demod.WithDeEmphasis(deEmphasisTau))
+	}
+	if s.dc {
+		opts = append(opts, demod.EnableDCBlockingFilter())
+	}
+	d, err := demod.New(t.Modulation, opts...)
+	if err != nil {
+		return nil, ArgumentError(fmt.Sprintf("%s is not supported by rtl_tcp source", t.Modulation))
+	}
+	return d, nil
+}
+
+// dialRTLTCP connects to the rtl_tcp server at addr and tunes it to t,
+// sampling at inputRate.
+func dialRTLTCP(ctx *cli.Context, addr string, t player.Tuning, inputRate int) (*rtltcp.Client, error) {
+	c, err := rtltcp.Dial(ctx.Context, addr)
+	if err != nil {
+		return nil, err
+	}
+	err = func() error {
+		if err := c.SetSampleRate(inputRate); err != nil {
+			return err
+		}
+		if err := c.SetFrequency(t.Frequency); err != nil {
+			return err
+		}
+		if t.Gain == nil {
+			if err := c.SetAutoGain(); err != nil {
+				return err
+			}
+		} else {
+			if err := c.SetGain(*t.Gain); err != nil {
+				return err
+			}
+		}
+		s := sessionSettings(ctx, t)
+		if s.ppm != 0 {
+			if err := c.SetPPMCorrection(s.ppm); err != nil {
+				return err
+			}
+		}
+		// sampling from the I branch as rtl_fm -E direct
+		if s.direct {
+			if err := c.SetDirectSampling(1); err != nil {
+				return err
+			}
+		}
+		if s.offset {
+			if err := c.SetOffsetTuning(true); err != nil {
+				return err
+			}
+		}
+		return nil
+	}()
+	if err != nil {
+		c.Close()
+		return nil, err
+	}
+
+	return c, nil
+}
@@ -0,0 +1,28 @@
+package source
+
+import (
+	"io"
+
+	"github.com/kechako/goradio/demod"
+	"github.com/kechako/goradio/rtlfm"
+)
+
+type iqSource struct {
+	rtlfm.FrameReader[int16]
+	rc         io.ReadCloser
+	sampleRate int
+}
+
+// NewIQ returns a source that demodulates unsigned 8-bit IQ samples read
+// from rc, e.g. *rtltcp.Client.
+func NewIQ(rc io.ReadCloser, d *demod.Demodulator) Source {
+	return &iqSource{
+		FrameReader: rtlfm.NewFrameReader[int16](demod.NewReader(rc, d)),
+		rc:          rc,
+		sampleRate:  d.OutputRate(),
+	}
+}
+
+func (s *iqSource) SampleRate() int            { return s.sampleRate }
+func (s *iqSource) Events() <-chan rtlfm.Event { return noEvents() }
+func (s *iqSource) Close() error               { return s.rc.Close() }
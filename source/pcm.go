@@ -0,0 +1,37 @@
+package source
+
+import (
+	"io"
+
+	"github.com/kechako/goradio/rtlfm"
+)
+
+type pcmSource struct {
+	rtlfm.FrameReader[int16]
+	r          io.Reader
+	sampleRate int
+}
+
+// NewPCM returns a source of raw signed 16-bit little endian mono PCM.
+func NewPCM(r io.Reader, sampleRate int) Source {
+	return &pcmSource{
+		FrameReader: rtlfm.NewFrameReader[int16](r),
+		r:           r,
+		sampleRate:  sampleRate,
+	}
+}
+
+func (s *pcmSource) SampleRate() int            { return s.sampleRate }
+func (s *pcmSource) Events() <-chan rtlfm.Event { return noEvents() }
+
+func (s *pcmSource) Close() error {
+	if c, ok := s.r.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// Stdin returns a source of raw PCM read from stdin.
+func Stdin(sampleRate int) Source {
+	return NewPCM(openStdin(), sampleRate)
+}
@@ -0,0 +1,33 @@
+package source
+
+import (
+	"io"
+
+	"github.com/kechako/goradio/rtlfm"
+)
+
+// Process is the output of rtl_fm, either *rtlfm.Process or
+// *rtlfm.Supervisor.
+type Process interface {
+	io.Reader
+	Events() <-chan rtlfm.Event
+	Close() error
+}
+
+type processSource struct {
+	rtlfm.FrameReader[int16]
+	p          Process
+	sampleRate int
+}
+
+func FromProcess(p Process, sampleRate int) Source {
+	return &processSource{
+		FrameReader: rtlfm.NewFrameReader[int16](p),
+		p:           p,
+		sampleRate:  sampleRate,
+	}
+}
+
+func (s *processSource) SampleRate() int            { return s.sampleRate }
+func (s *processSource) Events() <-chan rtlfm.Event { return s.p.Events() }
+func (s *processSource) Close() error               { return s.p.Close() }
@@ -0,0 +1,48 @@
+package source
+
+import (
+	"github.com/kechako/goradio/resample"
+)
+
+type resampledSource struct {
+	Source
+	r       *resample.Resampler[int16]
+	in      []int16
+	pending []int16
+}
+
+// Resample returns a source that converts the sample rate of src to
+// sampleRate. It returns src as is if the rates are the same.
+func Resample(src Source, sampleRate int, opts ...resample.Option) (Source, error) {
+	if src.SampleRate() == sampleRate {
+		return src, nil
+	}
+	r, err := resample.New[int16](src.SampleRate(), sampleRate, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &resampledSource{
+		Source: src,
+		r:      r,
+	}, nil
+}
+
+func (s *resampledSource) SampleRate() int { return s.r.OutputRate() }
+
+func (s *resampledSource) Read(frame []int16) error {
+	for len(s.pending) < len(frame) {
+		n := (len(frame)-len(s.pending))*s.r.InputRate()/s.r.OutputRate() + 1
+		if cap(s.in) < n {
+			s.in = make([]int16, n)
+		}
+		if err := s.Source.Read(s.in[:n]); err != nil {
+			return err
+		}
+		s.pending = s.r.Process(s.in[:n], s.pending)
+	}
+
+	copy(frame, s.pending)
+	s.pending = s.pending[:copy(s.pending, s.pending[len(frame):])]
+
+	return nil
+}
@@ -0,0 +1,21 @@
+package source
+
+import (
+	"github.com/kechako/goradio/rtlfm"
+)
+
+// Source produces mono 16-bit audio frames.
+type Source interface {
+	rtlfm.FrameReader[int16]
+	SampleRate() int
+	// Events returns a channel that receives status events. It is closed
+	// if the source has no events.
+	Events() <-chan rtlfm.Event
+	Close() error
+}
+
+func noEvents() <-chan rtlfm.Event {
+	ch := make(chan rtlfm.Event)
+	close(ch)
+	return ch
+}
@@ -0,0 +1,287 @@
+package source
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/kechako/goradio/wav"
+)
+
+// writeWAV writes a WAV file of format with data and returns its path.
+func writeWAV(t *testing.T, format wav.Format, data []byte) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "test.wav")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	w, err := wav.NewWriter(file, format)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func le16(samples ...int16) []byte {
+	b := make([]byte, 2*len(samples))
+	for i, s := range samples {
+		binary.LittleEndian.PutUint16(b[2*i:], uint16(s))
+	}
+	return b
+}
+
+func float32le(samples ...float32) []byte {
+	b := make([]byte, 4*len(samples))
+	for i, s := range samples {
+		binary.LittleEndian.PutUint32(b[4*i:], math.Float32bits(s))
+	}
+	return b
+}
+
+// readAll reads frames of size from src until io.EOF.
+func readAll(t *testing.T, src Source, size int) []int16 {
+	t.Helper()
+
+	var samples []int16
+	frame := make([]int16, size)
+	for {
+		err := src.Read(frame)
+		if errors.Is(err, io.EOF) {
+			return samples
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		samples = append(samples, frame...)
+	}
+}
+
+func TestWAV(t *testing.T) {
+	mulaw := []int16{0, 1000, -1000, 32767, -32768}
+
+	tests := []struct {
+		name   string
+		format wav.Format
+		data   []byte
+		want   []int16
+	}{
+		{
+			name:   "16-bit",
+			format: wav.Format{SampleRate: 8000, Channels: 1, BitsPerSample: 16},
+			data:   le16(0, 1000, -1000, 32767),
+			want:   []int16{0, 1000, -1000, 32767},
+		},
+		{
+			name:   "16-bit stereo mixed down",
+			format: wav.Format{SampleRate: 8000, Channels: 2, BitsPerSample: 16},
+			data:   le16(1000, 3000, -2000, 0, 32767, 32767, 100, -100),
+			want:   []int16{2000, -1000, 32767, 0},
+		},
+		{
+			name:   "8-bit unsigned",
+			format: wav.Format{SampleRate: 8000, Channels: 1, BitsPerSample: 8},
+			data:   []byte{128, 192, 64, 0},
+			want:   []int16{0, 16383, -16383, -32767},
+		},
+		{
+			name:   "8-bit padded",
+			format: wav.Format{SampleRate: 8000, Channels: 1, BitsPerSample: 8},
+			data:   []byte{255},
+			want:   []int16{32511, 0, 0, 0},
+		},
+		{
+			name:   "24-bit",
+			format: wav.Format{SampleRate: 8000, Channels: 1, BitsPerSample: 24},
+			data:   []byte{0x00, 0x00, 0x40, 0x00, 0x00, 0xc0, 0xff, 0xff, 0xff, 0, 0, 0},
+			want:   []int16{16383, -16383, 0, 0},
+		},
+		{
+			name:   "float",
+			format: wav.Format{SampleRate: 8000, Channels: 1, BitsPerSample: 32, Float: true},
+			data:   float32le(0.5, -0.5, 2, -2),
+			want:   []int16{16383, -16383, 32767, -32768},
+		},
+		{
+			name:   "mu-law",
+			format: wav.Format{SampleRate: 8000, Channels: 1, BitsPerSample: 8, MuLaw: true},
+			data:   wav.EncodeMuLaw(nil, mulaw),
+			// silence of mu-law is not zero
+			want: append(wav.DecodeMuLaw(nil, wav.EncodeMuLaw(nil, mulaw)), 0, 0, 0),
+		},
+		{
+			name:   "mu-law stereo",
+			format: wav.Format{SampleRate: 8000, Channels: 2, BitsPerSample: 8, MuLaw: true},
+			data:   wav.EncodeMuLaw(nil, []int16{1000, 1000, -5000, -5000, 0, 0, 300, 300}),
+			want:   wav.DecodeMuLaw(nil, wav.EncodeMuLaw(nil, []int16{1000, -5000, 0, 300})),
+		},
+	}
+	for _, tt := range tests {
+		src, err := OpenWAV(writeWAV(t, tt.format, tt.data))
+		if err != nil {
+			t.Errorf("%s: OpenWAV() = %v", tt.name, err)
+			continue
+		}
+		if got := src.SampleRate(); got != tt.format.SampleRate {
+			t.Errorf("%s: SampleRate() = %d, want %d", tt.name, got, tt.format.SampleRate)
+		}
+
+		// the last frame is padded with silence
+		got := readAll(t, src, 4)
+		if err := src.Close(); err != nil {
+			t.Errorf("%s: Close() = %v", tt.name, err)
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: read %v, want %v", tt.name, got, tt.want)
+			continue
+		}
+		for i := range got {
+			// rounding of the mix down
+			if d := int(got[i]) - int(tt.want[i]); d < -1 || d > 1 {
+				t.Errorf("%s: read %v, want %v", tt.name, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestOpenWAVInvalid(t *testing.T) {
+	if _, err := OpenWAV(filepath.Join(t.TempDir(), "none.wav")); err == nil {
+		t.Error("OpenWAV() of a missing file = nil, want an error")
+	}
+
+	path := filepath.Join(t.TempDir(), "text.wav")
+	if err := os.WriteFile(path, []byte("not a wav file"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenWAV(path); err == nil {
+		t.Error("OpenWAV() of a text file = nil, want an error")
+	}
+}
+
+type closeBuffer struct {
+	bytes.Buffer
+	closed bool
+}
+
+func (b *closeBuffer) Close() error {
+	b.closed = true
+	return nil
+}
+
+func TestPCM(t *testing.T) {
+	r := &closeBuffer{}
+	r.Write(le16(1, -2, 3, -4))
+	src := NewPCM(r, 16000)
+
+	if got := src.SampleRate(); got != 16000 {
+		t.Errorf("SampleRate() = %d, want 16000", got)
+	}
+	if got, want := readAll(t, src, 2), []int16{1, -2, 3, -4}; !reflect.DeepEqual(got, want) {
+		t.Errorf("read %v, want %v", got, want)
+	}
+	if _, ok := <-src.Events(); ok {
+		t.Error("Events() is not closed")
+	}
+	if err := src.Close(); err != nil || !r.closed {
+		t.Errorf("Close() = %v, reader closed %v, want nil, true", err, r.closed)
+	}
+}
+
+func TestTone(t *testing.T) {
+	const (
+		sampleRate = 8000
+		freq       = 1000
+	)
+	src := NewTone(freq, 0.5, sampleRate)
+
+	// a second of samples in frames not aligned to the period
+	var samples []int16
+	frame := make([]int16, 300)
+	for len(samples) < sampleRate {
+		if err := src.Read(frame); err != nil {
+			t.Fatal(err)
+		}
+		samples = append(samples, frame...)
+	}
+	samples = samples[:sampleRate]
+
+	var peak int16
+	crossings := 0
+	for i, v := range samples {
+		if v > peak {
+			peak = v
+		}
+		if i > 0 && samples[i-1] < 0 && v >= 0 {
+			crossings++
+		}
+	}
+	if want := int16(math.MaxInt16 / 2); peak < want-1 || peak > want {
+		t.Errorf("peak = %d, want %d", peak, want)
+	}
+	if crossings < freq-1 || crossings > freq {
+		t.Errorf("%d periods in a second, want %d", crossings, freq)
+	}
+}
+
+func TestResample(t *testing.T) {
+	src := NewTone(1000, 0.5, 48000)
+	same, err := Resample(src, 48000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if same != src {
+		t.Error("Resample() to the same rate does not return the source")
+	}
+
+	r, err := Resample(NewTone(1000, 0.5, 48000), 44100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := r.SampleRate(); got != 44100 {
+		t.Errorf("SampleRate() = %d, want 44100", got)
+	}
+
+	// frames of any size are filled
+	var samples []int16
+	for _, size := range []int{441, 1, 1000, 4410} {
+		frame := make([]int16, size)
+		if err := r.Read(frame); err != nil {
+			t.Fatal(err)
+		}
+		samples = append(samples, frame...)
+	}
+	var peak int16
+	for _, v := range samples[len(samples)/2:] {
+		if v > peak {
+			peak = v
+		}
+	}
+	if want := math.MaxInt16 / 2; int(peak) < want*9/10 || int(peak) > want*11/10 {
+		t.Errorf("peak of resampled tone = %d, want about %d", peak, want)
+	}
+
+	// errors of the source are returned
+	pcm, err := Resample(NewPCM(bytes.NewReader(le16(1, 2)), 8000), 16000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := readAll(t, pcm, 100); len(got) != 100 {
+		t.Errorf("read %d samples before EOF, want 100", len(got))
+	}
+}
@@ -0,0 +1,22 @@
+//go:build !windows
+
+package source
+
+import (
+	"os"
+	"syscall"
+)
+
+// openStdin returns stdin that can be closed to unblock a pending read.
+func openStdin() *os.File {
+	fi, err := os.Stdin.Stat()
+	if err != nil || fi.Mode()&os.ModeCharDevice != 0 {
+		// leave terminal as it is
+		return os.Stdin
+	}
+	// os.NewFile uses the runtime poller for non-blocking file
+	if err := syscall.SetNonblock(syscall.Stdin, true); err != nil {
+		return os.Stdin
+	}
+	return os.NewFile(uintptr(syscall.Stdin), "/dev/stdin")
+}
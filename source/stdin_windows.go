@@ -0,0 +1,7 @@
+package source
+
+import "os"
+
+func openStdin() *os.File {
+	return os.Stdin
+}
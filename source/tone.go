@@ -0,0 +1,39 @@
+package source
+
+import (
+	"math"
+
+	"github.com/kechako/goradio/rtlfm"
+)
+
+type toneSource struct {
+	step       float64
+	phase      float64
+	amplitude  float64
+	sampleRate int
+}
+
+// NewTone returns a source of a sine wave. amplitude is relative to full
+// scale.
+func NewTone(freq float64, amplitude float64, sampleRate int) Source {
+	return &toneSource{
+		step:       2 * math.Pi * freq / float64(sampleRate),
+		amplitude:  amplitude * math.MaxInt16,
+		sampleRate: sampleRate,
+	}
+}
+
+func (s *toneSource) Read(frame []int16) error {
+	for i := range frame {
+		frame[i] = int16(s.amplitude * math.Sin(s.phase))
+		s.phase += s.step
+		if s.phase >= 2*math.Pi {
+			s.phase -= 2 * math.Pi
+		}
+	}
+	return nil
+}
+
+func (s *toneSource) SampleRate() int            { return s.sampleRate }
+func (s *toneSource) Events() <-chan rtlfm.Event { return noEvents() }
+func (s *toneSource) Close() error               { return nil }
@@ -0,0 +1,138 @@
+package source
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+
+	"github.com/kechako/goradio/rtlfm"
+	"github.com/kechako/goradio/wav"
+)
+
+type wavSource struct {
+	file   *os.File
+	r      *wav.Reader
+	format wav.Format
+	buf    []byte
+	// samples decoded from mu-law
+	linear []int16
+	eof    bool
+}
+
+// OpenWAV opens a WAV file. Multi channel audio is mixed down to mono.
+func OpenWAV(path string) (Source, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open wav file: %w", err)
+	}
+
+	r, err := wav.NewReader(file)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return &wavSource{
+		file:   file,
+		r:      r,
+		format: r.Format(),
+	}, nil
+}
+
+func (s *wavSource) Read(frame []int16) error {
+	if s.eof {
+		return io.EOF
+	}
+
+	bytesPerSample := s.format.BitsPerSample / 8
+	blockAlign := bytesPerSample * s.format.Channels
+	size := blockAlign * len(frame)
+	if cap(s.buf) < size {
+		s.buf = make([]byte, size)
+	}
+	buf := s.buf[:size]
+
+	n, err := io.ReadFull(s.r, buf)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		if n == 0 {
+			return io.EOF
+		}
+		// pad the last frame with silence
+		s.eof = true
+		for i := n; i < size; i++ {
+			buf[i] = s.silence()
+		}
+	} else if err != nil {
+		return fmt.Errorf("failed to read wav file: %w", err)
+	}
+
+	if s.format.MuLaw {
+		s.linear = wav.DecodeMuLaw(s.linear[:0], buf)
+	}
+
+	for i := range frame {
+		var sum float64
+		for ch := 0; ch < s.format.Channels; ch++ {
+			if s.format.MuLaw {
+				sum += float64(s.linear[i*s.format.Channels+ch]) / 32768
+				continue
+			}
+			sum += s.sample(buf[i*blockAlign+ch*bytesPerSample:])
+		}
+		v := sum / float64(s.format.Channels) * math.MaxInt16
+		if v > math.MaxInt16 {
+			v = math.MaxInt16
+		} else if v < math.MinInt16 {
+			v = math.MinInt16
+		}
+		frame[i] = int16(v)
+	}
+
+	return nil
+}
+
+// silence returns the byte of silence, 8-bit PCM is unsigned.
+func (s *wavSource) silence() byte {
+	switch {
+	case s.format.MuLaw:
+		return 0xff
+	case s.format.BitsPerSample == 8 && !s.format.Float:
+		return 0x80
+	default:
+		return 0
+	}
+}
+
+// sample decodes a sample into [-1, 1].
+func (s *wavSource) sample(b []byte) float64 {
+	if s.format.Float {
+		if s.format.BitsPerSample == 64 {
+			return math.Float64frombits(binary.LittleEndian.Uint64(b))
+		}
+		return float64(math.Float32frombits(binary.LittleEndian.Uint32(b)))
+	}
+
+	switch s.format.BitsPerSample {
+	case 8:
+		return (float64(b[0]) - 128) / 128
+	case 16:
+		return float64(int16(binary.LittleEndian.Uint16(b))) / 32768
+	case 24:
+		v := int32(uint32(b[0])<<8|uint32(b[1])<<16|uint32(b[2])<<24) >> 8
+		return float64(v) / (1 << 23)
+	default:
+		return float64(int32(binary.LittleEndian.Uint32(b))) / (1 << 31)
+	}
+}
+
+func (s *wavSource) SampleRate() int            { return s.format.SampleRate }
+func (s *wavSource) Events() <-chan rtlfm.Event { return noEvents() }
+
+func (s *wavSource) Close() error {
+	if err := s.file.Close(); err != nil {
+		return fmt.Errorf("failed to close wav file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,373 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/kechako/goradio/rtlfm"
+	"github.com/kechako/goradio/station"
+	cli "github.com/urfave/cli/v2"
+)
+
+func stationFlags() []cli.Flag {
+	flags := []cli.Flag{
+		&cli.StringFlag{
+			Name:     "freq",
+			Aliases:  []string{"f"},
+			Usage:    "frequency of the station (e.g. 93.0M, 90500K)",
+			Required: false,
+		},
+	}
+	for _, flag := range tuningFlags() {
+		// dongle depends on the host, not on the station
+		if flag.Names()[0] == "dongle" {
+			continue
+		}
+		flags = append(flags, flag)
+	}
+	return append(flags, filterFlag())
+}
+
+func presetsPath(ctx *cli.Context) (string, error) {
+	if path := ctx.String("stations"); path != "" {
+		return path, nil
+	}
+	return station.DefaultPath()
+}
+
+func loadPresets(ctx *cli.Context) (*station.Presets, string, error) {
+	path, err := presetsPath(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	presets, err := station.Load(path)
+	if err != nil {
+		return nil, "", err
+	}
+	return presets, path, nil
+}
+
+func findStation(ctx *cli.Context, name string) (*station.Station, error) {
+	presets, _, err := loadPresets(ctx)
+	if err != nil {
+		return nil, err
+	}
+	st, err := presets.Find(name)
+	if errors.Is(err, station.ErrStationNotFound) {
+		return nil, ArgumentError(fmt.Sprintf("station %q is not found", name))
+	}
+	return st, err
+}
+
+// applyStation sets the flags from the station specified by --station.
+// Only the settings the station has are set, so that the others fall back
+// to the config. Flags given on the command line take precedence.
+//
+// The station is also kept as the preset of the initial tuning, see
+// stationPreset.
+func applyStation(ctx *cli.Context) error {
+	name := ctx.String("station")
+	if name == "" {
+		return nil
+	}
+
+	st, err := findStation(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	values := map[string]string{
+		"freq": st.Frequency.String(),
+	}
+	if st.Mode != 0 {
+		values["mode"] = st.Mode.String()
+	}
+	if st.Gain != nil {
+		values["gain"] = strconv.FormatFloat(*st.Gain, 'f', -1, 64)
+	}
+	if st.PPM != 0 {
+		values["ppm"] = strconv.Itoa(st.PPM)
+	}
+	if st.Squelch != 0 {
+		values["squelch"] = strconv.Itoa(st.Squelch)
+	}
+	for name, enabled := range map[string]*bool{
+		"edge":   st.Edge,
+		"dc":     st.DC,
+		"deemp":  st.DeEmphasis,
+		"direct": st.Direct,
+		"offset": st.Offset,
+	} {
+		if enabled != nil {
+			values[name] = strconv.FormatBool(*enabled)
+		}
+	}
+
+	for name, value := range values {
+		if onCommandLine(ctx, name) {
+			continue
+		}
+		if err := ctx.Set(name, value); err != nil {
+			return fmt.Errorf("failed to apply station: %w", err)
+		}
+	}
+
+	ctx.App.Metadata[stationMetadataKey] = withoutCommandLine(ctx, st)
+
+	return nil
+}
+
+// withoutCommandLine returns a copy of st without the settings given on
+// the command line.
+func withoutCommandLine(ctx *cli.Context, st *station.Station) *station.Station {
+	preset := *st
+	if onCommandLine(ctx, "mode") {
+		preset.Mode = 0
+	}
+	if onCommandLine(ctx, "gain") {
+		preset.Gain = nil
+	}
+	if onCommandLine(ctx, "ppm") {
+		preset.PPM = 0
+	}
+	if onCommandLine(ctx, "squelch") {
+		preset.Squelch = 0
+	}
+	for name, enabled := range map[string]**bool{
+		"edge":   &preset.Edge,
+		"dc":     &preset.DC,
+		"deemp":  &preset.DeEmphasis,
+		"direct": &preset.Direct,
+		"offset": &preset.Offset,
+	} {
+		if onCommandLine(ctx, name) {
+			*enabled = nil
+		}
+	}
+	if onCommandLine(ctx, "filter") {
+		preset.Filters = nil
+	}
+	return &preset
+}
+
+// stationPreset returns the station specified by --station without the
+// settings given on the command line, or nil.
+func stationPreset(ctx *cli.Context) *station.Station {
+	st, _ := ctx.App.Metadata[stationMetadataKey].(*station.Station)
+	return st
+}
+
+func boolFlag(ctx *cli.Context, name string) *bool {
+	v := ctx.Bool(name)
+	return &v
+}
+
+// updateStation updates st with the flags given on the command line. The
+// config values of the flags are not settings of the station.
+func updateStation(ctx *cli.Context, st *station.Station) error {
+	if onCommandLine(ctx, "freq") {
+		freq, err := frequency(ctx)
+		if err != nil {
+			return err
+		}
+		st.Frequency = freq
+	}
+	if onCommandLine(ctx, "mode") {
+		m, err := modulation(ctx)
+		if err != nil {
+			return err
+		}
+		st.Mode = m
+	}
+	if onCommandLine(ctx, "gain") {
+		gain, auto, err := rtlfm.ParseGain(ctx.String("gain"))
+		if err != nil {
+			return ArgumentError("invalid gain")
+		}
+		if auto {
+			st.Gain = nil
+		} else {
+			st.Gain = &gain
+		}
+	}
+	if onCommandLine(ctx, "ppm") {
+		st.PPM = ctx.Int("ppm")
+	}
+	if onCommandLine(ctx, "squelch") {
+		st.Squelch = ctx.Int("squelch")
+	}
+	if onCommandLine(ctx, "edge") {
+		st.Edge = boolFlag(ctx, "edge")
+	}
+	if onCommandLine(ctx, "dc") {
+		st.DC = boolFlag(ctx, "dc")
+	}
+	if onCommandLine(ctx, "deemp") {
+		st.DeEmphasis = boolFlag(ctx, "deemp")
+	}
+	if onCommandLine(ctx, "direct") {
+		st.Direct = boolFlag(ctx, "direct")
+	}
+	if onCommandLine(ctx, "offset") {
+		st.Offset = boolFlag(ctx, "offset")
+	}
+	if onCommandLine(ctx, "filter") {
+		filters, err := parseFilters(ctx.StringSlice("filter"))
+		if err != nil {
+			return err
+		}
+		st.Filters = filters
+	}
+
+	if err := st.Validate(); err != nil {
+		return ArgumentError(err.Error())
+	}
+	return nil
+}
+
+func stationAddCommand(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return ArgumentError("station name is not specified")
+	}
+	if !onCommandLine(ctx, "freq") {
+		return ArgumentError("frequency is not specified")
+	}
+
+	presets, path, err := loadPresets(ctx)
+	if err != nil {
+		return err
+	}
+
+	st := &station.Station{Name: ctx.Args().Get(0)}
+	if err := updateStation(ctx, st); err != nil {
+		return err
+	}
+	if err := presets.Add(st); err != nil {
+		if errors.Is(err, station.ErrStationExists) {
+			return ArgumentError(fmt.Sprintf("station %q already exists", st.Name))
+		}
+		return err
+	}
+
+	return presets.Save(path)
+}
+
+func stationListCommand(ctx *cli.Context) error {
+	if ctx.NArg() != 0 {
+		return ArgumentError("invalid argument")
+	}
+
+	presets, _, err := loadPresets(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, st := range presets.Stations {
+		fmt.Println(formatStation(st))
+	}
+
+	return nil
+}
+
+func formatStation(st *station.Station) string {
+	mode := st.Mode
+	if mode == 0 {
+		mode = rtlfm.WBFM
+	}
+	gain := "auto"
+	if st.Gain != nil {
+		gain = strconv.FormatFloat(*st.Gain, 'f', -1, 64) + "dB"
+	}
+
+	fields := []string{
+		st.Frequency.String(),
+		mode.String(),
+		"gain: " + gain,
+	}
+	if st.PPM != 0 {
+		fields = append(fields, fmt.Sprintf("ppm: %d", st.PPM))
+	}
+	if st.Squelch != 0 {
+		fields = append(fields, fmt.Sprintf("squelch: %d", st.Squelch))
+	}
+	for _, f := range []struct {
+		name    string
+		enabled *bool
+	}{
+		{"edge", st.Edge},
+		{"dc", st.DC},
+		{"deemp", st.DeEmphasis},
+		{"direct", st.Direct},
+		{"offset", st.Offset},
+	} {
+		switch {
+		case f.enabled == nil:
+		case *f.enabled:
+			fields = append(fields, f.name)
+		default:
+			fields = append(fields, "no "+f.name)
+		}
+	}
+	for _, f := range st.Filters {
+		fields = append(fields, "filter: "+f.String())
+	}
+
+	return fmt.Sprintf("%s [%s]", st.Name, strings.Join(fields, ", "))
+}
+
+func stationRemoveCommand(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return ArgumentError("station name is not specified")
+	}
+
+	presets, path, err := loadPresets(ctx)
+	if err != nil {
+		return err
+	}
+
+	name := ctx.Args().Get(0)
+	if err := presets.Remove(name); err != nil {
+		if errors.Is(err, station.ErrStationNotFound) {
+			return ArgumentError(fmt.Sprintf("station %q is not found", name))
+		}
+		return err
+	}
+
+	return presets.Save(path)
+}
+
+func stationEditCommand(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return ArgumentError("station name is not specified")
+	}
+
+	presets, path, err := loadPresets(ctx)
+	if err != nil {
+		return err
+	}
+
+	name := ctx.Args().Get(0)
+	st, err := presets.Find(name)
+	if err != nil {
+		if errors.Is(err, station.ErrStationNotFound) {
+			return ArgumentError(fmt.Sprintf("station %q is not found", name))
+		}
+		return err
+	}
+	if rename := ctx.String("name"); rename != "" && !strings.EqualFold(rename, st.Name) {
+		if _, err := presets.Find(rename); err == nil {
+			return ArgumentError(fmt.Sprintf("station %q already exists", rename))
+		}
+		st.Name = rename
+	} else if rename != "" {
+		st.Name = rename
+	}
+
+	if err := updateStation(ctx, st); err != nil {
+		return err
+	}
+
+	return presets.Save(path)
+}
@@ -0,0 +1,192 @@
+package station
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kechako/goradio/filter"
+	"github.com/kechako/goradio/rtlfm"
+)
+
+const fileName = "stations.json"
+
+var (
+	ErrStationNotFound = errors.New("station not found")
+	ErrStationExists   = errors.New("station already exists")
+)
+
+type Station struct {
+	Name      string           `json:"name"`
+	Frequency rtlfm.Frequency  `json:"frequency"`
+	Mode      rtlfm.Modulation `json:"mode,omitempty"`
+	Gain      *float64         `json:"gain,omitempty"`
+	PPM       int              `json:"ppm,omitempty"`
+	Squelch   int              `json:"squelch,omitempty"`
+	// The switches are nil if the station does not set them, false turns
+	// off the option enabled by a flag.
+	Edge       *bool `json:"edge,omitempty"`
+	DC         *bool `json:"dc,omitempty"`
+	DeEmphasis *bool `json:"deemp,omitempty"`
+	Direct     *bool `json:"direct,omitempty"`
+	Offset     *bool `json:"offset,omitempty"`
+	// Filters are applied to the audio instead of the default ones.
+	Filters []filter.Spec `json:"filters,omitempty"`
+}
+
+func (s *Station) Options() []rtlfm.Option {
+	var opts []rtlfm.Option
+	if s.Mode != 0 {
+		opts = append(opts, rtlfm.WithModulation(s.Mode))
+	}
+	if s.Gain != nil {
+		opts = append(opts, rtlfm.WithGain(*s.Gain))
+	}
+	if s.PPM != 0 {
+		opts = append(opts, rtlfm.WithPPMCorrection(s.PPM))
+	}
+	if s.Squelch != 0 {
+		opts = append(opts, rtlfm.WithSquelch(s.Squelch))
+	}
+	if enabled(s.Edge) {
+		opts = append(opts, rtlfm.EnableLowerEdgeTuning())
+	}
+	if enabled(s.DC) {
+		opts = append(opts, rtlfm.EnableDCBlockingFilter())
+	}
+	if enabled(s.DeEmphasis) {
+		opts = append(opts, rtlfm.EnableDeEmphasisFilter())
+	}
+	if enabled(s.Direct) {
+		opts = append(opts, rtlfm.EnableDirectSampling())
+	}
+	if enabled(s.Offset) {
+		opts = append(opts, rtlfm.EnableOffsetTuning())
+	}
+	return opts
+}
+
+func enabled(b *bool) bool {
+	return b != nil && *b
+}
+
+func (s *Station) Validate() error {
+	if strings.TrimSpace(s.Name) == "" {
+		return errors.New("station name is empty")
+	}
+	if s.Frequency <= 0 {
+		return errors.New("station frequency is not specified")
+	}
+	for _, f := range s.Filters {
+		if err := f.Validate(); err != nil {
+			return err
+		}
+	}
+	return rtlfm.ValidateOptions(s.Options()...)
+}
+
+type Presets struct {
+	Stations []*Station `json:"stations"`
+}
+
+// DefaultPath returns the path of the presets file under the user config
+// directory, e.g. $XDG_CONFIG_HOME/goradio/stations.json.
+func DefaultPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get config directory: %w", err)
+	}
+	return filepath.Join(dir, "goradio", fileName), nil
+}
+
+// Load loads presets from path. It returns empty presets if the file does
+// not exist.
+func Load(path string) (*Presets, error) {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &Presets{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read presets: %w", err)
+	}
+
+	var p Presets
+	if err := json.Unmarshal(b, &p); err != nil {
+		return nil, fmt.Errorf("failed to parse presets: %w", err)
+	}
+	return &p, nil
+}
+
+func (p *Presets) Save(path string) error {
+	b, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode presets: %w", err)
+	}
+	b = append(b, '\n')
+
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+
+	// write to a temporary file and rename it not to break presets
+	f, err := os.CreateTemp(dir, fileName+".*")
+	if err != nil {
+		return fmt.Errorf("failed to save presets: %w", err)
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to save presets: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to save presets: %w", err)
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		return fmt.Errorf("failed to save presets: %w", err)
+	}
+
+	return nil
+}
+
+// Find finds a station by name case-insensitively.
+func (p *Presets) Find(name string) (*Station, error) {
+	i := p.index(name)
+	if i < 0 {
+		return nil, ErrStationNotFound
+	}
+	return p.Stations[i], nil
+}
+
+func (p *Presets) Add(s *Station) error {
+	if err := s.Validate(); err != nil {
+		return err
+	}
+	if p.index(s.Name) >= 0 {
+		return ErrStationExists
+	}
+	p.Stations = append(p.Stations, s)
+	return nil
+}
+
+func (p *Presets) Remove(name string) error {
+	i := p.index(name)
+	if i < 0 {
+		return ErrStationNotFound
+	}
+	p.Stations = append(p.Stations[:i], p.Stations[i+1:]...)
+	return nil
+}
+
+func (p *Presets) index(name string) int {
+	for i, s := range p.Stations {
+		if strings.EqualFold(s.Name, name) {
+			return i
+		}
+	}
+	return -1
+}
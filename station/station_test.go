@@ -0,0 +1,171 @@
+package station
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/kechako/goradio/filter"
+	"github.com/kechako/goradio/rtlfm"
+)
+
+func gain(v float64) *float64 {
+	return &v
+}
+
+func TestStationValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		station Station
+		wantErr bool
+	}{
+		{"valid", Station{Name: "FM Tokyo", Frequency: 80 * rtlfm.MegaHertz}, false},
+		{"all settings", Station{
+			Name:      "Ham",
+			Frequency: 145 * rtlfm.MegaHertz,
+			Mode:      rtlfm.FM,
+			Gain:      gain(49.6),
+			PPM:       -3,
+			Squelch:   50,
+			Filters:   []filter.Spec{{Kind: filter.KindHighPass, Params: []float64{300}}},
+		}, false},
+		{"empty name", Station{Name: " ", Frequency: 80 * rtlfm.MegaHertz}, true},
+		{"no frequency", Station{Name: "FM Tokyo"}, true},
+		{"unsupported gain", Station{Name: "FM Tokyo", Frequency: 80 * rtlfm.MegaHertz, Gain: gain(1.23)}, true},
+		{"ppm out of range", Station{Name: "FM Tokyo", Frequency: 80 * rtlfm.MegaHertz, PPM: 5000}, true},
+		{"negative squelch", Station{Name: "FM Tokyo", Frequency: 80 * rtlfm.MegaHertz, Squelch: -1}, true},
+		{"invalid filter", Station{
+			Name:      "FM Tokyo",
+			Frequency: 80 * rtlfm.MegaHertz,
+			Filters:   []filter.Spec{{Kind: filter.KindLowPass}},
+		}, true},
+	}
+	for _, tt := range tests {
+		if err := tt.station.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("%s: Validate() = %v, want error %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func testPresets() *Presets {
+	return &Presets{
+		Stations: []*Station{
+			{Name: "FM Tokyo", Frequency: 80 * rtlfm.MegaHertz},
+			{Name: "J-WAVE", Frequency: 81300 * rtlfm.KiloHertz, Mode: rtlfm.WBFM},
+		},
+	}
+}
+
+func TestPresetsFind(t *testing.T) {
+	p := testPresets()
+	tests := []struct {
+		name    string
+		want    *Station
+		wantErr error
+	}{
+		{"FM Tokyo", p.Stations[0], nil},
+		{"fm tokyo", p.Stations[0], nil},
+		{"J-WAVE", p.Stations[1], nil},
+		{"j-wave", p.Stations[1], nil},
+		{"NHK", nil, ErrStationNotFound},
+		{"", nil, ErrStationNotFound},
+	}
+	for _, tt := range tests {
+		got, err := p.Find(tt.name)
+		if got != tt.want || !errors.Is(err, tt.wantErr) {
+			t.Errorf("Find(%q) = %v, %v, want %v, %v", tt.name, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestPresetsAdd(t *testing.T) {
+	tests := []struct {
+		name    string
+		station *Station
+		wantErr error
+	}{
+		{"new", &Station{Name: "NHK", Frequency: 82500 * rtlfm.KiloHertz}, nil},
+		{"exists", &Station{Name: "FM Tokyo", Frequency: 80 * rtlfm.MegaHertz}, ErrStationExists},
+		{"exists case-insensitively", &Station{Name: "j-wave", Frequency: 81300 * rtlfm.KiloHertz}, ErrStationExists},
+	}
+	for _, tt := range tests {
+		p := testPresets()
+		err := p.Add(tt.station)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: Add() = %v, want %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		n := 2
+		if tt.wantErr == nil {
+			n = 3
+		}
+		if len(p.Stations) != n {
+			t.Errorf("%s: %d stations after Add(), want %d", tt.name, len(p.Stations), n)
+		}
+	}
+
+	p := testPresets()
+	if err := p.Add(&Station{Name: "NHK"}); err == nil || len(p.Stations) != 2 {
+		t.Errorf("Add() of an invalid station = %v, %d stations", err, len(p.Stations))
+	}
+}
+
+func TestPresetsRemove(t *testing.T) {
+	p := testPresets()
+	if err := p.Remove("fm tokyo"); err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Stations) != 1 || p.Stations[0].Name != "J-WAVE" {
+		t.Errorf("stations after Remove() = %v", p.Stations)
+	}
+	if err := p.Remove("FM Tokyo"); !errors.Is(err, ErrStationNotFound) {
+		t.Errorf("Remove() of a removed station = %v, want %v", err, ErrStationNotFound)
+	}
+}
+
+func TestPresetsSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "goradio", fileName)
+
+	p := testPresets()
+	p.Stations[1].Gain = gain(0)
+	p.Stations[1].Filters = []filter.Spec{{Kind: filter.KindLowPass, Params: []float64{8000}}}
+	if err := p.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, p) {
+		t.Errorf("Load() = %+v, want %+v", got, p)
+	}
+
+	// no temporary file is left
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("%d files in the config directory, want 1", len(entries))
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+
+	p, err := Load(filepath.Join(dir, "missing.json"))
+	if err != nil || p == nil || len(p.Stations) != 0 {
+		t.Errorf("Load() of a missing file = %v, %v, want empty presets", p, err)
+	}
+
+	invalid := filepath.Join(dir, "invalid.json")
+	if err := os.WriteFile(invalid, []byte("{"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(invalid); err == nil {
+		t.Error("Load() of invalid JSON = nil error")
+	}
+}
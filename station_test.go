@@ -0,0 +1,129 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/kechako/goradio/filter"
+	"github.com/kechako/goradio/rtlfm"
+	"github.com/kechako/goradio/station"
+	cli "github.com/urfave/cli/v2"
+)
+
+// runStation runs a station command with the config and returns the
+// presets saved.
+func runStation(t *testing.T, config string, presets *station.Presets, args ...string) *station.Presets {
+	t.Helper()
+
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(configPath, []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	stationsPath := filepath.Join(dir, "stations.json")
+	if presets != nil {
+		if err := presets.Save(stationsPath); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	app := &cli.App{
+		Name: "goradio",
+		Commands: []*cli.Command{
+			{
+				Name: "station",
+				Subcommands: []*cli.Command{
+					{
+						Name:   "add",
+						Flags:  stationFlags(),
+						Action: stationAddCommand,
+					},
+					{
+						Name: "edit",
+						Flags: append(stationFlags(), &cli.StringFlag{
+							Name: "name",
+						}),
+						Action: stationEditCommand,
+					},
+				},
+			},
+		},
+		Flags: concatFlags(configFlags(), []cli.Flag{
+			&cli.StringFlag{Name: "stations"},
+		}),
+		Before: loadConfig,
+	}
+	setupCommands(app.Commands)
+
+	args = append([]string{"goradio", "--config", configPath, "--stations", stationsPath, "station"}, args...)
+	if err := app.Run(args); err != nil {
+		t.Fatal(err)
+	}
+
+	saved, err := station.Load(stationsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return saved
+}
+
+func TestStationAddIgnoresConfig(t *testing.T) {
+	const config = `{"defaults": {"deemp": true, "ppm": 52, "mode": "fm", "gain": "40", "filter": ["highpass:80"]}}`
+
+	gain := 49.6
+	on, off := true, false
+	tests := []struct {
+		name string
+		args []string
+		want *station.Station
+	}{
+		{
+			name: "frequency only",
+			args: []string{"--freq", "81.3M", "J-WAVE"},
+			want: &station.Station{Name: "J-WAVE", Frequency: 81300000},
+		},
+		{
+			name: "settings",
+			args: []string{"--freq", "80.0M", "--mode", "wbfm", "--ppm", "10", "--gain", "49.6", "--dc", "--deemp=false", "--filter", "lowpass:3k", "Tokyo"},
+			want: &station.Station{
+				Name:       "Tokyo",
+				Frequency:  80000000,
+				Mode:       rtlfm.WBFM,
+				Gain:       &gain,
+				PPM:        10,
+				DC:         &on,
+				DeEmphasis: &off,
+				Filters:    []filter.Spec{{Kind: filter.KindLowPass, Params: []float64{3000}}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			presets := runStation(t, config, nil, append([]string{"add"}, tt.args...)...)
+			if len(presets.Stations) != 1 {
+				t.Fatalf("stations = %d, want 1", len(presets.Stations))
+			}
+			if got := presets.Stations[0]; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("station = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStationEditIgnoresConfig(t *testing.T) {
+	const config = `{"defaults": {"deemp": true, "ppm": 52}}`
+
+	presets := &station.Presets{
+		Stations: []*station.Station{
+			{Name: "Tokyo", Frequency: 80000000, PPM: 10, Squelch: 5},
+		},
+	}
+	got := runStation(t, config, presets, "edit", "--squelch", "0", "--name", "TOKYO FM", "Tokyo")
+
+	want := &station.Station{Name: "TOKYO FM", Frequency: 80000000, PPM: 10}
+	if !reflect.DeepEqual(got.Stations[0], want) {
+		t.Errorf("station = %+v, want %+v", got.Stations[0], want)
+	}
+}
@@ -0,0 +1,138 @@
+package stream
+
+import (
+	"sync"
+)
+
+// Broadcaster distributes audio frames to any number of clients. Each
+// client has its own buffer, a client that does not keep up loses its
+// oldest frames instead of blocking Write.
+type Broadcaster struct {
+	mu      sync.Mutex
+	clients map[*Client]struct{}
+	closed  bool
+}
+
+func NewBroadcaster() *Broadcaster {
+	return &Broadcaster{
+		clients: make(map[*Client]struct{}),
+	}
+}
+
+// Write sends a copy of frame to all clients. It never blocks.
+func (b *Broadcaster) Write(frame []int16) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(b.clients) == 0 {
+		return nil
+	}
+
+	// clients share the copy read-only
+	f := make([]int16, len(frame))
+	copy(f, frame)
+
+	for c := range b.clients {
+		c.send(f)
+	}
+
+	return nil
+}
+
+// Subscribe adds a client that buffers up to frames frames.
+func (b *Broadcaster) Subscribe(frames int) *Client {
+	if frames < 1 {
+		frames = 1
+	}
+	c := &Client{
+		b:      b,
+		frames: make(chan []int16, frames),
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		close(c.frames)
+		return c
+	}
+	b.clients[c] = struct{}{}
+	return c
+}
+
+// Clients returns the number of clients.
+func (b *Broadcaster) Clients() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return len(b.clients)
+}
+
+// Close closes the frame channels of all clients.
+func (b *Broadcaster) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return nil
+	}
+	b.closed = true
+	for c := range b.clients {
+		close(c.frames)
+		delete(b.clients, c)
+	}
+	return nil
+}
+
+func (b *Broadcaster) unsubscribe(c *Client) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.clients[c]; ok {
+		close(c.frames)
+		delete(b.clients, c)
+	}
+}
+
+type Client struct {
+	b       *Broadcaster
+	frames  chan []int16
+	dropped int
+}
+
+// Frames returns the channel of frames. It is closed when the client or the
+// broadcaster is closed. Frames must not be modified.
+func (c *Client) Frames() <-chan []int16 {
+	return c.frames
+}
+
+// Dropped returns the number of frames dropped because the client was slow.
+func (c *Client) Dropped() int {
+	c.b.mu.Lock()
+	defer c.b.mu.Unlock()
+
+	return c.dropped
+}
+
+func (c *Client) Close() error {
+	c.b.unsubscribe(c)
+	return nil
+}
+
+// send is called with the lock of the broadcaster held.
+func (c *Client) send(frame []int16) {
+	for {
+		select {
+		case c.frames <- frame:
+			return
+		default:
+		}
+
+		// drop the oldest frame
+		select {
+		case <-c.frames:
+			c.dropped++
+		default:
+		}
+	}
+}
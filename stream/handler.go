@@ -0,0 +1,253 @@
+package stream
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kechako/goradio/wav"
+)
+
+const (
+	defaultClientBuffer = 2 * time.Second
+	frameDuration       = 10 * time.Millisecond
+
+	// bytes of audio between ICY metadata blocks
+	icyMetaInt = 16000
+)
+
+type Codec int
+
+const (
+	// PCM is 16-bit linear PCM.
+	PCM Codec = iota
+	// MuLaw is 8-bit G.711 mu-law, half the size of PCM. It is a reduced
+	// bandwidth variant of PCM rather than a compressed codec.
+	MuLaw
+	// IMAADPCM is 4-bit IMA ADPCM, a quarter of the size of PCM, which
+	// most players decode.
+	IMAADPCM
+)
+
+// Handler streams audio of a Broadcaster as a WAV file of unknown length.
+// If the client requests it with the Icy-MetaData header, the title is
+// sent as ICY metadata.
+type Handler struct {
+	b            *Broadcaster
+	sampleRate   int
+	codec        Codec
+	clientBuffer time.Duration
+
+	mu    sync.Mutex
+	name  string
+	title string
+}
+
+func NewHandler(b *Broadcaster, sampleRate int, opts ...Option) *Handler {
+	options := handlerOptions{
+		clientBuffer: defaultClientBuffer,
+	}
+	for _, opt := range opts {
+		opt.apply(&options)
+	}
+
+	return &Handler{
+		b:            b,
+		sampleRate:   sampleRate,
+		codec:        options.codec,
+		clientBuffer: options.clientBuffer,
+		name:         options.name,
+		title:        options.name,
+	}
+}
+
+// SetTitle sets the title sent as ICY metadata.
+func (h *Handler) SetTitle(title string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.title = title
+}
+
+func (h *Handler) Title() string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.title
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	format := wav.Format{
+		SampleRate:    h.sampleRate,
+		Channels:      1,
+		BitsPerSample: 16,
+	}
+	switch h.codec {
+	case MuLaw:
+		format.BitsPerSample = 8
+		format.MuLaw = true
+	case IMAADPCM:
+		format.BitsPerSample = 4
+		format.IMAADPCM = true
+	}
+
+	icy := r.Header.Get("Icy-MetaData") == "1"
+
+	header := w.Header()
+	header.Set("Content-Type", "audio/wav")
+	header.Set("Cache-Control", "no-cache, no-store")
+	if h.name != "" {
+		header.Set("icy-name", h.name)
+	}
+	if icy {
+		header.Set("icy-metaint", strconv.Itoa(icyMetaInt))
+	}
+	w.WriteHeader(http.StatusOK)
+	if r.Method == http.MethodHead {
+		return
+	}
+
+	client := h.b.Subscribe(int(h.clientBuffer / frameDuration))
+	defer client.Close()
+
+	var out io.Writer = w
+	if icy {
+		out = &icyWriter{w: w, h: h}
+	}
+
+	ww, err := wav.NewWriter(out, format)
+	if err != nil {
+		return
+	}
+
+	flusher, _ := w.(http.Flusher)
+	ctx := r.Context()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case frame, ok := <-client.Frames():
+			if !ok {
+				return
+			}
+			if err := ww.WriteInt16(frame); err != nil {
+				return
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+	}
+}
+
+// icyWriter inserts ICY metadata blocks every icyMetaInt bytes.
+type icyWriter struct {
+	w         io.Writer
+	h         *Handler
+	remaining int
+	lastTitle string
+	started   bool
+}
+
+func (w *icyWriter) Write(b []byte) (int, error) {
+	if !w.started {
+		w.started = true
+		w.remaining = icyMetaInt
+	}
+
+	written := 0
+	for len(b) > 0 {
+		n := len(b)
+		if n > w.remaining {
+			n = w.remaining
+		}
+		m, err := w.w.Write(b[:n])
+		written += m
+		if err != nil {
+			return written, err
+		}
+		b = b[n:]
+		w.remaining -= n
+
+		if w.remaining == 0 {
+			if _, err := w.w.Write(w.metadata()); err != nil {
+				return written, err
+			}
+			w.remaining = icyMetaInt
+		}
+	}
+
+	return written, nil
+}
+
+// metadata returns a metadata block, which is empty if the title has not
+// changed since the last block.
+func (w *icyWriter) metadata() []byte {
+	title := w.h.Title()
+	if title == w.lastTitle {
+		return []byte{0}
+	}
+	w.lastTitle = title
+
+	meta := fmt.Sprintf("StreamTitle='%s';", strings.ReplaceAll(title, "'", "’"))
+	if len(meta) > 255*16 {
+		meta = meta[:255*16]
+	}
+	blocks := (len(meta) + 15) / 16
+
+	var buf bytes.Buffer
+	buf.WriteByte(byte(blocks))
+	buf.WriteString(meta)
+	buf.Write(make([]byte, blocks*16-len(meta)))
+	return buf.Bytes()
+}
+
+type handlerOptions struct {
+	codec        Codec
+	clientBuffer time.Duration
+	name         string
+}
+
+type Option interface {
+	apply(opts *handlerOptions)
+}
+
+type optionFunc func(opts *handlerOptions)
+
+func (f optionFunc) apply(opts *handlerOptions) {
+	f(opts)
+}
+
+func WithCodec(codec Codec) Option {
+	return optionFunc(func(opts *handlerOptions) {
+		opts.codec = codec
+	})
+}
+
+// WithClientBuffer sets the amount of audio buffered for each client.
+func WithClientBuffer(d time.Duration) Option {
+	return optionFunc(func(opts *handlerOptions) {
+		if d > 0 {
+			opts.clientBuffer = d
+		}
+	})
+}
+
+// WithName sets the stream name sent in the icy-name header. It is also the
+// initial title.
+func WithName(name string) Option {
+	return optionFunc(func(opts *handlerOptions) {
+		opts.name = name
+	})
+}
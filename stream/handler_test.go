@@ -0,0 +1,228 @@
+package stream
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// broadcast writes frames of 10ms of a ramp to b until the test ends.
+func broadcast(t *testing.T, b *Broadcaster, sampleRate int) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		frame := make([]int16, sampleRate/100)
+		for i := range frame {
+			frame[i] = int16(i * 100)
+		}
+		for ctx.Err() == nil {
+			b.Write(frame)
+			time.Sleep(time.Millisecond)
+		}
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+}
+
+func TestHandlerCodec(t *testing.T) {
+	const sampleRate = 8000
+
+	tests := []struct {
+		name  string
+		codec Codec
+		tag   uint16
+		// header size up to the data, and bytes per second
+		header      int
+		blockAlign  int
+		bytesPerSec int
+	}{
+		{"pcm", PCM, 1, 44, 2, 16000},
+		{"mu-law", MuLaw, 7, 58, 1, 8000},
+		{"ima adpcm", IMAADPCM, 0x11, 60, 256, 8000 * 256 / 505},
+	}
+	for _, tt := range tests {
+		b := NewBroadcaster()
+		srv := httptest.NewServer(NewHandler(b, sampleRate, WithCodec(tt.codec)))
+		broadcast(t, b, sampleRate)
+
+		res, err := http.Get(srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := res.Header.Get("Content-Type"); got != "audio/wav" {
+			t.Errorf("%s: Content-Type = %q, want audio/wav", tt.name, got)
+		}
+
+		// the header and the first block
+		buf := make([]byte, tt.header+tt.blockAlign)
+		_, err = io.ReadFull(res.Body, buf)
+		res.Body.Close()
+		b.Close()
+		srv.Close()
+		if err != nil {
+			t.Errorf("%s: failed to read the stream: %v", tt.name, err)
+			continue
+		}
+
+		if string(buf[0:4]) != "RIFF" || string(buf[tt.header-8:tt.header-4]) != "data" {
+			t.Errorf("%s: invalid header % x", tt.name, buf[:tt.header])
+			continue
+		}
+		if got := binary.LittleEndian.Uint16(buf[20:]); got != tt.tag {
+			t.Errorf("%s: format tag = %#x, want %#x", tt.name, got, tt.tag)
+		}
+		if got := int(binary.LittleEndian.Uint32(buf[28:])); got != tt.bytesPerSec {
+			t.Errorf("%s: bytes per second = %d, want %d", tt.name, got, tt.bytesPerSec)
+		}
+		if got := int(binary.LittleEndian.Uint16(buf[32:])); got != tt.blockAlign {
+			t.Errorf("%s: block align = %d, want %d", tt.name, got, tt.blockAlign)
+		}
+	}
+}
+
+func TestHandlerICY(t *testing.T) {
+	b := NewBroadcaster()
+	h := NewHandler(b, 8000, WithName("NHK-FM"))
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+	defer b.Close()
+	broadcast(t, b, 8000)
+
+	req, err := http.NewRequest(http.MethodGet, srv.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Icy-MetaData", "1")
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	if got := res.Header.Get("icy-name"); got != "NHK-FM" {
+		t.Errorf("icy-name = %q, want %q", got, "NHK-FM")
+	}
+	if got := res.Header.Get("icy-metaint"); got != "16000" {
+		t.Fatalf("icy-metaint = %q, want %q", got, "16000")
+	}
+
+	// metadata follows every 16000 bytes of audio
+	readMetadata := func() string {
+		t.Helper()
+
+		if _, err := io.CopyN(io.Discard, res.Body, icyMetaInt); err != nil {
+			t.Fatal(err)
+		}
+		var n [1]byte
+		if _, err := io.ReadFull(res.Body, n[:]); err != nil {
+			t.Fatal(err)
+		}
+		meta := make([]byte, int(n[0])*16)
+		if _, err := io.ReadFull(res.Body, meta); err != nil {
+			t.Fatal(err)
+		}
+		return strings.TrimRight(string(meta), "\x00")
+	}
+
+	if got, want := readMetadata(), "StreamTitle='NHK-FM';"; got != want {
+		t.Errorf("first metadata = %q, want %q", got, want)
+	}
+	// unchanged titles are not repeated
+	if got := readMetadata(); got != "" {
+		t.Errorf("unchanged metadata = %q, want empty", got)
+	}
+	h.SetTitle("Rock'n'Roll")
+	if got, want := readMetadata(), "StreamTitle='Rock’n’Roll';"; got != want {
+		t.Errorf("metadata after SetTitle() = %q, want %q", got, want)
+	}
+}
+
+func TestIcyWriter(t *testing.T) {
+	h := NewHandler(nil, 8000, WithName("a"))
+
+	// writes are split at the metadata interval
+	var buf strings.Builder
+	w := &icyWriter{w: &buf, h: h}
+	data := strings.Repeat("x", icyMetaInt-1)
+	for _, s := range []string{data, "yz", data} {
+		if n, err := w.Write([]byte(s)); n != len(s) || err != nil {
+			t.Fatalf("Write() = %d, %v, want %d, nil", n, err, len(s))
+		}
+	}
+
+	// the title fits in a block of 16 bytes, it is not repeated
+	meta := "\x01StreamTitle='a';"
+	want := data + "y" + meta + "z" + data + "\x00"
+	if got := buf.String(); got != want {
+		t.Errorf("written %d bytes, want %d bytes with metadata at %d", len(got), len(want), icyMetaInt)
+	}
+}
+
+func TestBroadcasterDrop(t *testing.T) {
+	b := NewBroadcaster()
+	slow := b.Subscribe(2)
+	fast := b.Subscribe(10)
+
+	for i := 0; i < 5; i++ {
+		b.Write([]int16{int16(i)})
+	}
+
+	// the slow client keeps the latest frames
+	if got := slow.Dropped(); got != 3 {
+		t.Errorf("slow client dropped %d frames, want 3", got)
+	}
+	for _, want := range []int16{3, 4} {
+		if frame := <-slow.Frames(); frame[0] != want {
+			t.Errorf("slow client frame = %d, want %d", frame[0], want)
+		}
+	}
+	if got := fast.Dropped(); got != 0 {
+		t.Errorf("fast client dropped %d frames, want 0", got)
+	}
+	if got := len(fast.Frames()); got != 5 {
+		t.Errorf("fast client has %d frames, want 5", got)
+	}
+
+	for i := 0; i < 5; i++ {
+		<-fast.Frames()
+	}
+
+	// frames are copied
+	frame := []int16{100}
+	b.Write(frame)
+	frame[0] = 0
+	if got := <-fast.Frames(); got[0] != 100 {
+		t.Errorf("frame after modification = %d, want 100", got[0])
+	}
+
+	slow.Close()
+	// the frame buffered before Close
+	<-slow.Frames()
+	if _, ok := <-slow.Frames(); ok {
+		t.Error("frames of a closed client are not closed")
+	}
+	if got := b.Clients(); got != 1 {
+		t.Errorf("Clients() after Close() of a client = %d, want 1", got)
+	}
+
+	b.Close()
+	if _, ok := <-fast.Frames(); ok {
+		t.Error("frames are not closed by Close() of the broadcaster")
+	}
+	if _, ok := <-b.Subscribe(1).Frames(); ok {
+		t.Error("frames of a client subscribed after Close() are not closed")
+	}
+	if err := b.Write([]int16{0}); err != nil {
+		t.Errorf("Write() after Close() = %v", err)
+	}
+}
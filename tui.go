@@ -0,0 +1,581 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kechako/goradio/band"
+	"github.com/kechako/goradio/icecast"
+	"github.com/kechako/goradio/player"
+	"github.com/kechako/goradio/rtlfm"
+	"github.com/kechako/goradio/source"
+	"github.com/kechako/goradio/tui"
+	"github.com/kechako/goradio/volume"
+	"github.com/kechako/goradio/wav"
+	cli "github.com/urfave/cli/v2"
+	"golang.org/x/term"
+)
+
+const (
+	tuiRefreshInterval = 100 * time.Millisecond
+	// retuning waits for arrow keys to settle, rtl_fm takes a while to
+	// restart
+	tuiTuneDelay = 300 * time.Millisecond
+	// the level meter falls by this factor every refresh
+	tuiMeterDecay = 0.7
+	tuiMeterFloor = -60.0
+	tuiVolumeStep = 1.0
+	// frames queued to the recorder before dropping
+	recorderQueue = 200
+)
+
+// tuiCommand plays radio with a full-screen terminal interface.
+func tuiCommand(ctx *cli.Context) error {
+	if !term.IsTerminal(int(os.Stdin.Fd())) || !term.IsTerminal(int(os.Stdout.Fd())) {
+		return errors.New("tui requires a terminal")
+	}
+
+	device, err := outputDevice(ctx)
+	if err != nil {
+		return err
+	}
+	sampleRate := ctx.Int("sample-rate")
+	if sampleRate == 0 {
+		sampleRate = device.DefaultSampleRate()
+	}
+	quality, err := resampleQuality(ctx)
+	if err != nil {
+		return err
+	}
+	db, err := volumeDB(ctx)
+	if err != nil {
+		return err
+	}
+	filters, err := flagFilters(ctx, sampleRate)
+	if err != nil {
+		return err
+	}
+
+	var t player.Tuning
+	if ctx.String("freq") != "" {
+		t, err = tuning(ctx)
+	} else {
+		t, err = tuningSettings(ctx)
+	}
+	if err != nil {
+		return err
+	}
+	t.Filters, err = stationFilters(ctx)
+	if err != nil {
+		return err
+	}
+
+	stream, bufferSamples, err := openOutputStream(ctx, device, sampleRate)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	open := func(t player.Tuning) (source.Source, error) {
+		// the source runs at its native rate and is converted by the player
+		return openTunedSource(ctx, t, 0, true)
+	}
+	p := player.New(stream, sampleRate, bufferSamples, open,
+		player.WithLatency(ctx.Duration("latency")),
+		player.WithResampleQuality(quality),
+		player.WithVolume(db),
+		player.WithFade(ctx.Duration("fade")),
+		player.WithFilters(filters...),
+	)
+	defer p.Close()
+
+	pctx, cancel := context.WithCancel(ctx.Context)
+	defer cancel()
+
+	var push *playerPush
+	if ctx.String("icecast") != "" {
+		push, err = pushPlayer(pctx, ctx, p, sampleRate)
+		if err != nil {
+			return err
+		}
+		p.SetTap(push.write)
+	}
+
+	if err := p.Tune(t); err != nil {
+		return err
+	}
+	if t.Frequency != 0 {
+		if err := p.Start(); err != nil {
+			return err
+		}
+	}
+
+	terminal, err := tui.Open(os.Stdin, os.Stdout)
+	if err != nil {
+		return err
+	}
+	defer terminal.Close()
+
+	ui := &tuiModel{
+		ctx:        ctx,
+		p:          p,
+		sampleRate: sampleRate,
+		latency:    ctx.Duration("latency"),
+		recordDir:  ctx.String("record-dir"),
+		push:       push,
+		tunings:    make(chan player.Tuning, 1),
+		tuneErrs:   make(chan error, 1),
+	}
+	defer ui.stopRecording()
+
+	go ui.tuner()
+	defer close(ui.tunings)
+
+	return ui.run(pctx, terminal)
+}
+
+type tuiModel struct {
+	ctx        *cli.Context
+	p          *player.Player
+	sampleRate int
+	latency    time.Duration
+	recordDir  string
+	// stream to Icecast, nil if not streaming
+	push *playerPush
+
+	// tunings are applied by the tuner goroutine, only the latest one
+	// is kept
+	tunings  chan player.Tuning
+	tuneErrs chan error
+
+	// frequency stepped by arrow keys, but not tuned yet
+	pending rtlfm.Frequency
+	meter   float64
+	rec     *recorder
+	message string
+}
+
+func (ui *tuiModel) run(ctx context.Context, terminal *tui.Terminal) error {
+	sub := ui.p.Subscribe()
+	defer sub.Close()
+
+	ticker := time.NewTicker(tuiRefreshInterval)
+	defer ticker.Stop()
+
+	// nil until an arrow key is pressed
+	var retune <-chan time.Time
+
+	// nil if not streaming to Icecast
+	var (
+		icecastEvents <-chan icecast.Event
+		icecastErr    <-chan error
+	)
+	if ui.push != nil {
+		icecastEvents = ui.push.events
+		icecastErr = ui.push.err
+	}
+
+	for {
+		width, _ := terminal.Size()
+		if err := terminal.Draw(ui.view(width)); err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case key, ok := <-terminal.Keys():
+			if !ok {
+				return nil
+			}
+			quit, step := ui.handleKey(key)
+			if quit {
+				return nil
+			}
+			if step {
+				retune = time.After(tuiTuneDelay)
+			}
+		case <-retune:
+			retune = nil
+			t := ui.p.Tuning()
+			// settings of the station do not apply to other frequencies
+			t.Frequency = ui.pending
+			t.Station = ""
+			t.Preset = nil
+			t.Filters = nil
+			ui.tune(t)
+		case err := <-ui.tuneErrs:
+			ui.pending = 0
+			ui.message = err.Error()
+		case ev, ok := <-sub.Events():
+			if !ok {
+				return nil
+			}
+			ui.handleEvent(ev)
+		case ev, ok := <-icecastEvents:
+			if !ok {
+				icecastEvents = nil
+				continue
+			}
+			if msg := icecastMessage(ev); msg != "" {
+				ui.message = msg
+			}
+		case err := <-icecastErr:
+			if err != nil {
+				return err
+			}
+			icecastErr = nil
+		case <-ticker.C:
+			ui.meter *= tuiMeterDecay
+		}
+	}
+}
+
+// handleKey handles key. step reports whether the frequency is stepped
+// and must be tuned later.
+func (ui *tuiModel) handleKey(key tui.Key) (quit, step bool) {
+	switch key.Code {
+	case tui.KeyCtrlC:
+		return true, false
+	case tui.KeyUp, tui.KeyRight:
+		return false, ui.step(1)
+	case tui.KeyDown, tui.KeyLeft:
+		return false, ui.step(-1)
+	case tui.KeyRune:
+		switch r := key.Rune; {
+		case r == 'q':
+			return true, false
+		case r == 'm':
+			ui.p.SetMuted(!ui.p.Muted())
+		case r == '+' || r == '=':
+			ui.p.SetVolume(ui.p.Volume() + tuiVolumeStep)
+		case r == '-' || r == '_':
+			ui.p.SetVolume(ui.p.Volume() - tuiVolumeStep)
+		case r == 's':
+			ui.toggleRecording()
+		case r >= '0' && r <= '9':
+			// 1 to 9 and 0 recall the first ten presets
+			i := int(r - '1')
+			if r == '0' {
+				i = 9
+			}
+			ui.recallPreset(i)
+		}
+	}
+	return false, false
+}
+
+func (ui *tuiModel) step(n int) bool {
+	t := ui.p.Tuning()
+	freq := ui.pending
+	if freq == 0 {
+		freq = t.Frequency
+	}
+	if freq == 0 {
+		ui.message = "frequency is not tuned, recall a preset first"
+		return false
+	}
+	ui.pending = band.Step(freq, t.Modulation, n)
+	return true
+}
+
+func (ui *tuiModel) recallPreset(i int) {
+	presets, _, err := loadPresets(ui.ctx)
+	if err != nil {
+		ui.message = err.Error()
+		return
+	}
+	if i >= len(presets.Stations) {
+		ui.message = fmt.Sprintf("no preset %d", (i+1)%10)
+		return
+	}
+	st := presets.Stations[i]
+	ui.pending = st.Frequency
+	ui.tune(ui.p.Tuning().WithStation(st))
+}
+
+// tune queues t to the tuner, replacing a tuning not applied yet.
+func (ui *tuiModel) tune(t player.Tuning) {
+	for {
+		select {
+		case ui.tunings <- t:
+			return
+		default:
+			select {
+			case <-ui.tunings:
+			default:
+			}
+		}
+	}
+}
+
+// tuner applies tunings, starting the player if it is stopped.
+func (ui *tuiModel) tuner() {
+	for t := range ui.tunings {
+		err := ui.p.Tune(t)
+		if err == nil && ui.p.Status().State == player.Stopped {
+			err = ui.p.Start()
+		}
+		if err != nil {
+			select {
+			case ui.tuneErrs <- err:
+			default:
+			}
+		}
+	}
+}
+
+func (ui *tuiModel) handleEvent(ev player.Event) {
+	switch ev := ev.(type) {
+	case player.TunedEvent:
+		if ev.Tuning.Frequency == ui.pending {
+			ui.pending = 0
+		}
+		ui.message = ""
+	case player.StoppedEvent:
+		if ev.Err != nil {
+			ui.message = fmt.Sprintf("stopped: %v", ev.Err)
+		}
+	case player.SourceEvent:
+		switch ev := ev.Event.(type) {
+		case rtlfm.RestartEvent:
+			ui.message = fmt.Sprintf("rtl_fm stopped (%v), restarting (%d)", ev.Err, ev.Restarts)
+		case rtlfm.ErrorEvent:
+			ui.message = ev.Err.Error()
+		}
+	}
+}
+
+func (ui *tuiModel) toggleRecording() {
+	if ui.rec != nil {
+		ui.stopRecording()
+		return
+	}
+
+	name := time.Now().Format("goradio-20060102-150405.wav")
+	rec, err := startRecorder(filepath.Join(ui.recordDir, name), ui.sampleRate)
+	if err != nil {
+		ui.message = err.Error()
+		return
+	}
+	ui.rec = rec
+	ui.setTap()
+	ui.message = "recording to " + rec.path
+}
+
+func (ui *tuiModel) stopRecording() {
+	if ui.rec == nil {
+		return
+	}
+	rec := ui.rec
+	ui.rec = nil
+	ui.setTap()
+	if err := rec.close(); err != nil {
+		ui.message = err.Error()
+	} else {
+		ui.message = "saved " + rec.path
+	}
+}
+
+// setTap passes the frames played to the recorder and to Icecast.
+func (ui *tuiModel) setTap() {
+	rec, push := ui.rec, ui.push
+	switch {
+	case rec == nil && push == nil:
+		ui.p.SetTap(nil)
+	case rec == nil:
+		ui.p.SetTap(push.write)
+	case push == nil:
+		ui.p.SetTap(rec.write)
+	default:
+		ui.p.SetTap(func(frame []int16) {
+			rec.write(frame)
+			push.write(frame)
+		})
+	}
+}
+
+func (ui *tuiModel) view(width int) []string {
+	st := ui.p.Status()
+	t := st.Tuning
+	if st.Level > ui.meter {
+		ui.meter = st.Level
+	}
+
+	var lines []string
+	add := func(format string, args ...any) {
+		lines = append(lines, fmt.Sprintf(format, args...))
+	}
+
+	state := string(st.State)
+	if st.Muted {
+		state += ", muted"
+	}
+	add(" goradio  [%s]", state)
+	add("")
+
+	freq := t.Frequency
+	tuning := ""
+	if ui.pending != 0 {
+		freq = ui.pending
+		tuning = "  (tuning)"
+	}
+	mode := t.Modulation
+	if mode == 0 {
+		mode = rtlfm.WBFM
+	}
+	if freq == 0 {
+		add(" frequency  -")
+	} else {
+		add(" frequency  %s %s%s", freq, strings.ToUpper(mode.String()), tuning)
+	}
+	if t.Station != "" && ui.pending == 0 {
+		add(" station    %s", t.Station)
+	} else {
+		add(" station    -")
+	}
+	if b, ok := band.Find(freq); ok {
+		add(" band       %s, %s steps", b.Name, b.Spacing)
+	} else {
+		add(" band       -, %s steps", band.Spacing(freq, mode))
+	}
+	add("")
+
+	add(" level      %s", meter(ui.meter, width-27))
+	if st.Volume <= volume.MinDB {
+		add(" volume     off")
+	} else {
+		add(" volume     %s", volume.FormatDB(st.Volume))
+	}
+	if st.State == player.Playing {
+		squelch := "closed"
+		if st.SquelchOpen {
+			squelch = "open"
+		}
+		add(" squelch    %s", squelch)
+		add(" buffer     %dms of %dms, %d prefills, %d underruns, %d overruns",
+			st.Buffered.Milliseconds(), ui.latency.Milliseconds(), st.Stats.Prefills, st.Stats.Underruns, st.Stats.Overruns)
+		if st.Restarts > 0 {
+			add(" restarts   %d", st.Restarts)
+		}
+	} else {
+		add(" squelch    -")
+		add(" buffer     -")
+	}
+	if ui.rec != nil {
+		add(" recording  %s %s", filepath.Base(ui.rec.path), ui.rec.duration())
+	} else {
+		add(" recording  -")
+	}
+	add("")
+	add(" %s", ui.message)
+	add("")
+	add(" arrows: step  1-9,0: preset  +/-: volume  m: mute  s: record  q: quit")
+
+	return lines
+}
+
+// meter draws level from 0 to 1 as a bar in dBFS.
+func meter(level float64, width int) string {
+	if width < 10 {
+		width = 10
+	}
+	db := tuiMeterFloor
+	if level > 0 {
+		db = math.Max(20*math.Log10(level), tuiMeterFloor)
+	}
+	n := int(math.Round((1 - db/tuiMeterFloor) * float64(width)))
+
+	label := "  -inf dBFS"
+	if db > tuiMeterFloor {
+		label = fmt.Sprintf("%6.1f dBFS", db)
+	}
+	return "[" + strings.Repeat("#", n) + strings.Repeat("-", width-n) + "]" + label
+}
+
+// recorder writes frames to a WAV file on its own goroutine, so that the
+// playback is not blocked by the disk.
+type recorder struct {
+	path    string
+	started time.Time
+	frames  chan []int16
+	done    chan error
+
+	mu     sync.Mutex
+	closed bool
+}
+
+func startRecorder(path string, sampleRate int) (*recorder, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create output file: %w", err)
+	}
+	w, err := wav.NewWriter(file, wav.Format{
+		SampleRate:    sampleRate,
+		Channels:      1,
+		BitsPerSample: 16,
+	})
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	r := &recorder{
+		path:    path,
+		started: time.Now(),
+		frames:  make(chan []int16, recorderQueue),
+		done:    make(chan error, 1),
+	}
+	go func() {
+		var err error
+		for frame := range r.frames {
+			if err == nil {
+				err = w.WriteInt16(frame)
+			}
+		}
+		if cerr := w.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+		if cerr := file.Close(); cerr != nil && err == nil {
+			err = fmt.Errorf("failed to close output file: %w", cerr)
+		}
+		r.done <- err
+	}()
+
+	return r, nil
+}
+
+// write queues a copy of frame. The frame is dropped if the queue is full.
+func (r *recorder) write(frame []int16) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return
+	}
+	select {
+	case r.frames <- append([]int16(nil), frame...):
+	default:
+	}
+}
+
+func (r *recorder) duration() time.Duration {
+	return time.Since(r.started).Truncate(time.Second)
+}
+
+// close writes the queued frames and closes the file.
+func (r *recorder) close() error {
+	r.mu.Lock()
+	r.closed = true
+	close(r.frames)
+	r.mu.Unlock()
+
+	return <-r.done
+}
@@ -0,0 +1,199 @@
+package tui
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+var ErrNotTerminal = errors.New("not a terminal")
+
+type KeyCode int
+
+const (
+	KeyRune KeyCode = iota
+	KeyUp
+	KeyDown
+	KeyLeft
+	KeyRight
+	KeyEnter
+	KeyEscape
+	KeyBackspace
+	KeyCtrlC
+)
+
+// Key is a key pressed. Rune is set if Code is KeyRune.
+type Key struct {
+	Code KeyCode
+	Rune rune
+}
+
+// Terminal is a full-screen terminal in raw mode.
+type Terminal struct {
+	in    *os.File
+	out   *os.File
+	state *term.State
+	keys  chan Key
+}
+
+// Open switches the terminal of in and out to raw mode and the alternate
+// screen. Close must be called to restore the terminal.
+func Open(in, out *os.File) (*Terminal, error) {
+	if !term.IsTerminal(int(in.Fd())) || !term.IsTerminal(int(out.Fd())) {
+		return nil, ErrNotTerminal
+	}
+
+	state, err := term.MakeRaw(int(in.Fd()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to set terminal to raw mode: %w", err)
+	}
+
+	t := &Terminal{
+		in:    in,
+		out:   out,
+		state: state,
+		keys:  make(chan Key, 16),
+	}
+	// alternate screen, hide cursor
+	io.WriteString(out, "\x1b[?1049h\x1b[?25l")
+
+	go t.readKeys()
+
+	return t, nil
+}
+
+// Close restores the terminal.
+func (t *Terminal) Close() error {
+	// show cursor, main screen
+	io.WriteString(t.out, "\x1b[?25h\x1b[?1049l")
+	return term.Restore(int(t.in.Fd()), t.state)
+}
+
+// Size returns the width and the height of the terminal.
+func (t *Terminal) Size() (width, height int) {
+	width, height, err := term.GetSize(int(t.out.Fd()))
+	if err != nil || width == 0 || height == 0 {
+		return 80, 24
+	}
+	return width, height
+}
+
+// Keys returns the channel of keys pressed. It is closed when input ends.
+func (t *Terminal) Keys() <-chan Key {
+	return t.keys
+}
+
+// Draw redraws the screen with lines. Lines are truncated to the width of
+// the terminal and must not contain escape sequences nor wide characters.
+func (t *Terminal) Draw(lines []string) error {
+	width, height := t.Size()
+	if len(lines) > height {
+		lines = lines[:height]
+	}
+
+	var b strings.Builder
+	b.WriteString("\x1b[H")
+	for i, line := range lines {
+		if i > 0 {
+			b.WriteString("\r\n")
+		}
+		if r := []rune(line); len(r) > width {
+			line = string(r[:width])
+		}
+		b.WriteString(line)
+		// clear the rest of the line
+		b.WriteString("\x1b[K")
+	}
+	// clear the rest of the screen
+	b.WriteString("\x1b[J")
+
+	_, err := io.WriteString(t.out, b.String())
+	return err
+}
+
+func (t *Terminal) readKeys() {
+	defer close(t.keys)
+
+	r := bufio.NewReader(t.in)
+	for {
+		key, err := readKey(r)
+		if err != nil {
+			return
+		}
+		t.keys <- key
+	}
+}
+
+// readKey reads a key. Unknown escape sequences are ignored.
+func readKey(r *bufio.Reader) (Key, error) {
+	for {
+		c, _, err := r.ReadRune()
+		if err != nil {
+			return Key{}, err
+		}
+
+		switch c {
+		case 0x03:
+			return Key{Code: KeyCtrlC}, nil
+		case '\r', '\n':
+			return Key{Code: KeyEnter}, nil
+		case 0x7f, 0x08:
+			return Key{Code: KeyBackspace}, nil
+		case 0x1b:
+			if r.Buffered() == 0 {
+				return Key{Code: KeyEscape}, nil
+			}
+			key, ok, err := readEscape(r)
+			if err != nil {
+				return Key{}, err
+			}
+			if ok {
+				return key, nil
+			}
+		default:
+			if c >= 0x20 {
+				return Key{Code: KeyRune, Rune: c}, nil
+			}
+		}
+	}
+}
+
+// readEscape reads an escape sequence after ESC, such as "[A" or "OA" of
+// cursor keys.
+func readEscape(r *bufio.Reader) (Key, bool, error) {
+	c, err := r.ReadByte()
+	if err != nil {
+		return Key{}, false, err
+	}
+	if c != '[' && c != 'O' {
+		return Key{}, false, nil
+	}
+
+	// skip parameters, e.g. "1;5" of modified keys
+	for {
+		c, err = r.ReadByte()
+		if err != nil {
+			return Key{}, false, err
+		}
+		if c < '0' || c > ';' {
+			break
+		}
+	}
+
+	switch c {
+	case 'A':
+		return Key{Code: KeyUp}, true, nil
+	case 'B':
+		return Key{Code: KeyDown}, true, nil
+	case 'C':
+		return Key{Code: KeyRight}, true, nil
+	case 'D':
+		return Key{Code: KeyLeft}, true, nil
+	}
+	return Key{}, false, nil
+}
@@ -0,0 +1,44 @@
+package tui
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadKey(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []Key
+	}{
+		{"runes", "q+é", []Key{{Code: KeyRune, Rune: 'q'}, {Code: KeyRune, Rune: '+'}, {Code: KeyRune, Rune: 'é'}}},
+		{"control", "\x03\r\n\x7f\x08", []Key{{Code: KeyCtrlC}, {Code: KeyEnter}, {Code: KeyEnter}, {Code: KeyBackspace}, {Code: KeyBackspace}}},
+		{"cursor", "\x1b[A\x1b[B\x1b[C\x1b[D", []Key{{Code: KeyUp}, {Code: KeyDown}, {Code: KeyRight}, {Code: KeyLeft}}},
+		{"application cursor", "\x1bOA", []Key{{Code: KeyUp}}},
+		{"modified", "\x1b[1;5C", []Key{{Code: KeyRight}}},
+		{"unknown escape", "\x1b[3~x\x1bxy", []Key{{Code: KeyRune, Rune: 'x'}, {Code: KeyRune, Rune: 'y'}}},
+		{"escape", "\x1b", []Key{{Code: KeyEscape}}},
+		{"other control", "\x01\tz", []Key{{Code: KeyRune, Rune: 'z'}}},
+	}
+	for _, tt := range tests {
+		r := bufio.NewReader(strings.NewReader(tt.input))
+		var got []Key
+		for {
+			key, err := readKey(r)
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				t.Fatalf("%s: readKey() = %v", tt.name, err)
+			}
+			got = append(got, key)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: keys = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kechako/goradio/audio"
+	"github.com/kechako/goradio/player"
+	"github.com/kechako/goradio/rtlfm"
+	"github.com/kechako/goradio/source"
+	"github.com/kechako/goradio/stream"
+	"github.com/kechako/goradio/tui"
+	"github.com/kechako/goradio/wav"
+)
+
+const testSampleRate = 8000
+
+// newTestPlayer returns a player of a tone on the null audio backend.
+func newTestPlayer(t *testing.T) *player.Player {
+	t.Helper()
+
+	if err := audio.SetBackend(audio.Null()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		audio.Terminate()
+		audio.SetBackend(audio.PortAudio())
+	})
+
+	s, err := audio.Open[int16](
+		audio.WithDefaultOutputDevice(),
+		audio.WithOutputChannels(1),
+		audio.WithSampleRate(testSampleRate),
+		audio.WithBufferSamples(testSampleRate/100),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := player.New(s, testSampleRate, testSampleRate/100, func(t player.Tuning) (source.Source, error) {
+		return source.NewTone(1000, 0.5, testSampleRate), nil
+	}, player.WithFade(0), player.WithLatency(20*time.Millisecond))
+	t.Cleanup(func() {
+		p.Close()
+		s.Close()
+	})
+	return p
+}
+
+// readWAVSamples reads the 16-bit samples of a WAV file.
+func readWAVSamples(t *testing.T, path string) []int16 {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := wav.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	samples := make([]int16, len(b)/2)
+	for i := range samples {
+		samples[i] = int16(binary.LittleEndian.Uint16(b[2*i:]))
+	}
+	return samples
+}
+
+func TestRecorder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rec.wav")
+	rec, err := startRecorder(path, testSampleRate)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	frame := []int16{1, -2, 3}
+	rec.write(frame)
+	// the frame is copied
+	frame[0] = 100
+	rec.write(frame)
+	if err := rec.close(); err != nil {
+		t.Fatal(err)
+	}
+	// frames after close are discarded
+	rec.write(frame)
+
+	want := []int16{1, -2, 3, 100, -2, 3}
+	if got := readWAVSamples(t, path); !reflect.DeepEqual(got, want) {
+		t.Errorf("recorded %v, want %v", got, want)
+	}
+}
+
+func TestTUISetTap(t *testing.T) {
+	p := newTestPlayer(t)
+	b := stream.NewBroadcaster()
+	defer b.Close()
+	sub := b.Subscribe(1000)
+
+	ui := &tuiModel{
+		p:          p,
+		sampleRate: testSampleRate,
+		recordDir:  t.TempDir(),
+		push:       &playerPush{b: b},
+	}
+	ui.setTap()
+
+	if err := p.Tune(player.Tuning{Frequency: 80 * rtlfm.MegaHertz}); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Icecast receives frames without recording
+	select {
+	case <-sub.Frames():
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for frames to Icecast")
+	}
+
+	ui.toggleRecording()
+	if ui.rec == nil {
+		t.Fatalf("recording is not started: %s", ui.message)
+	}
+	path := ui.rec.path
+	time.Sleep(100 * time.Millisecond)
+	ui.toggleRecording()
+	if ui.rec != nil || ui.message != "saved "+path {
+		t.Fatalf("recording is not stopped: %s", ui.message)
+	}
+
+	// frames played while recording are passed to both
+	recorded := readWAVSamples(t, path)
+	if len(recorded) == 0 {
+		t.Error("no frames are recorded")
+	}
+	for len(sub.Frames()) > 0 {
+		<-sub.Frames()
+	}
+	select {
+	case <-sub.Frames():
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for frames to Icecast after recording")
+	}
+
+	// no tap without recording nor Icecast
+	ui.push = nil
+	ui.setTap()
+	time.Sleep(50 * time.Millisecond)
+	for len(sub.Frames()) > 0 {
+		<-sub.Frames()
+	}
+	time.Sleep(50 * time.Millisecond)
+	if n := len(sub.Frames()); n != 0 {
+		t.Errorf("Icecast received %d frames after the tap is removed", n)
+	}
+}
+
+func TestTUIHandleKey(t *testing.T) {
+	p := newTestPlayer(t)
+	ui := &tuiModel{p: p}
+
+	if quit, _ := ui.handleKey(tui.Key{Code: tui.KeyRune, Rune: 'm'}); quit || !p.Muted() {
+		t.Errorf("m: quit %v, muted %v, want false, true", quit, p.Muted())
+	}
+	ui.handleKey(tui.Key{Code: tui.KeyRune, Rune: '-'})
+	if got := p.Volume(); got != -tuiVolumeStep {
+		t.Errorf("-: volume = %g, want %g", got, -tuiVolumeStep)
+	}
+
+	// stepping needs a frequency
+	if _, step := ui.handleKey(tui.Key{Code: tui.KeyUp}); step || ui.message == "" {
+		t.Errorf("up without frequency: step %v, message %q", step, ui.message)
+	}
+	if err := p.Tune(player.Tuning{Frequency: 80 * rtlfm.MegaHertz, Modulation: rtlfm.WBFM}); err != nil {
+		t.Fatal(err)
+	}
+	if _, step := ui.handleKey(tui.Key{Code: tui.KeyUp}); !step || ui.pending != 80100*rtlfm.KiloHertz {
+		t.Errorf("up: step %v, pending %s, want true, 80.1M", step, ui.pending)
+	}
+	ui.handleKey(tui.Key{Code: tui.KeyLeft})
+	ui.handleKey(tui.Key{Code: tui.KeyLeft})
+	if ui.pending != 79900*rtlfm.KiloHertz {
+		t.Errorf("left twice: pending %s, want 79.9M", ui.pending)
+	}
+
+	for _, key := range []tui.Key{{Code: tui.KeyCtrlC}, {Code: tui.KeyRune, Rune: 'q'}} {
+		if quit, _ := ui.handleKey(key); !quit {
+			t.Errorf("%+v: quit = false, want true", key)
+		}
+	}
+}
+
+func TestMeter(t *testing.T) {
+	tests := []struct {
+		level float64
+		want  string
+	}{
+		{0, "[----------]  -inf dBFS"},
+		{1, "[##########]   0.0 dBFS"},
+		// the floor
+		{0.001, "[----------]  -inf dBFS"},
+		{0.1, "[#######---] -20.0 dBFS"},
+	}
+	for _, tt := range tests {
+		if got := meter(tt.level, 10); got != tt.want {
+			t.Errorf("meter(%g) = %q, want %q", tt.level, got, tt.want)
+		}
+	}
+	if got := meter(1, 0); !strings.HasPrefix(got, "["+strings.Repeat("#", 10)+"]") {
+		t.Errorf("meter() of width 0 = %q, want 10 characters", got)
+	}
+}
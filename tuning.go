@@ -0,0 +1,226 @@
+package main
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/kechako/goradio/player"
+	"github.com/kechako/goradio/rtlfm"
+	cli "github.com/urfave/cli/v2"
+)
+
+func tuningFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:     "mode",
+			Aliases:  []string{"M"},
+			Usage:    "demodulation mode (fm, wbfm, am, usb, lsb, raw)",
+			Value:    rtlfm.WBFM.String(),
+			Required: false,
+		},
+		&cli.StringFlag{
+			Name:     "dongle",
+			Usage:    "RTL-SDR dongle index or serial",
+			Required: false,
+		},
+		&cli.StringFlag{
+			Name:     "gain",
+			Aliases:  []string{"g"},
+			Usage:    "tuner gain in dB (e.g. 49.6) or auto",
+			Value:    "auto",
+			Required: false,
+		},
+		&cli.IntFlag{
+			Name:     "ppm",
+			Aliases:  []string{"p"},
+			Usage:    "frequency correction in PPM",
+			Required: false,
+		},
+		&cli.IntFlag{
+			Name:     "squelch",
+			Aliases:  []string{"l"},
+			Usage:    "squelch level (0 disables squelch)",
+			Required: false,
+		},
+		&cli.BoolFlag{
+			Name:     "edge",
+			Usage:    "enable lower edge tuning",
+			Required: false,
+		},
+		&cli.BoolFlag{
+			Name:     "dc",
+			Usage:    "enable DC blocking filter",
+			Required: false,
+		},
+		&cli.BoolFlag{
+			Name:     "deemp",
+			Usage:    "enable de-Emphasis filter",
+			Required: false,
+		},
+		&cli.BoolFlag{
+			Name:     "direct",
+			Usage:    "enable direct sampling",
+			Required: false,
+		},
+		&cli.BoolFlag{
+			Name:     "offset",
+			Usage:    "enable offset tuning",
+			Required: false,
+		},
+	}
+}
+
+func modulation(ctx *cli.Context) (rtlfm.Modulation, error) {
+	m, err := rtlfm.ParseModulation(ctx.String("mode"))
+	if err != nil {
+		return 0, ArgumentError("invalid demodulation mode")
+	}
+	return m, nil
+}
+
+// tuning returns the tuning specified by the flags.
+func tuning(ctx *cli.Context) (player.Tuning, error) {
+	freq, err := frequency(ctx)
+	if err != nil {
+		return player.Tuning{}, err
+	}
+	t, err := tuningSettings(ctx)
+	if err != nil {
+		return player.Tuning{}, err
+	}
+	t.Frequency = freq
+	return t, nil
+}
+
+// tuningSettings returns the tuning specified by the flags except the
+// frequency.
+func tuningSettings(ctx *cli.Context) (player.Tuning, error) {
+	m, err := modulation(ctx)
+	if err != nil {
+		return player.Tuning{}, err
+	}
+	t := player.Tuning{
+		Modulation: m,
+		Station:    ctx.String("station"),
+		Preset:     stationPreset(ctx),
+	}
+
+	gain, auto, err := rtlfm.ParseGain(ctx.String("gain"))
+	if err != nil {
+		return player.Tuning{}, ArgumentError("invalid gain")
+	}
+	if !auto {
+		t.Gain = &gain
+	}
+
+	return t, nil
+}
+
+// radioSettings are the receiver settings of a session other than those
+// of player.Tuning.
+type radioSettings struct {
+	dongle  string
+	ppm     int
+	squelch int
+	edge    bool
+	dc      bool
+	deemp   bool
+	direct  bool
+	offset  bool
+}
+
+// sessionSettings returns the settings of a session tuned to t. The preset
+// of t overrides the flags, which do not include the station specified by
+// --station, so that settings of a station do not apply to other tunings.
+func sessionSettings(ctx *cli.Context, t player.Tuning) radioSettings {
+	flags := baseFlags(ctx)
+	s := radioSettings{
+		dongle:  flags.String("dongle"),
+		ppm:     flags.Int("ppm"),
+		squelch: flags.Int("squelch"),
+		edge:    flags.Bool("edge"),
+		dc:      flags.Bool("dc"),
+		deemp:   flags.Bool("deemp"),
+		direct:  flags.Bool("direct"),
+		offset:  flags.Bool("offset"),
+	}
+
+	st := t.Preset
+	if st == nil {
+		return s
+	}
+	if st.PPM != 0 {
+		s.ppm = st.PPM
+	}
+	if st.Squelch != 0 {
+		s.squelch = st.Squelch
+	}
+	for _, o := range []struct {
+		enabled *bool
+		v       *bool
+	}{
+		{st.Edge, &s.edge},
+		{st.DC, &s.dc},
+		{st.DeEmphasis, &s.deemp},
+		{st.Direct, &s.direct},
+		{st.Offset, &s.offset},
+	} {
+		if o.enabled != nil {
+			*o.v = *o.enabled
+		}
+	}
+	return s
+}
+
+// rtlfmOptions returns the rtl_fm options of the session tuned to t.
+func rtlfmOptions(ctx *cli.Context, t player.Tuning) ([]rtlfm.Option, error) {
+	s := sessionSettings(ctx, t)
+
+	var opts []rtlfm.Option
+	if s.dongle != "" {
+		if index, err := strconv.Atoi(s.dongle); err == nil {
+			opts = append(opts, rtlfm.WithDeviceIndex(index))
+		} else {
+			opts = append(opts, rtlfm.WithDeviceSerial(s.dongle))
+		}
+	}
+	if s.ppm != 0 {
+		opts = append(opts, rtlfm.WithPPMCorrection(s.ppm))
+	}
+	if s.squelch != 0 {
+		opts = append(opts, rtlfm.WithSquelch(s.squelch))
+	}
+
+	if s.edge {
+		opts = append(opts, rtlfm.EnableLowerEdgeTuning())
+	}
+	if s.dc {
+		opts = append(opts, rtlfm.EnableDCBlockingFilter())
+	}
+	if s.deemp {
+		opts = append(opts, rtlfm.EnableDeEmphasisFilter())
+	}
+	if s.direct {
+		opts = append(opts, rtlfm.EnableDirectSampling())
+	}
+	if s.offset {
+		opts = append(opts, rtlfm.EnableOffsetTuning())
+	}
+
+	// the modulation and the gain of the tuning
+	if t.Modulation != 0 {
+		opts = append(opts, rtlfm.WithModulation(t.Modulation))
+	}
+	if t.Gain != nil {
+		opts = append(opts, rtlfm.WithGain(*t.Gain))
+	}
+
+	if err := rtlfm.ValidateOptions(opts...); err != nil {
+		if errors.Is(err, rtlfm.ErrInvalidOption) {
+			return nil, ArgumentError(err.Error())
+		}
+		return nil, err
+	}
+
+	return opts, nil
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kechako/goradio/control"
+	"github.com/kechako/goradio/player"
+	"github.com/kechako/goradio/source"
+	"github.com/kechako/goradio/station"
+)
+
+func TestSessionSettings(t *testing.T) {
+	const config = `{"defaults": {"deemp": true, "ppm": 52}}`
+	const stations = `{"stations": [
+		{"name": "Repeater", "frequency": "145.5M", "mode": "fm", "ppm": 10, "squelch": 20, "dc": true, "deemp": false},
+		{"name": "Tokyo", "frequency": "80.0M", "offset": true}
+	]}`
+
+	tests := []struct {
+		name string
+		args []string
+		// body of POST /tune after the initial tuning
+		tune string
+		want radioSettings
+	}{
+		{
+			name: "station",
+			args: []string{"--station", "Repeater"},
+			want: radioSettings{ppm: 10, squelch: 20, dc: true},
+		},
+		{
+			name: "command line over station",
+			args: []string{"--station", "Repeater", "--ppm", "5", "--deemp"},
+			want: radioSettings{ppm: 5, squelch: 20, dc: true, deemp: true},
+		},
+		{
+			name: "frequency after station",
+			args: []string{"--station", "Repeater"},
+			tune: `{"frequency": "81.3M"}`,
+			want: radioSettings{ppm: 52, deemp: true},
+		},
+		{
+			name: "command line after station",
+			args: []string{"--station", "Repeater", "--squelch", "3"},
+			tune: `{"frequency": "81.3M"}`,
+			want: radioSettings{ppm: 52, squelch: 3, deemp: true},
+		},
+		{
+			name: "station after station",
+			args: []string{"--station", "Repeater"},
+			tune: `{"station": "Tokyo"}`,
+			want: radioSettings{ppm: 52, deemp: true, offset: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := runWithConfig(t, config, stations, tt.args...)
+
+			open := func(player.Tuning) (source.Source, error) {
+				return nil, errors.New("not supported in tests")
+			}
+			p := player.New(nil, 48000, 480, open)
+			defer p.Close()
+
+			start, err := tuning(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := p.Tune(start); err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.tune != "" {
+				h := control.NewHandler(p, control.WithStations(func() (*station.Presets, error) {
+					presets, _, err := loadPresets(ctx)
+					return presets, err
+				}))
+				r := httptest.NewRequest(http.MethodPost, "/tune", strings.NewReader(tt.tune))
+				w := httptest.NewRecorder()
+				h.ServeHTTP(w, r)
+				if w.Code != http.StatusOK {
+					t.Fatalf("POST /tune = %d: %s", w.Code, w.Body)
+				}
+			}
+
+			if got := sessionSettings(ctx, p.Tuning()); got != tt.want {
+				t.Errorf("sessionSettings() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,212 @@
+package volume
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	MinDB = -60.0
+	MaxDB = 20.0
+
+	// time constant of volume and mute changes, to avoid clicks
+	smoothing = 10 * time.Millisecond
+	// samples above this level relative to full scale are compressed by
+	// the soft clipper
+	clipThreshold = 0.8
+	// gain below this is regarded as silence
+	silentGain = 1e-4
+)
+
+var ErrInvalidVolume = errors.New("invalid volume")
+
+// ParseDB parses a volume in dB, e.g. "-6dB", "+3", "0 dB".
+func ParseDB(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	if len(s) >= 2 && strings.EqualFold(s[len(s)-2:], "db") {
+		s = strings.TrimSpace(s[:len(s)-2])
+	}
+	db, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(db) || db < MinDB || db > MaxDB {
+		return 0, ErrInvalidVolume
+	}
+	return db, nil
+}
+
+// FormatDB formats db, e.g. "-6dB".
+func FormatDB(db float64) string {
+	return strconv.FormatFloat(db, 'f', -1, 64) + "dB"
+}
+
+// Control is a gain stage of mono int16 frames with volume, mute, fades and
+// a soft clipper. Its methods can be called while another goroutine calls
+// Process.
+type Control struct {
+	// per sample coefficient of smoothing
+	k float64
+	// samples per second
+	rate float64
+
+	mu    sync.Mutex
+	db    float64
+	muted bool
+	// current gain of volume and mute, approaches the target smoothly
+	gain float64
+
+	// fade envelope from 0 to 1
+	fade       float64
+	fadeTarget float64
+	fadeStep   float64
+	fadeDone   chan struct{}
+}
+
+// New returns a control at db with no fade.
+func New(sampleRate int, db float64) *Control {
+	c := &Control{
+		k:          1 - math.Exp(-1/(smoothing.Seconds()*float64(sampleRate))),
+		rate:       float64(sampleRate),
+		db:         db,
+		fade:       1,
+		fadeTarget: 1,
+	}
+	c.gain = c.targetGain()
+	return c
+}
+
+// SetVolume sets the volume in dB, clamped to MinDB and MaxDB. MinDB means
+// silence.
+func (c *Control) SetVolume(db float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.db = math.Max(MinDB, math.Min(MaxDB, db))
+}
+
+func (c *Control) Volume() float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.db
+}
+
+func (c *Control) SetMuted(muted bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.muted = muted
+}
+
+func (c *Control) Muted() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.muted
+}
+
+// FadeIn fades from silence to the volume over d.
+func (c *Control) FadeIn(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.fade = 0
+	c.startFade(1, d)
+}
+
+// FadeOut fades from the current level to silence over d. The returned
+// channel is closed when the fade is completed by Process.
+func (c *Control) FadeOut(d time.Duration) <-chan struct{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.startFade(0, d)
+	done := make(chan struct{})
+	if c.fade == 0 {
+		close(done)
+	} else {
+		c.fadeDone = done
+	}
+	return done
+}
+
+func (c *Control) startFade(target float64, d time.Duration) {
+	// the previous fade is superseded
+	c.endFade()
+
+	c.fadeTarget = target
+	samples := d.Seconds() * c.rate
+	if samples < 1 {
+		c.fade = target
+		return
+	}
+	c.fadeStep = 1 / samples
+}
+
+func (c *Control) targetGain() float64 {
+	if c.muted || c.db <= MinDB {
+		return 0
+	}
+	return math.Pow(10, c.db/20)
+}
+
+// Process applies the gain to frame in place.
+func (c *Control) Process(frame []int16) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	target := c.targetGain()
+	if c.gain < silentGain && target == 0 {
+		// fades are inaudible while silent
+		c.gain = 0
+		c.fade = c.fadeTarget
+		c.endFade()
+		for i := range frame {
+			frame[i] = 0
+		}
+		return
+	}
+	if c.gain == 1 && target == 1 && c.fade == 1 && c.fadeTarget == 1 {
+		return
+	}
+
+	for i, s := range frame {
+		c.gain += (target - c.gain) * c.k
+		if c.fade < c.fadeTarget {
+			c.fade = math.Min(c.fade+c.fadeStep, c.fadeTarget)
+		} else if c.fade > c.fadeTarget {
+			c.fade = math.Max(c.fade-c.fadeStep, c.fadeTarget)
+		}
+
+		v := softClip(float64(s) / -math.MinInt16 * c.gain * c.fade)
+		frame[i] = int16(math.Round(v * math.MaxInt16))
+	}
+
+	if math.Abs(c.gain-target) < silentGain {
+		c.gain = target
+	}
+	if c.fade == c.fadeTarget {
+		c.endFade()
+	}
+}
+
+func (c *Control) endFade() {
+	if c.fadeDone != nil {
+		close(c.fadeDone)
+		c.fadeDone = nil
+	}
+}
+
+// softClip compresses v above clipThreshold smoothly so that it does not
+// exceed 1.
+func softClip(v float64) float64 {
+	a := math.Abs(v)
+	if a <= clipThreshold {
+		return v
+	}
+	const knee = 1 - clipThreshold
+	a = clipThreshold + knee*math.Tanh((a-clipThreshold)/knee)
+	return math.Copysign(a, v)
+}
@@ -0,0 +1,241 @@
+package volume
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestParseDB(t *testing.T) {
+	tests := []struct {
+		in   string
+		want float64
+	}{
+		{"0", 0},
+		{"-6dB", -6},
+		{"+3", 3},
+		{"0 dB", 0},
+		{" -12.5db ", -12.5},
+		{"-60dB", MinDB},
+		{"20DB", MaxDB},
+	}
+	for _, tt := range tests {
+		got, err := ParseDB(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseDB(%q) = %v, %v, want %v", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestParseDBInvalid(t *testing.T) {
+	for _, in := range []string{"", "dB", "loud", "-61dB", "21", "NaN", "Inf", "6 d B"} {
+		if got, err := ParseDB(in); !errors.Is(err, ErrInvalidVolume) {
+			t.Errorf("ParseDB(%q) = %v, %v, want %v", in, got, err, ErrInvalidVolume)
+		}
+	}
+}
+
+func TestFormatDB(t *testing.T) {
+	tests := []struct {
+		in   float64
+		want string
+	}{
+		{0, "0dB"},
+		{-6, "-6dB"},
+		{3.5, "3.5dB"},
+	}
+	for _, tt := range tests {
+		if got := FormatDB(tt.in); got != tt.want {
+			t.Errorf("FormatDB(%v) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSoftClip(t *testing.T) {
+	tests := []struct {
+		in   float64
+		want float64
+	}{
+		{0, 0},
+		{0.5, 0.5},
+		{-0.8, -0.8},
+		{clipThreshold, clipThreshold},
+	}
+	for _, tt := range tests {
+		if got := softClip(tt.in); got != tt.want {
+			t.Errorf("softClip(%v) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+
+	// above the threshold the output rises monotonically below full scale
+	prev := clipThreshold
+	for v := clipThreshold + 0.01; v < 2; v += 0.01 {
+		got := softClip(v)
+		if got <= prev || got >= 1 {
+			t.Fatalf("softClip(%v) = %v, previous %v", v, got, prev)
+		}
+		if neg := softClip(-v); neg != -got {
+			t.Fatalf("softClip(%v) = %v, want %v", -v, neg, -got)
+		}
+		prev = got
+	}
+}
+
+const testRate = 48000
+
+func constant(n int, v int16) []int16 {
+	frame := make([]int16, n)
+	for i := range frame {
+		frame[i] = v
+	}
+	return frame
+}
+
+func TestControlVolume(t *testing.T) {
+	tests := []struct {
+		db   float64
+		in   int16
+		want int16
+	}{
+		{0, 10000, 10000},
+		{-6, 10000, 5012},
+		{-20, 10000, 1000},
+		{MinDB, 10000, 0},
+		// soft clipped
+		{12, 8000, 30775},
+		{MaxDB, 30000, 32767},
+		{MaxDB, -30000, -32767},
+	}
+	for _, tt := range tests {
+		c := New(testRate, tt.db)
+		frame := constant(testRate/10, tt.in)
+		c.Process(frame)
+		if got := frame[len(frame)-1]; math.Abs(float64(got)-float64(tt.want)) > 1 {
+			t.Errorf("%vdB: sample = %d, want %d", tt.db, got, tt.want)
+		}
+	}
+}
+
+func TestControlSetVolume(t *testing.T) {
+	c := New(testRate, 0)
+	c.SetVolume(100)
+	if got := c.Volume(); got != MaxDB {
+		t.Errorf("Volume() = %v, want %v", got, MaxDB)
+	}
+	c.SetVolume(-100)
+	if got := c.Volume(); got != MinDB {
+		t.Errorf("Volume() = %v, want %v", got, MinDB)
+	}
+
+	// the gain changes smoothly
+	c.SetVolume(-6)
+	frame := constant(testRate/10, 10000)
+	c.Process(frame)
+	if frame[0] < 9900 {
+		t.Errorf("first sample = %d, want close to 10000", frame[0])
+	}
+	if got := frame[len(frame)-1]; got != 5012 {
+		t.Errorf("last sample = %d, want 5012", got)
+	}
+}
+
+func TestControlMute(t *testing.T) {
+	c := New(testRate, 0)
+	c.SetMuted(true)
+	if !c.Muted() {
+		t.Fatal("Muted() = false")
+	}
+	frame := constant(testRate/10, 10000)
+	c.Process(frame)
+	if frame[0] == 0 {
+		t.Error("mute is not smoothed")
+	}
+	if got := frame[len(frame)-1]; got != 0 {
+		t.Errorf("last sample = %d, want 0", got)
+	}
+
+	// muted frames are silenced
+	frame = constant(100, 10000)
+	c.Process(frame)
+	for i, v := range frame {
+		if v != 0 {
+			t.Fatalf("frame[%d] = %d, want 0", i, v)
+		}
+	}
+
+	c.SetMuted(false)
+	frame = constant(testRate/10, 10000)
+	c.Process(frame)
+	if got := frame[len(frame)-1]; got < 9999 {
+		t.Errorf("last sample after unmute = %d, want 10000", got)
+	}
+}
+
+func TestControlFade(t *testing.T) {
+	c := New(testRate, 0)
+	c.FadeIn(100 * time.Millisecond)
+
+	// 100ms in 10ms frames
+	var levels []int16
+	for i := 0; i < 10; i++ {
+		frame := constant(testRate/100, 10000)
+		c.Process(frame)
+		levels = append(levels, frame[len(frame)-1])
+	}
+	for i := 1; i < len(levels); i++ {
+		if levels[i] <= levels[i-1] {
+			t.Fatalf("fade in levels = %v, want rising", levels)
+		}
+	}
+	if got := levels[len(levels)-1]; got != 10000 {
+		t.Errorf("level after fade in = %d, want 10000", got)
+	}
+
+	done := c.FadeOut(50 * time.Millisecond)
+	select {
+	case <-done:
+		t.Fatal("fade out is done before processing")
+	default:
+	}
+	// a frame more than the fade time
+	for i := 0; i < 6; i++ {
+		c.Process(constant(testRate/100, 10000))
+	}
+	select {
+	case <-done:
+	default:
+		t.Fatal("fade out is not done after processing")
+	}
+	frame := constant(100, 10000)
+	c.Process(frame)
+	if frame[0] != 0 {
+		t.Errorf("sample after fade out = %d, want 0", frame[0])
+	}
+}
+
+func TestControlFadeOutSilent(t *testing.T) {
+	c := New(testRate, MinDB)
+	done := c.FadeOut(time.Second)
+	c.Process(make([]int16, 10))
+	select {
+	case <-done:
+	default:
+		t.Fatal("fade out of silence is not done")
+	}
+
+	// a fade out superseded by another one is done
+	c = New(testRate, 0)
+	first := c.FadeOut(time.Second)
+	second := c.FadeOut(0)
+	select {
+	case <-first:
+	default:
+		t.Fatal("superseded fade out is not done")
+	}
+	select {
+	case <-second:
+	default:
+		t.Fatal("immediate fade out is not done")
+	}
+}
@@ -0,0 +1,144 @@
+package wav
+
+import "encoding/binary"
+
+var imaStepTable = [89]int{
+	7, 8, 9, 10, 11, 12, 13, 14, 16, 17,
+	19, 21, 23, 25, 28, 31, 34, 37, 41, 45,
+	50, 55, 60, 66, 73, 80, 88, 97, 107, 118,
+	130, 143, 157, 173, 190, 209, 230, 253, 279, 307,
+	337, 371, 408, 449, 494, 544, 598, 658, 724, 796,
+	876, 963, 1060, 1166, 1282, 1411, 1552, 1707, 1878, 2066,
+	2272, 2499, 2749, 3024, 3327, 3660, 4026, 4428, 4871, 5358,
+	5894, 6484, 7132, 7845, 8630, 9493, 10442, 11487, 12635, 13899,
+	15289, 16818, 18500, 20350, 22385, 24623, 27086, 29794, 32767,
+}
+
+var imaIndexTable = [16]int{
+	-1, -1, -1, -1, 2, 4, 6, 8,
+	-1, -1, -1, -1, 2, 4, 6, 8,
+}
+
+// adpcmBlockAlign returns the size of IMA ADPCM blocks, 256 bytes per
+// channel at 11025 Hz and larger at higher rates.
+func adpcmBlockAlign(sampleRate, channels int) int {
+	n := sampleRate / 11025
+	if n < 1 {
+		n = 1
+	}
+	return 256 * channels * n
+}
+
+// adpcmSamplesPerBlock returns the number of samples per channel in a
+// block, the first one is in the header of the block.
+func adpcmSamplesPerBlock(blockAlign, channels int) int {
+	return (blockAlign-4*channels)*2/channels + 1
+}
+
+// adpcmChannel is the state of the encoder of a channel.
+type adpcmChannel struct {
+	predictor int
+	index     int
+}
+
+func (c *adpcmChannel) encode(sample int16) byte {
+	diff := int(sample) - c.predictor
+	var nibble byte
+	if diff < 0 {
+		nibble = 8
+		diff = -diff
+	}
+
+	step := imaStepTable[c.index]
+	delta := step >> 3
+	if diff >= step {
+		nibble |= 4
+		diff -= step
+		delta += step
+	}
+	step >>= 1
+	if diff >= step {
+		nibble |= 2
+		diff -= step
+		delta += step
+	}
+	step >>= 1
+	if diff >= step {
+		nibble |= 1
+		delta += step
+	}
+
+	if nibble&8 != 0 {
+		c.predictor -= delta
+	} else {
+		c.predictor += delta
+	}
+	if c.predictor > 32767 {
+		c.predictor = 32767
+	} else if c.predictor < -32768 {
+		c.predictor = -32768
+	}
+
+	c.index += imaIndexTable[nibble]
+	if c.index < 0 {
+		c.index = 0
+	} else if c.index > 88 {
+		c.index = 88
+	}
+
+	return nibble
+}
+
+// adpcmEncoder encodes interleaved samples in IMA ADPCM blocks. The step
+// index is carried over blocks.
+type adpcmEncoder struct {
+	channels        int
+	blockAlign      int
+	samplesPerBlock int
+	state           []adpcmChannel
+}
+
+func newADPCMEncoder(format Format) *adpcmEncoder {
+	blockAlign := format.blockAlign()
+	return &adpcmEncoder{
+		channels:        format.Channels,
+		blockAlign:      blockAlign,
+		samplesPerBlock: adpcmSamplesPerBlock(blockAlign, format.Channels),
+		state:           make([]adpcmChannel, format.Channels),
+	}
+}
+
+// encode appends a block of samples to dst. samples has samplesPerBlock
+// samples of every channel.
+func (e *adpcmEncoder) encode(dst []byte, samples []int16) []byte {
+	off := len(dst)
+	for i := 0; i < e.blockAlign; i++ {
+		dst = append(dst, 0)
+	}
+	block := dst[off:]
+
+	// the first sample of every channel is in the header
+	for ch := range e.state {
+		c := &e.state[ch]
+		c.predictor = int(samples[ch])
+		binary.LittleEndian.PutUint16(block[4*ch:], uint16(samples[ch]))
+		block[4*ch+2] = byte(c.index)
+	}
+
+	// groups of 8 samples of every channel in turn, in 4 bytes with the
+	// earlier sample in the low nibble
+	data := block[4*e.channels:]
+	for i := 0; i < e.samplesPerBlock-1; i++ {
+		group, n := i/8, i%8
+		for ch := range e.state {
+			nibble := e.state[ch].encode(samples[(i+1)*e.channels+ch])
+			b := &data[(group*e.channels+ch)*4+n/2]
+			if n%2 == 0 {
+				*b |= nibble
+			} else {
+				*b |= nibble << 4
+			}
+		}
+	}
+	return dst
+}
@@ -0,0 +1,56 @@
+package wav
+
+const (
+	muLawBias = 0x84
+	muLawClip = 32635
+)
+
+// EncodeMuLaw appends samples encoded in G.711 mu-law to dst.
+func EncodeMuLaw(dst []byte, samples []int16) []byte {
+	for _, s := range samples {
+		dst = append(dst, muLaw(s))
+	}
+	return dst
+}
+
+func muLaw(s int16) byte {
+	v := int(s)
+	sign := 0
+	if v < 0 {
+		v = -v
+		sign = 0x80
+	}
+	if v > muLawClip {
+		v = muLawClip
+	}
+	v += muLawBias
+
+	exponent := 7
+	for mask := 0x4000; v&mask == 0 && exponent > 0; mask >>= 1 {
+		exponent--
+	}
+	mantissa := (v >> (exponent + 3)) & 0x0f
+
+	return ^byte(sign | exponent<<4 | mantissa)
+}
+
+// DecodeMuLaw appends samples decoded from G.711 mu-law to dst.
+func DecodeMuLaw(dst []int16, b []byte) []int16 {
+	for _, v := range b {
+		dst = append(dst, linear(v))
+	}
+	return dst
+}
+
+func linear(b byte) int16 {
+	b = ^b
+	exponent := int(b>>4) & 0x07
+	mantissa := int(b & 0x0f)
+
+	v := (mantissa<<3 + muLawBias) << exponent
+	v -= muLawBias
+	if b&0x80 != 0 {
+		v = -v
+	}
+	return int16(v)
+}
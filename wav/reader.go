@@ -0,0 +1,120 @@
+package wav
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+var ErrInvalidFormat = errors.New("invalid wav format")
+
+type Reader struct {
+	r         io.Reader
+	format    Format
+	remaining int64
+}
+
+// NewReader reads the header of r until the beginning of the data chunk.
+func NewReader(r io.Reader) (*Reader, error) {
+	var riff [12]byte
+	if _, err := io.ReadFull(r, riff[:]); err != nil {
+		return nil, fmt.Errorf("failed to read wav header: %w", err)
+	}
+	if string(riff[0:4]) != "RIFF" || string(riff[8:12]) != "WAVE" {
+		return nil, ErrInvalidFormat
+	}
+
+	var format *Format
+	for {
+		var h [8]byte
+		if _, err := io.ReadFull(r, h[:]); err != nil {
+			return nil, fmt.Errorf("failed to read wav chunk: %w", err)
+		}
+		id := string(h[0:4])
+		size := int64(binary.LittleEndian.Uint32(h[4:]))
+
+		switch id {
+		case "fmt ":
+			f, err := readFormat(r, size)
+			if err != nil {
+				return nil, err
+			}
+			format = &f
+		case "data":
+			if format == nil {
+				return nil, ErrInvalidFormat
+			}
+			remaining := size
+			if size >= unknownSize-int64(format.headerSize()) {
+				// streamed wav, read until EOF
+				remaining = -1
+			}
+			return &Reader{
+				r:         r,
+				format:    *format,
+				remaining: remaining,
+			}, nil
+		default:
+			if _, err := io.CopyN(io.Discard, r, size+size%2); err != nil {
+				return nil, fmt.Errorf("failed to skip wav chunk: %w", err)
+			}
+		}
+	}
+}
+
+func readFormat(r io.Reader, size int64) (Format, error) {
+	if size < 16 {
+		return Format{}, ErrInvalidFormat
+	}
+	b := make([]byte, size+size%2)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return Format{}, fmt.Errorf("failed to read wav format: %w", err)
+	}
+
+	tag := binary.LittleEndian.Uint16(b[0:])
+	// WAVE_FORMAT_EXTENSIBLE has the actual format in the sub format GUID
+	if tag == 0xfffe && size >= 26 {
+		tag = binary.LittleEndian.Uint16(b[24:])
+	}
+
+	f := Format{
+		Channels:      int(binary.LittleEndian.Uint16(b[2:])),
+		SampleRate:    int(binary.LittleEndian.Uint32(b[4:])),
+		BitsPerSample: int(binary.LittleEndian.Uint16(b[14:])),
+	}
+	switch tag {
+	case formatPCM:
+	case formatIEEEFloat:
+		f.Float = true
+	case formatMuLaw:
+		f.MuLaw = true
+	default:
+		return Format{}, fmt.Errorf("%w: unsupported format tag %d", ErrInvalidFormat, tag)
+	}
+	if err := f.validate(); err != nil {
+		return Format{}, fmt.Errorf("%w: %v", ErrInvalidFormat, err)
+	}
+
+	return f, nil
+}
+
+func (r *Reader) Format() Format {
+	return r.format
+}
+
+// Read reads raw sample data of the data chunk.
+func (r *Reader) Read(b []byte) (int, error) {
+	if r.remaining == 0 {
+		return 0, io.EOF
+	}
+	if r.remaining > 0 && int64(len(b)) > r.remaining {
+		b = b[:r.remaining]
+	}
+
+	n, err := r.r.Read(b)
+	if r.remaining > 0 {
+		r.remaining -= int64(n)
+	}
+	return n, err
+}
@@ -0,0 +1,326 @@
+package wav
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+const (
+	formatPCM       = 1
+	formatIEEEFloat = 3
+	formatMuLaw     = 7
+	formatIMAADPCM  = 0x11
+
+	// size of the header of PCM, other formats have a larger fmt chunk and
+	// a fact chunk
+	headerSize = 44
+
+	// size written when the data length is not known yet
+	unknownSize = math.MaxUint32
+)
+
+type Format struct {
+	SampleRate    int
+	Channels      int
+	BitsPerSample int
+	Float         bool
+	// MuLaw is G.711 mu-law with 8 bits per sample.
+	MuLaw bool
+	// IMAADPCM is IMA ADPCM with 4 bits per sample, a quarter of the size
+	// of 16-bit PCM.
+	IMAADPCM bool
+}
+
+func (f Format) blockAlign() int {
+	if f.IMAADPCM {
+		return adpcmBlockAlign(f.SampleRate, f.Channels)
+	}
+	return f.Channels * f.BitsPerSample / 8
+}
+
+func (f Format) tag() uint16 {
+	switch {
+	case f.Float:
+		return formatIEEEFloat
+	case f.MuLaw:
+		return formatMuLaw
+	case f.IMAADPCM:
+		return formatIMAADPCM
+	default:
+		return formatPCM
+	}
+}
+
+// fmtSize returns the size of the fmt chunk. Formats other than PCM have
+// the cbSize field, followed by the samples per block of IMA ADPCM.
+func (f Format) fmtSize() int {
+	switch f.tag() {
+	case formatPCM:
+		return 16
+	case formatIMAADPCM:
+		return 20
+	default:
+		return 18
+	}
+}
+
+// headerSize returns the size of the header up to the data. Formats other
+// than PCM have the fact chunk.
+func (f Format) headerSize() int {
+	if f.tag() == formatPCM {
+		return headerSize
+	}
+	return headerSize + f.fmtSize() - 16 + 12
+}
+
+func (f Format) validate() error {
+	if f.SampleRate <= 0 {
+		return errors.New("invalid sample rate")
+	}
+	if f.Channels <= 0 {
+		return errors.New("invalid channels")
+	}
+	if f.MuLaw {
+		if f.Float || f.IMAADPCM || f.BitsPerSample != 8 {
+			return errors.New("invalid bits per sample")
+		}
+		return nil
+	}
+	if f.IMAADPCM {
+		if f.Float || f.BitsPerSample != 4 {
+			return errors.New("invalid bits per sample")
+		}
+		return nil
+	}
+	if f.Float {
+		if f.BitsPerSample != 32 && f.BitsPerSample != 64 {
+			return errors.New("invalid bits per sample")
+		}
+		return nil
+	}
+	switch f.BitsPerSample {
+	case 8, 16, 24, 32:
+	default:
+		return errors.New("invalid bits per sample")
+	}
+	return nil
+}
+
+type Writer struct {
+	w        io.Writer
+	format   Format
+	dataSize int64
+	buf      []byte
+	closed   bool
+
+	adpcm *adpcmEncoder
+	// samples per channel encoded in IMA ADPCM, and interleaved samples
+	// of the block not complete yet
+	samples int64
+	pending []int16
+}
+
+func NewWriter(w io.Writer, format Format) (*Writer, error) {
+	if err := format.validate(); err != nil {
+		return nil, err
+	}
+
+	wr := &Writer{
+		w:      w,
+		format: format,
+	}
+	if format.IMAADPCM {
+		wr.adpcm = newADPCMEncoder(format)
+		wr.pending = make([]int16, 0, wr.adpcm.samplesPerBlock*format.Channels)
+	}
+	if err := wr.writeHeader(unknownSize); err != nil {
+		return nil, err
+	}
+
+	return wr, nil
+}
+
+func (w *Writer) Format() Format {
+	return w.format
+}
+
+func (w *Writer) Write(b []byte) (int, error) {
+	if w.closed {
+		return 0, errors.New("wav writer is closed")
+	}
+
+	n, err := w.w.Write(b)
+	w.dataSize += int64(n)
+	if err != nil {
+		return n, fmt.Errorf("failed to write wav data: %w", err)
+	}
+	return n, nil
+}
+
+// WriteInt16 writes 16-bit samples. They are encoded if the format is
+// mu-law or IMA ADPCM, IMA ADPCM is written by blocks.
+func (w *Writer) WriteInt16(samples []int16) error {
+	if w.adpcm != nil {
+		return w.writeADPCM(samples)
+	}
+	if w.format.MuLaw {
+		w.buf = EncodeMuLaw(w.buf[:0], samples)
+		_, err := w.Write(w.buf)
+		return err
+	}
+	if w.format.BitsPerSample != 16 || w.format.Float {
+		return errors.New("sample type does not match wav format")
+	}
+
+	size := 2 * len(samples)
+	if cap(w.buf) < size {
+		w.buf = make([]byte, size)
+	}
+	buf := w.buf[:size]
+	for i, s := range samples {
+		binary.LittleEndian.PutUint16(buf[2*i:], uint16(s))
+	}
+
+	_, err := w.Write(buf)
+	return err
+}
+
+func (w *Writer) writeADPCM(samples []int16) error {
+	for len(samples) > 0 {
+		n := cap(w.pending) - len(w.pending)
+		if n > len(samples) {
+			n = len(samples)
+		}
+		w.pending = append(w.pending, samples[:n]...)
+		samples = samples[n:]
+
+		if len(w.pending) == cap(w.pending) {
+			if err := w.flushADPCM(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// flushADPCM writes the pending samples as a block, padded with the last
+// sample of every channel.
+func (w *Writer) flushADPCM() error {
+	channels := w.format.Channels
+	w.pending = w.pending[:len(w.pending)/channels*channels]
+	if len(w.pending) == 0 {
+		return nil
+	}
+	w.samples += int64(len(w.pending) / channels)
+	for len(w.pending) < cap(w.pending) {
+		w.pending = append(w.pending, w.pending[len(w.pending)-channels])
+	}
+
+	w.buf = w.adpcm.encode(w.buf[:0], w.pending)
+	w.pending = w.pending[:0]
+	_, err := w.Write(w.buf)
+	return err
+}
+
+// Close patches the header with the actual data size if the underlying
+// writer is an io.Seeker. It does not close the underlying writer.
+func (w *Writer) Close() error {
+	if w.closed {
+		return nil
+	}
+	if w.adpcm != nil {
+		if err := w.flushADPCM(); err != nil {
+			return err
+		}
+	}
+	w.closed = true
+
+	// pad byte for odd sized data chunk
+	if w.dataSize%2 != 0 {
+		if _, err := w.w.Write([]byte{0}); err != nil {
+			return fmt.Errorf("failed to write wav data: %w", err)
+		}
+	}
+
+	seeker, ok := w.w.(io.Seeker)
+	if !ok {
+		return nil
+	}
+
+	if _, err := seeker.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to seek wav header: %w", err)
+	}
+	size := w.dataSize
+	if max := int64(unknownSize - w.format.headerSize()); size > max {
+		size = max
+	}
+	if err := w.writeHeader(uint32(size)); err != nil {
+		return err
+	}
+	if _, err := seeker.Seek(0, io.SeekEnd); err != nil {
+		return fmt.Errorf("failed to seek wav end: %w", err)
+	}
+
+	return nil
+}
+
+func (w *Writer) writeHeader(dataSize uint32) error {
+	size := w.format.headerSize()
+	blockAlign := w.format.blockAlign()
+
+	riffSize := uint32(unknownSize)
+	samples := uint32(unknownSize)
+	if dataSize != unknownSize {
+		riffSize = uint32(size) - 8 + dataSize + dataSize%2
+		samples = dataSize / uint32(blockAlign)
+		if w.adpcm != nil {
+			samples = uint32(w.samples)
+		}
+	} else {
+		dataSize = unknownSize - uint32(size)
+	}
+
+	fmtSize := w.format.fmtSize()
+	bytesPerSecond := w.format.SampleRate * blockAlign
+	if w.adpcm != nil {
+		bytesPerSecond = w.format.SampleRate * blockAlign / w.adpcm.samplesPerBlock
+	}
+
+	h := make([]byte, size)
+	copy(h[0:], "RIFF")
+	binary.LittleEndian.PutUint32(h[4:], riffSize)
+	copy(h[8:], "WAVE")
+	copy(h[12:], "fmt ")
+	binary.LittleEndian.PutUint32(h[16:], uint32(fmtSize))
+	binary.LittleEndian.PutUint16(h[20:], w.format.tag())
+	binary.LittleEndian.PutUint16(h[22:], uint16(w.format.Channels))
+	binary.LittleEndian.PutUint32(h[24:], uint32(w.format.SampleRate))
+	binary.LittleEndian.PutUint32(h[28:], uint32(bytesPerSecond))
+	binary.LittleEndian.PutUint16(h[32:], uint16(blockAlign))
+	binary.LittleEndian.PutUint16(h[34:], uint16(w.format.BitsPerSample))
+
+	if w.adpcm != nil {
+		// cbSize and the samples per block
+		binary.LittleEndian.PutUint16(h[36:], 2)
+		binary.LittleEndian.PutUint16(h[38:], uint16(w.adpcm.samplesPerBlock))
+	}
+
+	b := h[20+fmtSize:]
+	if fmtSize > 16 {
+		// the fact chunk has the number of samples per channel
+		copy(b[0:], "fact")
+		binary.LittleEndian.PutUint32(b[4:], 4)
+		binary.LittleEndian.PutUint32(b[8:], samples)
+		b = b[12:]
+	}
+	copy(b[0:], "data")
+	binary.LittleEndian.PutUint32(b[4:], dataSize)
+
+	if _, err := w.w.Write(h); err != nil {
+		return fmt.Errorf("failed to write wav header: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,359 @@
+package wav
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriterReader(t *testing.T) {
+	samples := []int16{0, 1000, -1000, 32767, -32768, 12345, -5}
+
+	tests := []struct {
+		name   string
+		format Format
+		// size of the header up to the data
+		header int
+		// samples of the format as 16-bit
+		want []int16
+	}{
+		{"pcm", Format{SampleRate: 8000, Channels: 1, BitsPerSample: 16}, 44, samples},
+		{"stereo", Format{SampleRate: 48000, Channels: 2, BitsPerSample: 16}, 44, samples[:6]},
+		{"mu-law", Format{SampleRate: 8000, Channels: 1, BitsPerSample: 8, MuLaw: true}, 58, DecodeMuLaw(nil, EncodeMuLaw(nil, samples))},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), "test.wav")
+		file, err := os.Create(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w, err := NewWriter(file, tt.format)
+		if err != nil {
+			t.Fatalf("%s: NewWriter() error: %v", tt.name, err)
+		}
+		if err := w.WriteInt16(tt.want); err != nil {
+			t.Fatalf("%s: WriteInt16() error: %v", tt.name, err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("%s: Close() error: %v", tt.name, err)
+		}
+		file.Close()
+
+		b, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		dataSize := len(tt.want) * tt.format.BitsPerSample / 8
+		if got, want := len(b), tt.header+dataSize+dataSize%2; got != want {
+			t.Errorf("%s: file of %d bytes, want %d", tt.name, got, want)
+		}
+		if got, want := binary.LittleEndian.Uint32(b[4:]), uint32(len(b)-8); got != want {
+			t.Errorf("%s: RIFF size = %d, want %d", tt.name, got, want)
+		}
+		if got, want := binary.LittleEndian.Uint32(b[tt.header-4:]), uint32(dataSize); got != want {
+			t.Errorf("%s: data size = %d, want %d", tt.name, got, want)
+		}
+
+		r, err := NewReader(bytes.NewReader(b))
+		if err != nil {
+			t.Errorf("%s: NewReader() error: %v", tt.name, err)
+			continue
+		}
+		if r.Format() != tt.format {
+			t.Errorf("%s: Format() = %+v, want %+v", tt.name, r.Format(), tt.format)
+		}
+		data, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatalf("%s: Read() error: %v", tt.name, err)
+		}
+		var got []int16
+		if tt.format.MuLaw {
+			got = DecodeMuLaw(nil, data)
+		} else {
+			for i := 0; i+1 < len(data); i += 2 {
+				got = append(got, int16(binary.LittleEndian.Uint16(data[i:])))
+			}
+		}
+		if !equal(got, tt.want) {
+			t.Errorf("%s: read %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestWriterExtendedFormat(t *testing.T) {
+	tests := []struct {
+		name   string
+		format Format
+		tag    uint16
+	}{
+		{"mu-law", Format{SampleRate: 8000, Channels: 1, BitsPerSample: 8, MuLaw: true}, formatMuLaw},
+		{"float", Format{SampleRate: 48000, Channels: 2, BitsPerSample: 32, Float: true}, formatIEEEFloat},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), "test.wav")
+		file, err := os.Create(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w, err := NewWriter(file, tt.format)
+		if err != nil {
+			t.Fatalf("%s: NewWriter() error: %v", tt.name, err)
+		}
+		frames := 10
+		if _, err := w.Write(make([]byte, frames*tt.format.blockAlign())); err != nil {
+			t.Fatal(err)
+		}
+		w.Close()
+		file.Close()
+
+		b, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := binary.LittleEndian.Uint32(b[16:]); got != 18 {
+			t.Errorf("%s: fmt size = %d, want 18", tt.name, got)
+		}
+		if got := binary.LittleEndian.Uint16(b[20:]); got != tt.tag {
+			t.Errorf("%s: format tag = %d, want %d", tt.name, got, tt.tag)
+		}
+		if got := binary.LittleEndian.Uint16(b[36:]); got != 0 {
+			t.Errorf("%s: cbSize = %d, want 0", tt.name, got)
+		}
+		if string(b[38:42]) != "fact" || binary.LittleEndian.Uint32(b[42:]) != 4 {
+			t.Errorf("%s: no fact chunk after fmt: %q", tt.name, b[38:46])
+		}
+		if got := binary.LittleEndian.Uint32(b[46:]); got != uint32(frames) {
+			t.Errorf("%s: fact sample length = %d, want %d", tt.name, got, frames)
+		}
+		if string(b[50:54]) != "data" {
+			t.Errorf("%s: no data chunk after fact: %q", tt.name, b[50:54])
+		}
+	}
+}
+
+func TestWriterStreamed(t *testing.T) {
+	// the header of a writer that is not seekable is not patched
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, Format{SampleRate: 8000, Channels: 1, BitsPerSample: 8, MuLaw: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.WriteInt16(make([]int16, 100)); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+
+	r, err := NewReader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.remaining != -1 {
+		t.Errorf("remaining = %d, want -1 to read until EOF", r.remaining)
+	}
+	data, _ := io.ReadAll(r)
+	if len(data) != 100 {
+		t.Errorf("read %d bytes, want 100", len(data))
+	}
+}
+
+func TestWriterIMAADPCM(t *testing.T) {
+	tests := []struct {
+		name       string
+		sampleRate int
+		channels   int
+		frames     int
+		blockAlign int
+		perBlock   int
+	}{
+		{"mono", 48000, 1, 5000, 1024, 2041},
+		{"stereo", 11025, 2, 1000, 512, 505},
+		{"whole blocks", 8000, 1, 2 * 505, 256, 505},
+	}
+	for _, tt := range tests {
+		samples := make([]int16, tt.frames*tt.channels)
+		for i := range samples {
+			ch := i % tt.channels
+			samples[i] = int16(10000 * math.Sin(2*math.Pi*float64(300*(ch+1))*float64(i/tt.channels)/float64(tt.sampleRate)))
+		}
+
+		path := filepath.Join(t.TempDir(), "test.wav")
+		file, err := os.Create(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		format := Format{SampleRate: tt.sampleRate, Channels: tt.channels, BitsPerSample: 4, IMAADPCM: true}
+		w, err := NewWriter(file, format)
+		if err != nil {
+			t.Fatalf("%s: NewWriter() error: %v", tt.name, err)
+		}
+		// frames of 10ms
+		chunk := tt.sampleRate / 100 * tt.channels
+		for i := 0; i < len(samples); i += chunk {
+			end := i + chunk
+			if end > len(samples) {
+				end = len(samples)
+			}
+			if err := w.WriteInt16(samples[i:end]); err != nil {
+				t.Fatalf("%s: WriteInt16() error: %v", tt.name, err)
+			}
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("%s: Close() error: %v", tt.name, err)
+		}
+		file.Close()
+
+		b, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := binary.LittleEndian.Uint32(b[16:]); got != 20 {
+			t.Errorf("%s: fmt size = %d, want 20", tt.name, got)
+		}
+		if got := binary.LittleEndian.Uint16(b[20:]); got != formatIMAADPCM {
+			t.Errorf("%s: format tag = %d, want %d", tt.name, got, formatIMAADPCM)
+		}
+		if got := int(binary.LittleEndian.Uint16(b[32:])); got != tt.blockAlign {
+			t.Errorf("%s: block align = %d, want %d", tt.name, got, tt.blockAlign)
+		}
+		if got := binary.LittleEndian.Uint16(b[36:]); got != 2 {
+			t.Errorf("%s: cbSize = %d, want 2", tt.name, got)
+		}
+		if got := int(binary.LittleEndian.Uint16(b[38:])); got != tt.perBlock {
+			t.Errorf("%s: samples per block = %d, want %d", tt.name, got, tt.perBlock)
+		}
+		if got := int(binary.LittleEndian.Uint32(b[48:])); string(b[40:44]) != "fact" || got != tt.frames {
+			t.Errorf("%s: fact sample length = %d, want %d", tt.name, got, tt.frames)
+		}
+		if string(b[52:56]) != "data" {
+			t.Fatalf("%s: no data chunk after fact: %q", tt.name, b[52:56])
+		}
+		data := b[60:]
+		blocks := (tt.frames + tt.perBlock - 1) / tt.perBlock
+		if len(data) != blocks*tt.blockAlign || int(binary.LittleEndian.Uint32(b[56:])) != len(data) {
+			t.Fatalf("%s: %d bytes of data, want %d blocks", tt.name, len(data), blocks)
+		}
+
+		decoded := decodeIMAADPCM(data, tt.channels, tt.blockAlign)[:len(samples)]
+		var signal, noise float64
+		for i, s := range samples {
+			d := float64(decoded[i]) - float64(s)
+			signal += float64(s) * float64(s)
+			noise += d * d
+		}
+		if snr := 10 * math.Log10(signal/noise); snr < 20 {
+			t.Errorf("%s: SNR = %.1fdB, want 20dB or more", tt.name, snr)
+		}
+	}
+}
+
+func TestNewWriterInvalid(t *testing.T) {
+	tests := []Format{
+		{SampleRate: 0, Channels: 1, BitsPerSample: 16},
+		{SampleRate: 8000, Channels: 0, BitsPerSample: 16},
+		{SampleRate: 8000, Channels: 1, BitsPerSample: 12},
+		{SampleRate: 8000, Channels: 1, BitsPerSample: 16, MuLaw: true},
+		{SampleRate: 8000, Channels: 1, BitsPerSample: 16, Float: true},
+		{SampleRate: 8000, Channels: 1, BitsPerSample: 16, IMAADPCM: true},
+	}
+	for _, format := range tests {
+		if _, err := NewWriter(io.Discard, format); err == nil {
+			t.Errorf("NewWriter(%+v) = nil error", format)
+		}
+	}
+}
+
+func TestMuLaw(t *testing.T) {
+	tests := []struct {
+		sample int16
+		code   byte
+	}{
+		{0, 0xff},
+		{-1, 0x7f},
+		{32767, 0x80},
+		{-32768, 0x00},
+	}
+	for _, tt := range tests {
+		if got := EncodeMuLaw(nil, []int16{tt.sample}); got[0] != tt.code {
+			t.Errorf("EncodeMuLaw(%d) = %#x, want %#x", tt.sample, got[0], tt.code)
+		}
+	}
+
+	// decoding every code and encoding it again gives the same code, except
+	// negative zero
+	for i := 0; i < 256; i++ {
+		code := byte(i)
+		s := DecodeMuLaw(nil, []byte{code})
+		if got := EncodeMuLaw(nil, s); got[0] != code && code != 0x7f {
+			t.Errorf("EncodeMuLaw(DecodeMuLaw(%#x)) = %#x", code, got[0])
+		}
+	}
+}
+
+func equal(a, b []int16) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// decodeIMAADPCM decodes blocks of IMA ADPCM to interleaved samples.
+func decodeIMAADPCM(data []byte, channels, blockAlign int) []int16 {
+	var samples []int16
+	perBlock := adpcmSamplesPerBlock(blockAlign, channels)
+	for ; len(data) >= blockAlign; data = data[blockAlign:] {
+		block := make([]int16, perBlock*channels)
+		for ch := 0; ch < channels; ch++ {
+			predictor := int(int16(binary.LittleEndian.Uint16(data[4*ch:])))
+			index := int(data[4*ch+2])
+			block[ch] = int16(predictor)
+
+			for i := 0; i < perBlock-1; i++ {
+				b := data[4*channels+(i/8*channels+ch)*4+i%8/2]
+				nibble := b & 0xf
+				if i%2 == 1 {
+					nibble = b >> 4
+				}
+
+				step := imaStepTable[index]
+				delta := step >> 3
+				if nibble&4 != 0 {
+					delta += step
+				}
+				if nibble&2 != 0 {
+					delta += step >> 1
+				}
+				if nibble&1 != 0 {
+					delta += step >> 2
+				}
+				if nibble&8 != 0 {
+					predictor -= delta
+				} else {
+					predictor += delta
+				}
+				if predictor > 32767 {
+					predictor = 32767
+				} else if predictor < -32768 {
+					predictor = -32768
+				}
+				index += imaIndexTable[nibble]
+				if index < 0 {
+					index = 0
+				} else if index > 88 {
+					index = 88
+				}
+				block[(i+1)*channels+ch] = int16(predictor)
+			}
+		}
+		samples = append(samples, block...)
+	}
+	return samples
+}